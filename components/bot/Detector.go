@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"net/http"
+	"strings"
+)
+
+// knownAgents are lower case user agent fragments of link unfurlers, uptime checkers and crawlers.
+var knownAgents = []string{
+	"slackbot", "slack-imgproxy", "whatsapp", "twitterbot", "facebookexternalhit", "facebookcatalog",
+	"linkedinbot", "discordbot", "telegrambot", "skypeuripreview", "pinterest", "redditbot",
+	"googlebot", "bingbot", "yandex", "baiduspider", "duckduckbot", "applebot", "embedly",
+	"uptimerobot", "pingdom", "statuscake", "site24x7", "newrelicpinger", "better uptime",
+	"headlesschrome", "python-requests", "go-http-client", "curl/", "wget/",
+	"bot", "crawler", "spider", "preview",
+}
+
+// IsBot reports whether the request comes from an automated client rather than a person.
+func IsBot(r *http.Request) bool {
+	if r.Method == http.MethodHead {
+		return true
+	}
+
+	if isPrefetch(r) {
+		return true
+	}
+
+	userAgent := strings.ToLower(r.UserAgent())
+	if strings.TrimSpace(userAgent) == "" {
+		return true
+	}
+
+	for _, agent := range knownAgents {
+		if strings.Contains(userAgent, agent) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPrefetch(r *http.Request) bool {
+	for _, header := range []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"} {
+		value := strings.ToLower(r.Header.Get(header))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") || strings.Contains(value, "prerender") {
+			return true
+		}
+	}
+	return false
+}
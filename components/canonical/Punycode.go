@@ -0,0 +1,131 @@
+package canonical
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bootstring parameters for punycode, RFC 3492 section 5.
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+)
+
+// toASCIIHost converts every non-ASCII label of a host name to its "xn--" punycode form.
+func toASCIIHost(host string) (string, error) {
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if isASCII(label) {
+			continue
+		}
+		encoded, err := punycodeEncode(label)
+		if err != nil {
+			return "", err
+		}
+		labels[i] = "xn--" + encoded
+	}
+	return strings.Join(labels, "."), nil
+}
+
+func punycodeEncode(label string) (string, error) {
+	if !utf8.ValidString(label) {
+		return "", errors.New("host is not valid utf-8")
+	}
+
+	input := []rune(label)
+	var output strings.Builder
+
+	for _, r := range input {
+		if r < 0x80 {
+			output.WriteRune(r)
+		}
+	}
+	basicCount := output.Len()
+	handled := basicCount
+	if basicCount > 0 {
+		output.WriteByte('-')
+	}
+
+	n, delta, bias := rune(punyInitialN), 0, punyInitialBias
+	for handled < len(input) {
+		m := rune(0x10FFFF)
+		for _, r := range input {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+
+		delta += int(m-n) * (handled + 1)
+		n = m
+
+		for _, r := range input {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := k - bias
+				if t < punyTMin {
+					t = punyTMin
+				} else if t > punyTMax {
+					t = punyTMax
+				}
+				if q < t {
+					break
+				}
+				output.WriteByte(punyDigit(t + (q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			output.WriteByte(punyDigit(q))
+
+			bias = punyAdapt(delta, handled+1, handled == basicCount)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return output.String(), nil
+}
+
+func punyAdapt(delta, numPoints int, firstTime bool) int {
+	if firstTime {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+
+	k := 0
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+func punyDigit(digit int) byte {
+	if digit < 26 {
+		return byte('a' + digit)
+	}
+	return byte('0' + digit - 26)
+}
+
+func isASCII(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,218 @@
+package canonical
+
+import (
+	"errors"
+	"net"
+	urlNet "net/url"
+	"sort"
+	"strings"
+)
+
+// trackingParams are dropped from the query when tracking parameters are stripped, any utm_ parameter is dropped too.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"gbraid":  true,
+	"wbraid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+	"_gl":     true,
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Canonicalize returns the canonical form of an http or https url, so that urls that point
+// to the same resource compare equal:
+//   - scheme and host are lowercased, international host names are converted to punycode
+//   - default ports and the trailing dot of the host are removed
+//   - the trailing slash of a path is removed, an empty path becomes "/"
+//   - percent-encoding uses upper case hex and unreserved characters are decoded
+//   - query parameters are sorted by name and tracking parameters are optionally dropped
+func Canonicalize(rawUrl string, stripTracking bool) (string, error) {
+	parsed, err := urlNet.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return "", err
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", errors.New("only http and https urls can be shortened")
+	}
+
+	host, err := canonicalHost(parsed.Host, scheme)
+	if err != nil {
+		return "", err
+	}
+
+	var canonical strings.Builder
+	canonical.WriteString(scheme)
+	canonical.WriteString("://")
+	if parsed.User != nil {
+		canonical.WriteString(parsed.User.String())
+		canonical.WriteByte('@')
+	}
+	canonical.WriteString(host)
+	canonical.WriteString(canonicalPath(parsed.EscapedPath()))
+
+	if query := canonicalQuery(parsed.RawQuery, stripTracking); query != "" {
+		canonical.WriteByte('?')
+		canonical.WriteString(query)
+	}
+
+	if parsed.Fragment != "" {
+		canonical.WriteByte('#')
+		canonical.WriteString(normalizeEscapes(parsed.EscapedFragment()))
+	}
+
+	return canonical.String(), nil
+}
+
+func canonicalHost(hostPort, scheme string) (string, error) {
+	host, port := hostPort, ""
+	if h, p, err := net.SplitHostPort(hostPort); err == nil {
+		host, port = h, p
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return "", errors.New("url must have a host")
+	}
+
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	} else {
+		asciiHost, err := toASCIIHost(host)
+		if err != nil {
+			return "", err
+		}
+		host = asciiHost
+	}
+
+	if port != "" && port != defaultPorts[scheme] {
+		host += ":" + port
+	}
+	return host, nil
+}
+
+func canonicalPath(escapedPath string) string {
+	path := normalizeEscapes(escapedPath)
+	if path == "" {
+		return "/"
+	}
+	if len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+		if path == "" {
+			path = "/"
+		}
+	}
+	return path
+}
+
+func canonicalQuery(rawQuery string, stripTracking bool) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	type param struct {
+		name  string
+		value string
+		raw   string
+	}
+
+	var params []param
+	for _, part := range strings.Split(rawQuery, "&") {
+		if part == "" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(part, "=")
+		name = normalizeEscapes(name)
+
+		if stripTracking && isTrackingParam(name) {
+			continue
+		}
+
+		raw := name
+		if hasValue {
+			value = normalizeEscapes(value)
+			raw += "=" + value
+		}
+		params = append(params, param{name: name, value: value, raw: raw})
+	}
+
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].name < params[j].name
+	})
+
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		parts = append(parts, p.raw)
+	}
+	return strings.Join(parts, "&")
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return trackingParams[name] || strings.HasPrefix(name, "utm_")
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters, upper-cases the hex digits
+// of the remaining escapes and escapes bytes that are not allowed in a url.
+func normalizeEscapes(component string) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var normalized strings.Builder
+	for i := 0; i < len(component); i++ {
+		c := component[i]
+
+		if c == '%' && i+2 < len(component) && isHex(component[i+1]) && isHex(component[i+2]) {
+			decoded := unhex(component[i+1])<<4 | unhex(component[i+2])
+			if isUnreserved(decoded) {
+				normalized.WriteByte(decoded)
+			} else {
+				normalized.WriteByte('%')
+				normalized.WriteByte(hexDigits[decoded>>4])
+				normalized.WriteByte(hexDigits[decoded&0x0F])
+			}
+			i += 2
+			continue
+		}
+
+		if c <= ' ' || c >= 0x7F || c == '%' || c == '"' || c == '<' || c == '>' || c == '\\' || c == '^' || c == '`' || c == '{' || c == '|' || c == '}' {
+			normalized.WriteByte('%')
+			normalized.WriteByte(hexDigits[c>>4])
+			normalized.WriteByte(hexDigits[c&0x0F])
+			continue
+		}
+
+		normalized.WriteByte(c)
+	}
+	return normalized.String()
+}
+
+func isUnreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -15,4 +15,75 @@ const (
 	// For Server
 	PORT   EnvKey = "PORT"
 	JWTKey EnvKey = "JWT_KEY"
+
+	// For Mail
+	MailDriver EnvKey = "MAIL_DRIVER"
+	MailFrom   EnvKey = "MAIL_FROM"
+	MailDir    EnvKey = "MAIL_DIR"
+	SMTPHost   EnvKey = "SMTP_HOST"
+	SMTPPort   EnvKey = "SMTP_PORT"
+	SMTPUser   EnvKey = "SMTP_USER"
+	SMTPPass   EnvKey = "SMTP_PASS"
+
+	// For Notifications
+	LowVisitThreshold      EnvKey = "LOW_VISIT_THRESHOLD"
+	LinkHealthCheckMinutes EnvKey = "LINK_HEALTH_CHECK_MINUTES"
+
+	// For Redirect Rate Limits
+	RedirectIPRatePerMinute   EnvKey = "REDIRECT_IP_RATE_PER_MINUTE"
+	RedirectIPBurst           EnvKey = "REDIRECT_IP_BURST"
+	RedirectLinkRatePerMinute EnvKey = "REDIRECT_LINK_RATE_PER_MINUTE"
+	RedirectLinkBurst         EnvKey = "REDIRECT_LINK_BURST"
+
+	// For Unique Visitors
+	VisitorSalt              EnvKey = "VISITOR_SALT"
+	VisitorSaltRotationHours EnvKey = "VISITOR_SALT_ROTATION_HOURS"
+
+	// For GeoIP
+	GeoIPDatabase  EnvKey = "GEOIP_DATABASE"
+	TrustedProxies EnvKey = "TRUSTED_PROXIES"
+
+	// For File Links
+	StorageDriver EnvKey = "STORAGE_DRIVER"
+	StorageDir    EnvKey = "STORAGE_DIR"
+	MaxUploadMB   EnvKey = "MAX_UPLOAD_MB"
+
+	// For Long Url Canonicalisation
+	StripTrackingParams EnvKey = "STRIP_TRACKING_PARAMS"
+
+	// For Redirect Chains
+	ShortLinkHosts       EnvKey = "SHORT_LINK_HOSTS"
+	RedirectChainMaxHops EnvKey = "REDIRECT_CHAIN_MAX_HOPS"
+
+	// For Wallet Ledger
+	LedgerReconciliationMinutes EnvKey = "LEDGER_RECONCILIATION_MINUTES"
+
+	// For Idempotency Keys
+	IdempotencyKeyTTLHours     EnvKey = "IDEMPOTENCY_KEY_TTL_HOURS"
+	IdempotencyKeyPurgeMinutes EnvKey = "IDEMPOTENCY_KEY_PURGE_MINUTES"
+
+	// For Payments
+	PaymentGateway       EnvKey = "PAYMENT_GATEWAY"
+	PaymentWebhookSecret EnvKey = "PAYMENT_WEBHOOK_SECRET"
+	RazorpayKeyID        EnvKey = "RAZORPAY_KEY_ID"
+	RazorpayKeySecret    EnvKey = "RAZORPAY_KEY_SECRET"
+
+	// For Withdrawals
+	PayoutProvider EnvKey = "PAYOUT_PROVIDER"
+
+	// For Invoices
+	InvoiceSellerName    EnvKey = "INVOICE_SELLER_NAME"
+	InvoiceSellerAddress EnvKey = "INVOICE_SELLER_ADDRESS"
+	InvoiceSellerGSTIN   EnvKey = "INVOICE_SELLER_GSTIN"
+	InvoiceSellerState   EnvKey = "INVOICE_SELLER_STATE"
+	InvoiceSACCode       EnvKey = "INVOICE_SAC_CODE"
+
+	// For Referrals, credits are in rupees
+	ReferralReferrerCredit   EnvKey = "REFERRAL_REFERRER_CREDIT"
+	ReferralRefereeCredit    EnvKey = "REFERRAL_REFEREE_CREDIT"
+	ReferralReferrerFreeUrls EnvKey = "REFERRAL_REFERRER_FREE_URLS"
+	ReferralRefereeFreeUrls  EnvKey = "REFERRAL_REFEREE_FREE_URLS"
+
+	// For Subscription Plans
+	PlanRenewalMinutes EnvKey = "PLAN_RENEWAL_MINUTES"
 )
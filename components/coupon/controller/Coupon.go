@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+	couponService "url-shortner-be/components/coupon/service"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/security"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/coupon"
+
+	"github.com/gorilla/mux"
+)
+
+type CouponController struct {
+	log           log.Logger
+	CouponService *couponService.CouponService
+}
+
+func NewCouponController(couponService *couponService.CouponService, log log.Logger) *CouponController {
+	return &CouponController{
+		log:           log,
+		CouponService: couponService,
+	}
+}
+
+func (couponController *CouponController) RegisterRoutes(router *mux.Router) {
+
+	couponRouter := router.PathPrefix("/coupons").Subrouter()
+	adminguardedRouter := couponRouter.PathPrefix("/").Subrouter()
+
+	adminguardedRouter.HandleFunc("/", couponController.getAllCoupons).Methods(http.MethodGet)
+	adminguardedRouter.HandleFunc("/", couponController.addCoupon).Methods(http.MethodPost)
+	adminguardedRouter.HandleFunc("/report", couponController.getReport).Methods(http.MethodGet)
+	adminguardedRouter.HandleFunc("/{couponId}", couponController.updateCoupon).Methods(http.MethodPut)
+	adminguardedRouter.HandleFunc("/{couponId}/redemptions", couponController.getRedemptions).Methods(http.MethodGet)
+
+	adminguardedRouter.Use(security.MiddlewareAdmin)
+}
+
+func (controller *CouponController) getAllCoupons(w http.ResponseWriter, r *http.Request) {
+	coupons := []coupon.Coupon{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	if err := controller.CouponService.GetAllCoupons(&coupons, &totalCount, parser); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, coupons)
+}
+
+func (controller *CouponController) addCoupon(w http.ResponseWriter, r *http.Request) {
+	newCoupon := &coupon.Coupon{}
+
+	if err := web.UnmarshalJSON(r, newCoupon); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.CouponService.AddCoupon(newCoupon, userIdFromToken); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newCoupon)
+}
+
+func (controller *CouponController) updateCoupon(w http.ResponseWriter, r *http.Request) {
+	targetCoupon := &coupon.Coupon{}
+	parser := web.NewParser(r)
+
+	couponID, err := parser.GetUUID("couponId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid coupon ID format"))
+		return
+	}
+
+	if err = web.UnmarshalJSON(r, targetCoupon); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+	targetCoupon.ID = couponID
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.CouponService.UpdateCoupon(targetCoupon, userIdFromToken); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, targetCoupon)
+}
+
+func (controller *CouponController) getRedemptions(w http.ResponseWriter, r *http.Request) {
+	redemptions := []coupon.CouponRedemption{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	couponID, err := parser.GetUUID("couponId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid coupon ID format"))
+		return
+	}
+
+	if err = controller.CouponService.GetRedemptions(&redemptions, &totalCount, parser, couponID); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, redemptions)
+}
+
+// getReport returns the discount and bonus given by each coupon, limited to redemptions
+// on or after ?from= and before ?to= when given as dates like 2026-04-01.
+func (controller *CouponController) getReport(w http.ResponseWriter, r *http.Request) {
+	reports := []coupon.CouponReport{}
+
+	from, err := parseDate(r.URL.Query().Get("from"))
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid from date, expected YYYY-MM-DD"))
+		return
+	}
+	to, err := parseDate(r.URL.Query().Get("to"))
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid to date, expected YYYY-MM-DD"))
+		return
+	}
+
+	if err = controller.CouponService.GetReport(&reports, from, to); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, reports)
+}
+
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
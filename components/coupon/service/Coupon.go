@@ -0,0 +1,290 @@
+package service
+
+import (
+	"strings"
+	"time"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/money"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/coupon"
+	"url-shortner-be/model/invoice"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type CouponService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewCouponService(DB *gorm.DB, repo repository.Repository) *CouponService {
+	return &CouponService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+// Redeem applies a coupon to a purchase of the given type and price in the purchase's unit of work,
+// so a purchase that rolls back gives its redemption back. It returns nil when no code was given.
+// The redemption is saved with Record once the purchase has its transaction.
+func (service *CouponService) Redeem(uow *repository.UnitOfWork, code string, userID uuid.UUID, purchaseType string, price money.Money) (*coupon.CouponRedemption, error) {
+	code = normalizeCode(code)
+	if code == "" {
+		return nil, nil
+	}
+
+	existingCoupon := &coupon.Coupon{}
+	if err := service.repository.GetRecord(uow, existingCoupon, repository.Filter("code = ?", code), repository.ForUpdate()); err != nil {
+		return nil, errors.NewValidationError("invalid coupon code")
+	}
+
+	now := time.Now()
+	if existingCoupon.IsActive != nil && !*existingCoupon.IsActive {
+		return nil, errors.NewValidationError("coupon is no longer active")
+	}
+	if existingCoupon.ValidFrom != nil && now.Before(*existingCoupon.ValidFrom) {
+		return nil, errors.NewValidationError("coupon is not valid yet")
+	}
+	if existingCoupon.ValidUntil != nil && now.After(*existingCoupon.ValidUntil) {
+		return nil, errors.NewValidationError("coupon has expired")
+	}
+	if !existingCoupon.AppliesToType(purchaseType) {
+		return nil, errors.NewValidationError("coupon cannot be used for this purchase")
+	}
+	if existingCoupon.MaxRedemptions > 0 && existingCoupon.RedemptionCount >= existingCoupon.MaxRedemptions {
+		return nil, errors.NewValidationError("coupon has been fully redeemed")
+	}
+
+	if existingCoupon.MaxRedemptionsPerUser > 0 {
+		var redeemed int
+		if err := service.repository.GetCount(uow, &coupon.CouponRedemption{}, &redeemed,
+			repository.Filter("coupon_id = ? AND user_id = ?", existingCoupon.ID, userID),
+		); err != nil {
+			return nil, errors.NewDatabaseError("unable to fetch coupon redemptions")
+		}
+		if redeemed >= existingCoupon.MaxRedemptionsPerUser {
+			return nil, errors.NewValidationError("you have already used this coupon the maximum number of times")
+		}
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &coupon.Coupon{}, map[string]interface{}{
+		"redemption_count": gorm.Expr("redemption_count + 1"),
+	}, repository.Filter("id = ?", existingCoupon.ID)); err != nil {
+		return nil, errors.NewDatabaseError("unable to redeem coupon")
+	}
+
+	return &coupon.CouponRedemption{
+		CouponID:      existingCoupon.ID,
+		Code:          existingCoupon.Code,
+		UserID:        userID,
+		Type:          purchaseType,
+		Discount:      existingCoupon.DiscountOn(price),
+		BonusQuantity: existingCoupon.BonusQuantity,
+	}, nil
+}
+
+// Record saves a redemption against the purchase it was applied to, nothing is saved when no coupon was redeemed.
+func (service *CouponService) Record(uow *repository.UnitOfWork, redemption *coupon.CouponRedemption, transactionID *uuid.UUID) error {
+	if redemption == nil {
+		return nil
+	}
+
+	redemption.ID = uuid.NewV4()
+	redemption.TransactionID = transactionID
+	redemption.CreatedBy = redemption.UserID
+
+	if err := service.repository.Add(uow, redemption); err != nil {
+		return errors.NewDatabaseError("unable to record coupon redemption")
+	}
+	return nil
+}
+
+// InvoiceLines are the lines a redemption adds to the invoice of its purchase.
+func InvoiceLines(redemption *coupon.CouponRedemption) []*invoice.InvoiceLine {
+	lines := []*invoice.InvoiceLine{}
+	if redemption == nil {
+		return lines
+	}
+	if redemption.Discount > 0 {
+		lines = append(lines, invoice.NewLine("Coupon "+redemption.Code, 1, -redemption.Discount))
+	}
+	if redemption.BonusQuantity > 0 {
+		lines = append(lines, invoice.NewLine("Bonus from coupon "+redemption.Code, redemption.BonusQuantity, 0))
+	}
+	return lines
+}
+
+// Note describes the redemption in the note of its purchase's transaction.
+func Note(redemption *coupon.CouponRedemption) string {
+	if redemption == nil {
+		return ""
+	}
+	return " with coupon " + redemption.Code
+}
+
+func (service *CouponService) AddCoupon(newCoupon *coupon.Coupon, userIdFromToken uuid.UUID) error {
+
+	newCoupon.Code = normalizeCode(newCoupon.Code)
+	if err := newCoupon.Validate(); err != nil {
+		return err
+	}
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	var count int
+	if err := service.repository.GetCount(uow, &coupon.Coupon{}, &count, repository.Filter("code = ?", newCoupon.Code)); err != nil {
+		return errors.NewDatabaseError("unable to fetch coupons")
+	}
+	if count > 0 {
+		return errors.NewValidationError("coupon code already exists")
+	}
+
+	newCoupon.ID = uuid.NewV4()
+	newCoupon.CreatedBy = userIdFromToken
+	newCoupon.RedemptionCount = 0
+	if newCoupon.IsActive == nil {
+		isActive := true
+		newCoupon.IsActive = &isActive
+	}
+
+	if err := service.repository.Add(uow, newCoupon); err != nil {
+		return errors.NewDatabaseError("unable to save coupon")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// UpdateCoupon changes everything but the code of a coupon, past redemptions keep what they gave.
+func (service *CouponService) UpdateCoupon(targetCoupon *coupon.Coupon, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingCoupon := &coupon.Coupon{}
+	if err := service.repository.GetRecordByID(uow, targetCoupon.ID, existingCoupon, repository.ForUpdate()); err != nil {
+		return errors.NewNotFoundError("coupon not found")
+	}
+
+	targetCoupon.Code = existingCoupon.Code
+	targetCoupon.RedemptionCount = existingCoupon.RedemptionCount
+	if targetCoupon.IsActive == nil {
+		targetCoupon.IsActive = existingCoupon.IsActive
+	}
+	if err := targetCoupon.Validate(); err != nil {
+		return err
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &coupon.Coupon{}, map[string]interface{}{
+		"description":              targetCoupon.Description,
+		"kind":                     targetCoupon.Kind,
+		"percent_off":              targetCoupon.PercentOff,
+		"amount_off_paise":         targetCoupon.AmountOff,
+		"bonus_quantity":           targetCoupon.BonusQuantity,
+		"applies_to":               targetCoupon.AppliesTo,
+		"valid_from":               targetCoupon.ValidFrom,
+		"valid_until":              targetCoupon.ValidUntil,
+		"max_redemptions":          targetCoupon.MaxRedemptions,
+		"max_redemptions_per_user": targetCoupon.MaxRedemptionsPerUser,
+		"is_active":                targetCoupon.IsActive,
+		"updated_by":               userIdFromToken,
+	}, repository.Filter("id = ?", targetCoupon.ID)); err != nil {
+		return errors.NewDatabaseError("unable to update coupon")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *CouponService) GetAllCoupons(coupons *[]coupon.Coupon, totalCount *int, parser *web.Parser) error {
+
+	var queryProcessors []repository.QueryProcessor
+
+	if code := parser.Form.Get("code"); code != "" {
+		queryProcessors = append(queryProcessors, repository.Filter("code LIKE ?", "%"+normalizeCode(code)+"%"))
+	}
+
+	limit, offset := parser.ParseLimitAndOffset()
+	queryProcessors = append(queryProcessors,
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("created_at desc"),
+	)
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetAll(uow, coupons, queryProcessors...); err != nil {
+		return errors.NewDatabaseError("unable to fetch coupons")
+	}
+
+	return nil
+}
+
+func (service *CouponService) GetRedemptions(redemptions *[]coupon.CouponRedemption, totalCount *int, parser *web.Parser, couponID uuid.UUID) error {
+
+	limit, offset := parser.ParseLimitAndOffset()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetAll(uow, redemptions,
+		repository.Filter("coupon_id = ?", couponID),
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("created_at desc"),
+	); err != nil {
+		return errors.NewDatabaseError("unable to fetch coupon redemptions")
+	}
+
+	return nil
+}
+
+// GetReport sums the discount and bonus each coupon gave in redemptions made from from until to,
+// either of which may be nil to leave that end open.
+func (service *CouponService) GetReport(reports *[]coupon.CouponReport, from, to *time.Time) error {
+
+	conditions := []string{"r.deleted_at IS NULL"}
+	values := []interface{}{}
+	if from != nil {
+		conditions = append(conditions, "r.created_at >= ?")
+		values = append(values, *from)
+	}
+	if to != nil {
+		conditions = append(conditions, "r.created_at < ?")
+		values = append(values, *to)
+	}
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetRaw(uow, reports, repository.RawQuery(`
+		SELECT c.id AS coupon_id, c.code, c.kind, COUNT(r.id) AS redemptions,
+		 COALESCE(SUM(r.discount_paise), 0) AS discount, COALESCE(SUM(r.bonus_quantity), 0) AS bonus_quantity
+		FROM coupons c
+		LEFT JOIN coupon_redemptions r ON r.coupon_id = c.id AND `+strings.Join(conditions, " AND ")+`
+		WHERE c.deleted_at IS NULL
+		GROUP BY c.id, c.code, c.kind
+		ORDER BY discount DESC, c.code
+	`, values...)); err != nil {
+		return errors.NewDatabaseError("unable to fetch coupon report")
+	}
+
+	return nil
+}
+
+// ---------------- Helpers ----------------
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
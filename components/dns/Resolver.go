@@ -0,0 +1,34 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// Resolver looks up DNS TXT records, it is an interface so that verification can be faked in tests.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// NewResolver returns the system resolver.
+func NewResolver() Resolver {
+	return net.DefaultResolver
+}
+
+// StaticResolver answers TXT lookups from a fixed set of records, or fails every lookup with Err when it is set.
+type StaticResolver struct {
+	Records map[string][]string
+	Err     error
+}
+
+func (resolver *StaticResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if resolver.Err != nil {
+		return nil, resolver.Err
+	}
+	records, ok := resolver.Records[strings.TrimSuffix(strings.ToLower(name), ".")]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"net/http"
+	domainService "url-shortner-be/components/domain/service"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/security"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/domain"
+
+	"github.com/gorilla/mux"
+)
+
+type DomainController struct {
+	log           log.Logger
+	DomainService *domainService.DomainService
+}
+
+func NewDomainController(domainService *domainService.DomainService, log log.Logger) *DomainController {
+	return &DomainController{
+		log:           log,
+		DomainService: domainService,
+	}
+}
+
+func (domainController *DomainController) RegisterRoutes(router *mux.Router) {
+
+	domainRouter := router.PathPrefix("/domains").Subrouter()
+
+	domainRouter.HandleFunc("", domainController.addDomain).Methods(http.MethodPost)
+	domainRouter.HandleFunc("", domainController.getAllDomains).Methods(http.MethodGet)
+	domainRouter.HandleFunc("/{domainId}/verify", domainController.verifyDomain).Methods(http.MethodPost)
+	domainRouter.HandleFunc("/{domainId}", domainController.deleteDomain).Methods(http.MethodDelete)
+
+	domainRouter.Use(security.MiddlewareUser)
+}
+
+func (controller *DomainController) addDomain(w http.ResponseWriter, r *http.Request) {
+	newDomain := &domain.Domain{}
+
+	err := web.UnmarshalJSON(r, newDomain)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := newDomain.Validate(); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	newDomain.UserID, err = security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.DomainService.AddDomain(newDomain); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newDomain)
+}
+
+func (controller *DomainController) getAllDomains(w http.ResponseWriter, r *http.Request) {
+	domains := []domain.Domain{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.DomainService.GetAllDomains(&domains, &totalCount, parser, userIdFromToken); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, domains)
+}
+
+func (controller *DomainController) verifyDomain(w http.ResponseWriter, r *http.Request) {
+	targetDomain := &domain.Domain{}
+	parser := web.NewParser(r)
+
+	domainID, err := parser.GetUUID("domainId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid domain ID format"))
+		return
+	}
+	targetDomain.ID = domainID
+
+	targetDomain.UserID, err = security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.DomainService.VerifyDomain(targetDomain); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Domain verified successfully",
+	})
+}
+
+func (controller *DomainController) deleteDomain(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	domainID, err := parser.GetUUID("domainId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid domain ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.DomainService.DeleteDomain(domainID, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Domain deleted successfully",
+	})
+}
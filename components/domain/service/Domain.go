@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"time"
+	"url-shortner-be/components/dns"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/domain"
+	"url-shortner-be/model/url"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type DomainService struct {
+	db         *gorm.DB
+	repository repository.Repository
+	resolver   dns.Resolver
+}
+
+func NewDomainService(DB *gorm.DB, repo repository.Repository, resolver dns.Resolver) *DomainService {
+	return &DomainService{
+		db:         DB,
+		repository: repo,
+		resolver:   resolver,
+	}
+}
+
+func (service *DomainService) AddDomain(newDomain *domain.Domain) error {
+
+	if err := user.Exists(service.db, newDomain.UserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	domainOwner := user.User{}
+	if err := service.repository.GetRecordByID(uow, newDomain.UserID, &domainOwner); err != nil {
+		return errors.NewDatabaseError("unable to get user record")
+	}
+
+	if !*domainOwner.IsActive {
+		return errors.NewValidationError("Inactive user cannot add domains")
+	}
+
+	// Other users' unverified claims of the host do not stop this one, only a verified domain does.
+	var count int
+	if err := service.repository.GetCount(uow, &domain.Domain{}, &count,
+		repository.Filter("host = ? AND (is_verified = ? OR user_id = ?)", newDomain.Host, true, newDomain.UserID)); err != nil {
+		return errors.NewDatabaseError("unable to check domain")
+	}
+	if count > 0 {
+		return errors.NewValidationError("This domain is already registered")
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return errors.NewDatabaseError("unable to generate verification token")
+	}
+
+	isVerified := false
+	newDomain.VerificationToken = hex.EncodeToString(token)
+	newDomain.IsVerified = &isVerified
+	newDomain.VerifiedAt = nil
+	newDomain.CreatedBy = newDomain.UserID
+
+	if err := service.repository.Add(uow, newDomain); err != nil {
+		return errors.NewDatabaseError("unable to add domain")
+	}
+
+	uow.Commit()
+	newDomain.SetVerificationDetails()
+	return nil
+}
+
+func (service *DomainService) GetAllDomains(domains *[]domain.Domain, totalCount *int, parser *web.Parser, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	limit, offset := parser.ParseLimitAndOffset()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetAll(uow, domains, repository.Filter("user_id = ?", userIdFromToken),
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("created_at desc")); err != nil {
+		return errors.NewDatabaseError("unable to fetch domains")
+	}
+
+	for i := range *domains {
+		(*domains)[i].SetVerificationDetails()
+	}
+	return nil
+}
+
+// VerifyDomain checks that the owner published the verification token as a TXT record of the domain.
+func (service *DomainService) VerifyDomain(targetDomain *domain.Domain) error {
+
+	if err := user.Exists(service.db, targetDomain.UserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	if err := service.repository.GetRecord(uow, targetDomain, repository.Filter("id = ? AND user_id = ?", targetDomain.ID, targetDomain.UserID)); err != nil {
+		return errors.NewValidationError("no domain found for this user with given domain id")
+	}
+	targetDomain.SetVerificationDetails()
+
+	if targetDomain.IsVerified != nil && *targetDomain.IsVerified {
+		return nil
+	}
+
+	if err := service.checkVerificationRecord(targetDomain); err != nil {
+		return err
+	}
+
+	var verifiedCount int
+	if err := service.repository.GetCount(uow, &domain.Domain{}, &verifiedCount,
+		repository.Filter("host = ? AND is_verified = ?", targetDomain.Host, true)); err != nil {
+		return errors.NewDatabaseError("unable to check domain")
+	}
+	if verifiedCount > 0 {
+		return errors.NewValidationError("This domain is already verified by another user")
+	}
+
+	// The unique verified_host index settles two owners verifying at the same time.
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, targetDomain, map[string]interface{}{
+		"is_verified":   true,
+		"verified_at":   now,
+		"verified_host": targetDomain.Host,
+		"updated_by":    targetDomain.UserID,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to verify domain")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *DomainService) DeleteDomain(domainID, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingDomain := &domain.Domain{}
+	if err := service.repository.GetRecord(uow, existingDomain, repository.Filter("id = ? AND user_id = ?", domainID, userIdFromToken)); err != nil {
+		return errors.NewValidationError("no domain found for this user with given domain id")
+	}
+
+	var urlCount int
+	if err := service.repository.GetCount(uow, &url.Url{}, &urlCount, repository.Filter("domain_id = ?", existingDomain.ID)); err != nil {
+		return errors.NewDatabaseError("unable to fetch urls of domain")
+	}
+	if urlCount > 0 {
+		return errors.NewValidationError("delete the urls of this domain before deleting the domain")
+	}
+
+	// The host is released so that it can be registered again.
+	if err := service.repository.UpdateWithMap(uow, existingDomain, map[string]interface{}{
+		"host":          existingDomain.ID.String(),
+		"verified_host": nil,
+		"deleted_at":    time.Now(),
+		"deleted_by":    userIdFromToken,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to delete domain")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// ---------------- Helpers ----------------
+
+// checkVerificationRecord looks for the domain's verification token among the TXT records of its verification record.
+func (service *DomainService) checkVerificationRecord(targetDomain *domain.Domain) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	records, err := service.resolver.LookupTXT(ctx, targetDomain.VerificationRecord)
+	if dnsError, ok := err.(*net.DNSError); ok && dnsError.IsNotFound {
+		return errors.NewValidationError("verification TXT record not found, it can take a while for DNS changes to propagate")
+	}
+	if err != nil {
+		log.GetLogger().Print("TXT lookup of ", targetDomain.VerificationRecord, " failed: ", err.Error())
+		return errors.NewHTTPError("unable to look up the verification TXT record, please try again later", http.StatusBadGateway)
+	}
+
+	for _, record := range records {
+		if record == targetDomain.VerificationValue {
+			return nil
+		}
+	}
+	return errors.NewValidationError("verification TXT record does not contain the verification value of this domain")
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"url-shortner-be/components/dns"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/model/domain"
+)
+
+func TestCheckVerificationRecord(t *testing.T) {
+	pendingDomain := &domain.Domain{Host: "go.example.com", VerificationToken: "0123456789abcdef"}
+	pendingDomain.SetVerificationDetails()
+
+	tests := []struct {
+		name       string
+		resolver   *dns.StaticResolver
+		wantErr    bool
+		wantStatus int
+	}{
+		{
+			name: "verify",
+			resolver: &dns.StaticResolver{Records: map[string][]string{
+				"_url-shortner-verify.go.example.com": {"v=spf1 -all", pendingDomain.VerificationValue},
+			}},
+		},
+		{
+			name:       "reject when the record is missing",
+			resolver:   &dns.StaticResolver{Records: map[string][]string{}},
+			wantErr:    true,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "mismatch",
+			resolver: &dns.StaticResolver{Records: map[string][]string{
+				"_url-shortner-verify.go.example.com": {domain.VerificationValuePrefix + "someone-elses-token"},
+			}},
+			wantErr:    true,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "lookup error",
+			resolver:   &dns.StaticResolver{Err: &net.DNSError{Err: "server misbehaving", Name: pendingDomain.VerificationRecord, IsTemporary: true}},
+			wantErr:    true,
+			wantStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := &DomainService{resolver: test.resolver}
+
+			err := service.checkVerificationRecord(pendingDomain)
+			if !test.wantErr {
+				if err != nil {
+					t.Fatalf("expected the domain to verify, got %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected verification to fail")
+			}
+			if status := statusOf(err); status != test.wantStatus {
+				t.Errorf("expected status %d, got %d (%v)", test.wantStatus, status, err)
+			}
+		})
+	}
+}
+
+func statusOf(err error) int {
+	switch e := err.(type) {
+	case *errors.ValidationError:
+		return http.StatusBadRequest
+	case *errors.HTTPError:
+		return e.HTTPStatus
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+type ipRange struct {
+	start    net.IP
+	end      net.IP
+	location Location
+}
+
+// CSVDatabase resolves addresses from rows of start_ip,end_ip,country_code,country_name,region,city.
+type CSVDatabase struct {
+	ranges []ipRange
+}
+
+func OpenCSV(path string) (*CSVDatabase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadCSV(file)
+}
+
+func ReadCSV(reader io.Reader) (*CSVDatabase, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.Comment = '#'
+
+	database := &CSVDatabase{}
+	for line := 1; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: expected at least start_ip,end_ip,country_code", line)
+		}
+
+		start, end := net.ParseIP(strings.TrimSpace(record[0])), net.ParseIP(strings.TrimSpace(record[1]))
+		if start == nil || end == nil {
+			if line == 1 {
+				// header row
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid ip range", line)
+		}
+
+		database.ranges = append(database.ranges, ipRange{
+			start:    start.To16(),
+			end:      end.To16(),
+			location: Location{CountryCode: strings.ToUpper(field(record, 2)), CountryName: field(record, 3), Region: field(record, 4), City: field(record, 5)},
+		})
+	}
+
+	sort.Slice(database.ranges, func(i, j int) bool {
+		return bytes.Compare(database.ranges[i].start, database.ranges[j].start) < 0
+	})
+	return database, nil
+}
+
+func (database *CSVDatabase) Lookup(ip net.IP) (*Location, error) {
+	ip = ip.To16()
+	if ip == nil {
+		return nil, nil
+	}
+
+	// first range starting after the address, the candidate is the one before it
+	index := sort.Search(len(database.ranges), func(i int) bool {
+		return bytes.Compare(database.ranges[i].start, ip) > 0
+	}) - 1
+
+	if index < 0 || bytes.Compare(ip, database.ranges[index].end) > 0 {
+		return nil, nil
+	}
+
+	location := database.ranges[index].location
+	return &location, nil
+}
+
+func field(record []string, index int) string {
+	if index < len(record) {
+		return strings.TrimSpace(record[index])
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package geoip
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestCSVLookup(t *testing.T) {
+	database, err := Open("testdata/geoip-sample.csv")
+	if err != nil {
+		t.Fatalf("unable to open sample database: %v", err)
+	}
+
+	tests := []struct {
+		ip          string
+		wantCountry string
+		wantRegion  string
+		wantCity    string
+	}{
+		{"1.0.0.5", "AU", "Queensland", "Brisbane"},
+		{"8.8.8.0", "US", "California", "Mountain View"},
+		{"8.8.8.255", "US", "California", "Mountain View"},
+		{"49.36.128.1", "IN", "Maharashtra", "Mumbai"},
+		{"103.21.245.9", "IN", "Karnataka", "Bengaluru"},
+		{"81.2.69.160", "GB", "England", "London"},
+		{"2001:4860::8888", "US", "California", "Mountain View"},
+		{"8.8.9.0", "", "", ""},
+		{"9.9.9.9", "", "", ""},
+		{"0.0.0.1", "", "", ""},
+		{"2001:db8::1", "", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ip, func(t *testing.T) {
+			location, err := database.Lookup(net.ParseIP(test.ip))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if test.wantCountry == "" {
+				if location != nil {
+					t.Fatalf("expected no location, got %+v", location)
+				}
+				return
+			}
+
+			if location == nil {
+				t.Fatal("expected a location")
+			}
+			if location.CountryCode != test.wantCountry || location.Region != test.wantRegion || location.City != test.wantCity {
+				t.Errorf("expected %s/%s/%s, got %s/%s/%s", test.wantCountry, test.wantRegion, test.wantCity,
+					location.CountryCode, location.Region, location.City)
+			}
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr bool
+	}{
+		{"header and comments", "start_ip,end_ip,country_code\n# comment\n1.0.0.0,1.0.0.255,au\n", false},
+		{"without header", "1.0.0.0,1.0.0.255,AU\n", false},
+		{"invalid range", "start_ip,end_ip,country_code\n1.0.0.0,nope,AU\n", true},
+		{"missing country", "1.0.0.0,1.0.0.255\n", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			database, err := ReadCSV(strings.NewReader(test.csv))
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			location, _ := database.Lookup(net.ParseIP("1.0.0.1"))
+			if location == nil || location.CountryCode != "AU" {
+				t.Errorf("expected AU, got %+v", location)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+)
+
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// MMDBReader reads the MaxMind DB format (GeoLite2 / GeoIP2 City and Country databases).
+type MMDBReader struct {
+	buffer         []byte
+	nodeCount      uint
+	recordSize     uint
+	ipVersion      uint
+	treeSize       uint
+	dataSection    []byte
+	ipv4StartNode  uint
+	ipv4StartDepth int
+}
+
+func OpenMMDB(path string) (*MMDBReader, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMMDBReader(buffer)
+}
+
+func NewMMDBReader(buffer []byte) (*MMDBReader, error) {
+	markerIndex := bytes.LastIndex(buffer, metadataMarker)
+	if markerIndex == -1 {
+		return nil, errors.New("invalid mmdb file, metadata not found")
+	}
+
+	metadataStart := markerIndex + len(metadataMarker)
+	metadataDecoder := &decoder{buffer: buffer[metadataStart:]}
+	value, _, err := metadataDecoder.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mmdb metadata: %w", err)
+	}
+
+	metadata, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid mmdb metadata")
+	}
+
+	reader := &MMDBReader{
+		buffer:     buffer,
+		nodeCount:  toUint(metadata["node_count"]),
+		recordSize: toUint(metadata["record_size"]),
+		ipVersion:  toUint(metadata["ip_version"]),
+	}
+
+	if reader.recordSize != 24 && reader.recordSize != 28 && reader.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported mmdb record size %d", reader.recordSize)
+	}
+
+	reader.treeSize = reader.recordSize * 2 / 8 * reader.nodeCount
+	dataStart := reader.treeSize + 16
+	if dataStart > uint(markerIndex) {
+		return nil, errors.New("invalid mmdb file, search tree exceeds file size")
+	}
+	reader.dataSection = buffer[dataStart:markerIndex]
+
+	if reader.ipVersion == 6 {
+		node := uint(0)
+		depth := 0
+		for ; depth < 96 && node < reader.nodeCount; depth++ {
+			node = reader.readRecord(node, 0)
+		}
+		reader.ipv4StartNode, reader.ipv4StartDepth = node, depth
+	}
+
+	return reader, nil
+}
+
+func (reader *MMDBReader) Lookup(ip net.IP) (*Location, error) {
+	node, bitCount := uint(0), 128
+	address := ip.To16()
+
+	if ipv4 := ip.To4(); ipv4 != nil {
+		address, bitCount = ipv4, 32
+		if reader.ipVersion == 6 {
+			node = reader.ipv4StartNode
+		}
+	} else if reader.ipVersion == 4 {
+		return nil, nil
+	}
+	if address == nil {
+		return nil, nil
+	}
+
+	for i := 0; i < bitCount && node < reader.nodeCount; i++ {
+		bit := uint(address[i>>3]>>(7-uint(i%8))) & 1
+		node = reader.readRecord(node, bit)
+	}
+
+	if node == reader.nodeCount {
+		return nil, nil
+	}
+	if node < reader.nodeCount {
+		return nil, errors.New("invalid mmdb search tree")
+	}
+
+	offset := node - reader.nodeCount - 16
+	dataDecoder := &decoder{buffer: reader.dataSection}
+	value, _, err := dataDecoder.decode(offset)
+	if err != nil {
+		return nil, err
+	}
+
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	location := &Location{
+		CountryCode: lookupString(record, "country", "iso_code"),
+		CountryName: lookupString(record, "country", "names", "en"),
+		City:        lookupString(record, "city", "names", "en"),
+	}
+	if subdivisions, ok := record["subdivisions"].([]interface{}); ok && len(subdivisions) > 0 {
+		if subdivision, ok := subdivisions[0].(map[string]interface{}); ok {
+			location.Region = lookupString(subdivision, "names", "en")
+		}
+	}
+	return location, nil
+}
+
+func (reader *MMDBReader) readRecord(node, bit uint) uint {
+	switch reader.recordSize {
+	case 24:
+		offset := node*6 + bit*3
+		b := reader.buffer[offset : offset+3]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		offset := node * 7
+		b := reader.buffer[offset : offset+7]
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		offset := node*8 + bit*4
+		return uint(binary.BigEndian.Uint32(reader.buffer[offset : offset+4]))
+	}
+}
+
+// ---------------- Data section decoder ----------------
+
+const (
+	typeExtended = 0
+	typePointer  = 1
+	typeString   = 2
+	typeDouble   = 3
+	typeBytes    = 4
+	typeUint16   = 5
+	typeUint32   = 6
+	typeMap      = 7
+	typeInt32    = 8
+	typeUint64   = 9
+	typeUint128  = 10
+	typeArray    = 11
+	typeBoolean  = 14
+	typeFloat    = 15
+)
+
+type decoder struct {
+	buffer []byte
+}
+
+// decode returns the value at offset and the offset just after it.
+func (d *decoder) decode(offset uint) (interface{}, uint, error) {
+	if offset >= uint(len(d.buffer)) {
+		return nil, 0, errors.New("unexpected end of mmdb data")
+	}
+
+	control := d.buffer[offset]
+	offset++
+	dataType := uint(control >> 5)
+
+	if dataType == typePointer {
+		pointer, next, err := d.decodePointer(control, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer)
+		return value, next, err
+	}
+
+	if dataType == typeExtended {
+		if offset >= uint(len(d.buffer)) {
+			return nil, 0, errors.New("unexpected end of mmdb data")
+		}
+		dataType = 7 + uint(d.buffer[offset])
+		offset++
+	}
+
+	size, offset, err := d.decodeSize(control, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch dataType {
+	case typeMap:
+		values := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, next, err := d.decode(next)
+			if err != nil {
+				return nil, 0, err
+			}
+			keyString, _ := key.(string)
+			values[keyString] = value
+			offset = next
+		}
+		return values, offset, nil
+	case typeArray:
+		values := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			values = append(values, value)
+			offset = next
+		}
+		return values, offset, nil
+	case typeBoolean:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buffer)) {
+		return nil, 0, errors.New("unexpected end of mmdb data")
+	}
+	raw := d.buffer[offset : offset+size]
+	next := offset + size
+
+	switch dataType {
+	case typeString:
+		return string(raw), next, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), raw...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid mmdb double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid mmdb float size")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		var value uint64
+		for _, b := range raw {
+			value = value<<8 | uint64(b)
+		}
+		return value, next, nil
+	case typeInt32:
+		var value uint32
+		for _, b := range raw {
+			value = value<<8 | uint32(b)
+		}
+		return int64(int32(value)), next, nil
+	}
+
+	return nil, next, fmt.Errorf("unsupported mmdb data type %d", dataType)
+}
+
+func (d *decoder) decodePointer(control byte, offset uint) (uint, uint, error) {
+	pointerSize := uint((control>>3)&0x3) + 1
+	if offset+pointerSize > uint(len(d.buffer)) {
+		return 0, 0, errors.New("unexpected end of mmdb data")
+	}
+
+	var prefix uint
+	if pointerSize != 4 {
+		prefix = uint(control & 0x7)
+	}
+
+	pointer := prefix
+	for _, b := range d.buffer[offset : offset+pointerSize] {
+		pointer = pointer<<8 | uint(b)
+	}
+
+	switch pointerSize {
+	case 2:
+		pointer += 2048
+	case 3:
+		pointer += 526336
+	}
+	return pointer, offset + pointerSize, nil
+}
+
+func (d *decoder) decodeSize(control byte, offset uint) (uint, uint, error) {
+	size := uint(control & 0x1f)
+	if size < 29 {
+		return size, offset, nil
+	}
+
+	extra := size - 28
+	if offset+extra > uint(len(d.buffer)) {
+		return 0, 0, errors.New("unexpected end of mmdb data")
+	}
+
+	var value uint
+	for _, b := range d.buffer[offset : offset+extra] {
+		value = value<<8 | uint(b)
+	}
+
+	switch size {
+	case 29:
+		value += 29
+	case 30:
+		value += 285
+	default:
+		value += 65821
+	}
+	return value, offset + extra, nil
+}
+
+func lookupString(record map[string]interface{}, path ...string) string {
+	var current interface{} = record
+	for _, key := range path {
+		values, ok := current.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		current = values[key]
+	}
+	value, _ := current.(string)
+	return value
+}
+
+func toUint(value interface{}) uint {
+	switch typed := value.(type) {
+	case uint64:
+		return uint(typed)
+	case int64:
+		return uint(typed)
+	}
+	return 0
+}
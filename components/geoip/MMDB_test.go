@@ -0,0 +1,140 @@
+package geoip
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestMMDBLookup(t *testing.T) {
+	reader, err := NewMMDBReader(buildMMDB(net.ParseIP("81.2.69.0"), 24))
+	if err != nil {
+		t.Fatalf("unable to read database: %v", err)
+	}
+
+	tests := []struct {
+		ip           string
+		wantLocation *Location
+	}{
+		{"81.2.69.0", &Location{CountryCode: "GB", CountryName: "United Kingdom", Region: "England", City: "London"}},
+		{"81.2.69.160", &Location{CountryCode: "GB", CountryName: "United Kingdom", Region: "England", City: "London"}},
+		{"81.2.69.255", &Location{CountryCode: "GB", CountryName: "United Kingdom", Region: "England", City: "London"}},
+		{"81.2.70.1", nil},
+		{"8.8.8.8", nil},
+		{"2001:db8::1", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ip, func(t *testing.T) {
+			location, err := reader.Lookup(net.ParseIP(test.ip))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if test.wantLocation == nil {
+				if location != nil {
+					t.Fatalf("expected no location, got %+v", location)
+				}
+				return
+			}
+
+			if location == nil || *location != *test.wantLocation {
+				t.Errorf("expected %+v, got %+v", test.wantLocation, location)
+			}
+		})
+	}
+}
+
+func TestNewMMDBReaderRejectsInvalidFiles(t *testing.T) {
+	valid := buildMMDB(net.ParseIP("81.2.69.0"), 24)
+
+	tests := []struct {
+		name   string
+		buffer []byte
+	}{
+		{"empty", nil},
+		{"csv instead of mmdb", []byte("1.0.0.0,1.0.0.255,AU\n")},
+		{"truncated search tree", append(valid[:10:10], valid[bytes.LastIndex(valid, metadataMarker):]...)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewMMDBReader(test.buffer); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// buildMMDB writes an IPv4 database with 24 bit records in which only the given prefix has a location.
+func buildMMDB(prefix net.IP, prefixLength int) []byte {
+	address := prefix.To4()
+	nodeCount := uint(prefixLength)
+	dataPointer := nodeCount + 16
+
+	var tree bytes.Buffer
+	for depth := 0; depth < prefixLength; depth++ {
+		bit := (address[depth/8] >> (7 - uint(depth%8))) & 1
+
+		match := uint(depth + 1)
+		if depth == prefixLength-1 {
+			match = dataPointer
+		}
+		records := [2]uint{nodeCount, nodeCount}
+		records[bit] = match
+
+		for _, record := range records {
+			tree.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+		}
+	}
+
+	var data bytes.Buffer
+	writeMap(&data, 3)
+	writeString(&data, "country")
+	writeMap(&data, 2)
+	writeString(&data, "iso_code")
+	writeString(&data, "GB")
+	writeString(&data, "names")
+	writeMap(&data, 1)
+	writeString(&data, "en")
+	writeString(&data, "United Kingdom")
+	writeString(&data, "city")
+	writeMap(&data, 1)
+	writeString(&data, "names")
+	writeMap(&data, 1)
+	writeString(&data, "en")
+	writeString(&data, "London")
+	writeString(&data, "subdivisions")
+	data.Write([]byte{1, typeArray - 7})
+	writeMap(&data, 1)
+	writeString(&data, "names")
+	writeMap(&data, 1)
+	writeString(&data, "en")
+	writeString(&data, "England")
+
+	var metadata bytes.Buffer
+	writeMap(&metadata, 3)
+	writeString(&metadata, "node_count")
+	metadata.Write([]byte{typeUint32<<5 | 4, 0, 0, 0, byte(nodeCount)})
+	writeString(&metadata, "record_size")
+	metadata.Write([]byte{typeUint16<<5 | 1, 24})
+	writeString(&metadata, "ip_version")
+	metadata.Write([]byte{typeUint16<<5 | 1, 4})
+
+	var buffer bytes.Buffer
+	buffer.Write(tree.Bytes())
+	buffer.Write(make([]byte, 16))
+	buffer.Write(data.Bytes())
+	buffer.Write(metadataMarker)
+	buffer.Write(metadata.Bytes())
+	return buffer.Bytes()
+}
+
+func writeMap(buffer *bytes.Buffer, size int) {
+	buffer.WriteByte(typeMap<<5 | byte(size))
+}
+
+func writeString(buffer *bytes.Buffer, value string) {
+	buffer.WriteByte(typeString<<5 | byte(len(value)))
+	buffer.WriteString(value)
+}
@@ -0,0 +1,54 @@
+package geoip
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"url-shortner-be/components/config"
+	"url-shortner-be/components/log"
+)
+
+type Location struct {
+	CountryCode string `json:"countryCode"`
+	CountryName string `json:"countryName"`
+	Region      string `json:"region"`
+	City        string `json:"city"`
+}
+
+// Resolver maps an IP address to a location, a nil location means the address is unknown.
+type Resolver interface {
+	Lookup(ip net.IP) (*Location, error)
+}
+
+// Open loads a MaxMind (.mmdb) or CSV range database from disk.
+func Open(path string) (Resolver, error) {
+	if strings.EqualFold(filepath.Ext(path), ".mmdb") {
+		return OpenMMDB(path)
+	}
+	return OpenCSV(path)
+}
+
+var (
+	defaultResolver Resolver
+	defaultOnce     sync.Once
+)
+
+// Default returns the resolver for GEOIP_DATABASE, loaded once, or nil when none is configured.
+func Default() Resolver {
+	defaultOnce.Do(func() {
+		path := config.GeoIPDatabase.GetStringValue()
+		if path == "" {
+			log.GetLogger().Warn("GeoIP database not configured, click locations will not be recorded")
+			return
+		}
+
+		resolver, err := Open(path)
+		if err != nil {
+			log.GetLogger().Error("unable to load GeoIP database ", path, ": ", err.Error())
+			return
+		}
+		defaultResolver = resolver
+	})
+	return defaultResolver
+}
@@ -0,0 +1,141 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/security"
+	"url-shortner-be/components/web"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Guard makes handlers safe to retry. A request carrying an Idempotency-Key header runs once,
+// later requests with the same key and body get the stored response replayed.
+type Guard struct {
+	store Store
+	ttl   time.Duration
+}
+
+func NewGuard(store Store, ttl time.Duration) *Guard {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &Guard{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+func (guard *Guard) Wrap(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderKey)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxKeyLength {
+			web.RespondError(w, errors.NewValidationError("Idempotency-Key must not be longer than 255 characters"))
+			return
+		}
+
+		userIdFromToken, err := security.ExtractUserIDFromToken(r)
+		if err != nil {
+			web.RespondError(w, err)
+			return
+		}
+		scope := userIdFromToken.String()
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			web.RespondError(w, errors.NewHTTPError("Unable to read request body", http.StatusBadRequest))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		requestHash := hashRequest(r, body)
+
+		existing, err := guard.store.Reserve(scope, key, requestHash, time.Now().Add(guard.ttl))
+		if err != nil {
+			log.GetLogger().Error("unable to reserve idempotency key: ", err.Error())
+			web.RespondError(w, errors.NewDatabaseError("unable to process idempotency key"))
+			return
+		}
+
+		if existing != nil {
+			switch {
+			case existing.RequestHash != requestHash:
+				web.RespondError(w, errors.NewHTTPError("Idempotency-Key was already used with a different request", http.StatusUnprocessableEntity))
+			case !existing.IsCompleted():
+				web.RespondError(w, errors.NewHTTPError("a request with this Idempotency-Key is still in progress", http.StatusConflict))
+			default:
+				if existing.ContentType != "" {
+					w.Header().Set("Content-Type", existing.ContentType)
+				}
+				w.Header().Set(HeaderReplayed, "true")
+				w.WriteHeader(existing.StatusCode)
+				w.Write([]byte(existing.ResponseBody))
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		// Server errors are not remembered so that the client can retry them.
+		if recorder.statusCode >= http.StatusInternalServerError {
+			err = guard.store.Release(scope, key)
+		} else {
+			err = guard.store.Complete(scope, key, recorder.statusCode, w.Header().Get("Content-Type"), recorder.body.Bytes())
+		}
+		if err != nil {
+			log.GetLogger().Error("unable to store idempotency key: ", err.Error())
+		}
+	})
+}
+
+// PurgeExpired is the background job removing keys past their window.
+func (guard *Guard) PurgeExpired() {
+	if err := guard.store.PurgeExpired(time.Now()); err != nil {
+		log.GetLogger().Error("unable to purge idempotency keys: ", err.Error())
+	}
+}
+
+// hashRequest covers the method and path so that a key reused on another endpoint counts as a different request.
+func hashRequest(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (recorder *responseRecorder) WriteHeader(statusCode int) {
+	if !recorder.wroteHeader {
+		recorder.statusCode = statusCode
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (recorder *responseRecorder) Write(data []byte) (int, error) {
+	recorder.wroteHeader = true
+	recorder.body.Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
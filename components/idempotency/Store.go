@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"time"
+	"url-shortner-be/model/idempotency"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Store persists idempotency keys, keys are unique per scope (the requesting user).
+type Store interface {
+	// Reserve claims the key for a new request. When an unexpired record already holds the key,
+	// that record is returned and nothing is claimed.
+	Reserve(scope, key, requestHash string, expiresAt time.Time) (*idempotency.IdempotencyKey, error)
+	Complete(scope, key string, statusCode int, contentType string, body []byte) error
+	Release(scope, key string) error
+	PurgeExpired(now time.Time) error
+}
+
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{
+		db: db,
+	}
+}
+
+func (store *GormStore) Reserve(scope, key, requestHash string, expiresAt time.Time) (*idempotency.IdempotencyKey, error) {
+	record := &idempotency.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   expiresAt,
+	}
+	if err := store.db.Create(record).Error; err == nil {
+		return nil, nil
+	}
+
+	// The key exists, an expired record is taken over as if it were new.
+	now := time.Now()
+	takeOver := store.db.Unscoped().Model(&idempotency.IdempotencyKey{}).
+		Where("scope = ? AND idempotency_key = ? AND expires_at <= ?", scope, key, now).
+		Updates(map[string]interface{}{
+			"request_hash":  requestHash,
+			"status_code":   0,
+			"content_type":  "",
+			"response_body": "",
+			"completed_at":  nil,
+			"expires_at":    expiresAt,
+			"deleted_at":    nil,
+			"updated_at":    now,
+		})
+	if takeOver.Error != nil {
+		return nil, takeOver.Error
+	}
+	if takeOver.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	existing := &idempotency.IdempotencyKey{}
+	if err := store.db.Where("scope = ? AND idempotency_key = ?", scope, key).First(existing).Error; err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (store *GormStore) Complete(scope, key string, statusCode int, contentType string, body []byte) error {
+	now := time.Now()
+	return store.db.Model(&idempotency.IdempotencyKey{}).
+		Where("scope = ? AND idempotency_key = ?", scope, key).
+		Updates(map[string]interface{}{
+			"status_code":   statusCode,
+			"content_type":  contentType,
+			"response_body": string(body),
+			"completed_at":  now,
+			"updated_at":    now,
+		}).Error
+}
+
+// Release forgets a key whose request did not finish so that the client can retry with it.
+func (store *GormStore) Release(scope, key string) error {
+	return store.db.Unscoped().
+		Where("scope = ? AND idempotency_key = ?", scope, key).
+		Delete(&idempotency.IdempotencyKey{}).Error
+}
+
+func (store *GormStore) PurgeExpired(now time.Time) error {
+	return store.db.Unscoped().
+		Where("expires_at <= ?", now).
+		Delete(&idempotency.IdempotencyKey{}).Error
+}
@@ -0,0 +1,202 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+
+	// MaxLinks is the largest number of links accepted in a single import.
+	MaxLinks = 1000
+)
+
+// Link is one link read from an export of another shortener.
+type Link struct {
+	Row       int
+	LongUrl   string
+	ShortCode string
+	Clicks    int
+}
+
+// Column names used by the exports of Bitly, Rebrandly, TinyURL, YOURLS, Short.io and similar tools,
+// compared after lowercasing and removing everything but letters and digits.
+var (
+	longUrlColumns   = []string{"longurl", "originalurl", "destination", "destinationurl", "target", "targeturl", "url"}
+	shortCodeColumns = []string{"bitlink", "shorturl", "shortlink", "short", "link", "slashtag", "keyword", "alias", "slug", "code", "path", "id"}
+	clickColumns     = []string{"clicks", "totalclicks", "clickcount", "visits", "hits", "visitcount"}
+)
+
+// Parse reads links from a CSV or JSON export.
+func Parse(format string, content io.Reader) ([]Link, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return ParseCSV(content)
+	case FormatJSON:
+		return ParseJSON(content)
+	}
+	return nil, fmt.Errorf("unsupported import format %q, use csv or json", format)
+}
+
+// ParseCSV reads a CSV export, the first row must name the columns.
+func ParseCSV(content io.Reader) ([]Link, error) {
+	reader := csv.NewReader(content)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("export is empty or not a valid csv file")
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[normalizeKey(name)] = i
+	}
+
+	longUrlIndex := findColumn(columns, longUrlColumns)
+	if longUrlIndex == -1 {
+		return nil, errors.New("export has no long url column")
+	}
+	shortCodeIndex := findColumn(columns, shortCodeColumns)
+	clickIndex := findColumn(columns, clickColumns)
+
+	var links []Link
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+		if len(links) == MaxLinks {
+			return nil, fmt.Errorf("an import can have at most %d links", MaxLinks)
+		}
+
+		links = append(links, Link{
+			Row:       row,
+			LongUrl:   field(record, longUrlIndex),
+			ShortCode: ShortCode(field(record, shortCodeIndex)),
+			Clicks:    parseClicks(field(record, clickIndex)),
+		})
+	}
+	return links, nil
+}
+
+// ParseJSON reads a JSON export, either an array of links or an object holding the array under
+// "links", "data", "urls" or "items".
+func ParseJSON(content io.Reader) ([]Link, error) {
+	var document interface{}
+	decoder := json.NewDecoder(content)
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, errors.New("export is not a valid json file")
+	}
+
+	items, ok := document.([]interface{})
+	if object, isObject := document.(map[string]interface{}); isObject {
+		for _, key := range []string{"links", "data", "urls", "items"} {
+			if items, ok = object[key].([]interface{}); ok {
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, errors.New("export must contain an array of links")
+	}
+	if len(items) > MaxLinks {
+		return nil, fmt.Errorf("an import can have at most %d links", MaxLinks)
+	}
+
+	links := make([]Link, 0, len(items))
+	for i, item := range items {
+		object, _ := item.(map[string]interface{})
+		values := make(map[string]string, len(object))
+		for key, value := range object {
+			values[normalizeKey(key)] = jsonString(value)
+		}
+
+		links = append(links, Link{
+			Row:       i + 1,
+			LongUrl:   findValue(values, longUrlColumns),
+			ShortCode: ShortCode(findValue(values, shortCodeColumns)),
+			Clicks:    parseClicks(findValue(values, clickColumns)),
+		})
+	}
+	return links, nil
+}
+
+// ShortCode extracts the code from a short link like "bit.ly/abc12" or "https://rebrand.ly/abc12".
+func ShortCode(shortLink string) string {
+	shortLink = strings.TrimSpace(shortLink)
+	if i := strings.IndexAny(shortLink, "?#"); i != -1 {
+		shortLink = shortLink[:i]
+	}
+	shortLink = strings.TrimRight(shortLink, "/")
+	if i := strings.LastIndex(shortLink, "/"); i != -1 {
+		shortLink = shortLink[i+1:]
+	}
+	return shortLink
+}
+
+func normalizeKey(key string) string {
+	var normalized strings.Builder
+	for _, r := range strings.ToLower(key) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			normalized.WriteRune(r)
+		}
+	}
+	return normalized.String()
+}
+
+func findColumn(columns map[string]int, aliases []string) int {
+	for _, alias := range aliases {
+		if index, ok := columns[alias]; ok {
+			return index
+		}
+	}
+	return -1
+}
+
+func findValue(values map[string]string, aliases []string) string {
+	for _, alias := range aliases {
+		if value, ok := values[alias]; ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
+
+func jsonString(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return strings.TrimSpace(typed)
+	case json.Number:
+		return typed.String()
+	}
+	return ""
+}
+
+func parseClicks(value string) int {
+	clicks, err := strconv.Atoi(strings.ReplaceAll(value, ",", ""))
+	if err != nil || clicks < 0 {
+		return 0
+	}
+	return clicks
+}
@@ -0,0 +1,126 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 in PDF points.
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+)
+
+// helveticaWidths are the widths of the printable ASCII characters in the standard Helvetica font,
+// in thousandths of the font size. They are only used to right align text.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// pdfDocument writes plain text and rules on A4 pages using the built in Helvetica fonts,
+// which is all an invoice needs and keeps the renderer free of dependencies.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+}
+
+func newPDFDocument() *pdfDocument {
+	document := &pdfDocument{}
+	document.addPage()
+	return document
+}
+
+func (document *pdfDocument) addPage() {
+	document.pages = append(document.pages, &bytes.Buffer{})
+}
+
+func (document *pdfDocument) page() *bytes.Buffer {
+	return document.pages[len(document.pages)-1]
+}
+
+// text writes s with its baseline y points from the top of the page.
+func (document *pdfDocument) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(document.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, pageHeight-y, escapePDFText(s))
+}
+
+// textRight writes s so that it ends at x.
+func (document *pdfDocument) textRight(x, y, size float64, bold bool, s string) {
+	document.text(x-textWidth(s, size), y, size, bold, s)
+}
+
+func (document *pdfDocument) rule(x1, x2, y float64) {
+	fmt.Fprintf(document.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, pageHeight-y, x2, pageHeight-y)
+}
+
+func (document *pdfDocument) bytes() []byte {
+	out := &bytes.Buffer{}
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1 to 4 are the catalog, the page tree and the two fonts, every page then adds itself and its content.
+	kids := make([]string, len(document.pages))
+	for i := range document.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(document.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range document.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// escapePDFText keeps s inside a PDF string literal, characters outside printable ASCII are replaced
+// since the standard fonts cannot show them.
+func escapePDFText(s string) string {
+	var escaped strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			escaped.WriteRune('\\')
+			escaped.WriteRune(r)
+		case r < 32 || r > 126:
+			escaped.WriteRune('?')
+		default:
+			escaped.WriteRune(r)
+		}
+	}
+	return escaped.String()
+}
+
+func textWidth(s string, size float64) float64 {
+	width := 0
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			width += helveticaWidths[r-32]
+		} else {
+			width += helveticaWidths['?'-32]
+		}
+	}
+	return float64(width) * size / 1000
+}
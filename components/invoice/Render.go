@@ -0,0 +1,183 @@
+package invoice
+
+import (
+	"bytes"
+	"html/template"
+	"strconv"
+	"strings"
+	"url-shortner-be/model/invoice"
+)
+
+const (
+	FormatPDF  = "pdf"
+	FormatHTML = "html"
+	FormatJSON = "json"
+)
+
+var htmlTemplate = template.Must(template.New("invoice").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 13px; color: #222; max-width: 760px; margin: 32px auto; }
+h1 { font-size: 22px; margin: 0 0 4px; }
+table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+th, td { padding: 6px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+.number { text-align: right; }
+.parties { display: flex; justify-content: space-between; margin-top: 24px; }
+.totals td { border: none; }
+.total td { font-weight: bold; border-top: 1px solid #222; }
+footer { margin-top: 32px; color: #777; font-size: 11px; }
+</style>
+</head>
+<body>
+<h1>Tax Invoice</h1>
+<div>Invoice No: {{.Number}}</div>
+<div>Date: {{.IssuedAt.Format "02 Jan 2006"}}</div>
+<div class="parties">
+<div>
+<strong>{{.SellerName}}</strong><br>
+{{.SellerAddress}}<br>
+{{if .SellerGSTIN}}GSTIN: {{.SellerGSTIN}}<br>{{end}}
+{{if .SellerState}}State: {{.SellerState}}{{end}}
+</div>
+<div>
+<strong>Bill To</strong><br>
+{{.BuyerName}}<br>
+{{.BuyerEmail}}<br>
+{{.BuyerPhone}}<br>
+{{if .BuyerGSTIN}}GSTIN: {{.BuyerGSTIN}}<br>{{end}}
+{{if .BuyerState}}State: {{.BuyerState}}<br>{{end}}
+{{if .PlaceOfSupply}}Place of Supply: {{.PlaceOfSupply}}{{end}}
+</div>
+</div>
+<table>
+<tr><th>Description</th><th>SAC</th><th class="number">Qty</th><th class="number">Unit Price</th><th class="number">Amount</th></tr>
+{{range .Lines}}<tr><td>{{.Description}}</td><td>{{.SAC}}</td><td class="number">{{.Quantity}}</td><td class="number">{{.UnitPrice}}</td><td class="number">{{.Amount}}</td></tr>
+{{end}}</table>
+<table class="totals">
+<tr><td class="number">Taxable Value</td><td class="number">{{.Subtotal}}</td></tr>
+{{range .Taxes}}<tr><td class="number">{{.Name}} @ {{.Rate}}</td><td class="number">{{.Amount}}</td></tr>
+{{else}}<tr><td class="number">Tax</td><td class="number">{{.TaxTotal}}</td></tr>
+{{end}}<tr class="total"><td class="number">Total ({{.Currency}})</td><td class="number">{{.Total}}</td></tr>
+</table>
+<footer>This is a computer generated invoice and does not need a signature.</footer>
+</body>
+</html>
+`))
+
+// RenderHTML renders the invoice as a standalone HTML page.
+func RenderHTML(inv *invoice.Invoice) ([]byte, error) {
+	out := &bytes.Buffer{}
+	if err := htmlTemplate.Execute(out, inv); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// RenderPDF renders the invoice as an A4 PDF with the same content as RenderHTML.
+func RenderPDF(inv *invoice.Invoice) []byte {
+	const (
+		left   = 50.0
+		right  = pageWidth - 50.0
+		bottom = pageHeight - 60.0
+	)
+	document := newPDFDocument()
+
+	document.text(left, 70, 20, true, "Tax Invoice")
+	document.textRight(right, 62, 10, false, "Invoice No: "+inv.Number)
+	document.textRight(right, 76, 10, false, "Date: "+inv.IssuedAt.Format("02 Jan 2006"))
+
+	y := 115.0
+	document.text(left, y, 11, true, inv.SellerName)
+	document.text(330, y, 11, true, "Bill To")
+	seller := []string{inv.SellerAddress}
+	if inv.SellerGSTIN != "" {
+		seller = append(seller, "GSTIN: "+inv.SellerGSTIN)
+	}
+	if inv.SellerState != "" {
+		seller = append(seller, "State: "+inv.SellerState)
+	}
+	buyer := []string{inv.BuyerName, inv.BuyerEmail, inv.BuyerPhone}
+	if inv.BuyerGSTIN != "" {
+		buyer = append(buyer, "GSTIN: "+inv.BuyerGSTIN)
+	}
+	if inv.BuyerState != "" {
+		buyer = append(buyer, "State: "+inv.BuyerState)
+	}
+	if inv.PlaceOfSupply != "" {
+		buyer = append(buyer, "Place of Supply: "+inv.PlaceOfSupply)
+	}
+	for i := 0; i < len(seller) || i < len(buyer); i++ {
+		y += 14
+		if i < len(seller) {
+			document.text(left, y, 10, false, seller[i])
+		}
+		if i < len(buyer) {
+			document.text(330, y, 10, false, buyer[i])
+		}
+	}
+
+	header := func(y float64) {
+		document.text(left, y, 10, true, "Description")
+		document.text(300, y, 10, true, "SAC")
+		document.textRight(390, y, 10, true, "Qty")
+		document.textRight(470, y, 10, true, "Unit Price")
+		document.textRight(right, y, 10, true, "Amount")
+		document.rule(left, right, y+6)
+	}
+
+	y += 40
+	header(y)
+	for _, line := range inv.Lines {
+		y += 20
+		if y > bottom {
+			document.addPage()
+			y = 70
+			header(y)
+			y += 20
+		}
+		document.text(left, y, 10, false, truncate(line.Description, 45))
+		document.text(300, y, 10, false, line.SAC)
+		document.textRight(390, y, 10, false, strconv.Itoa(line.Quantity))
+		document.textRight(470, y, 10, false, line.UnitPrice.String())
+		document.textRight(right, y, 10, false, line.Amount.String())
+	}
+
+	totals := [][2]string{{"Taxable Value", inv.Subtotal.String()}}
+	for _, tax := range inv.Taxes {
+		totals = append(totals, [2]string{tax.Name + " @ " + tax.Rate(), tax.Amount.String()})
+	}
+	if len(inv.Taxes) == 0 {
+		totals = append(totals, [2]string{"Tax", inv.TaxTotal.String()})
+	}
+
+	if y+float64(len(totals)+2)*18+40 > bottom {
+		document.addPage()
+		y = 50
+	}
+	y += 12
+	document.rule(left, right, y)
+	for _, total := range totals {
+		y += 18
+		document.textRight(470, y, 10, false, total[0])
+		document.textRight(right, y, 10, false, total[1])
+	}
+	y += 10
+	document.rule(330, right, y)
+	y += 16
+	document.textRight(470, y, 11, true, "Total ("+inv.Currency+")")
+	document.textRight(right, y, 11, true, inv.Total.String())
+
+	document.text(left, pageHeight-40, 8, false, "This is a computer generated invoice and does not need a signature.")
+	return document.bytes()
+}
+
+func truncate(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
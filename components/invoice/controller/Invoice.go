@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+	"url-shortner-be/components/errors"
+	invoiceRenderer "url-shortner-be/components/invoice"
+	invoiceService "url-shortner-be/components/invoice/service"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/security"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/invoice"
+
+	"github.com/gorilla/mux"
+)
+
+type InvoiceController struct {
+	log            log.Logger
+	InvoiceService *invoiceService.InvoiceService
+}
+
+func NewInvoiceController(invoiceService *invoiceService.InvoiceService, log log.Logger) *InvoiceController {
+	return &InvoiceController{
+		log:            log,
+		InvoiceService: invoiceService,
+	}
+}
+
+// RegisterRoutes adds the invoice routes under /users next to the other per user routes.
+func (invoiceController *InvoiceController) RegisterRoutes(router *mux.Router) {
+
+	invoiceRouter := router.PathPrefix("/users").Subrouter()
+	commonRouter := invoiceRouter.PathPrefix("/").Subrouter()
+
+	commonRouter.HandleFunc("/{userId}/invoices", invoiceController.getAllInvoices).Methods(http.MethodGet)
+	commonRouter.HandleFunc("/{userId}/invoices/{invoiceId}", invoiceController.getInvoice).Methods(http.MethodGet)
+
+	commonRouter.Use(security.MiddlewareCommon)
+}
+
+func (controller *InvoiceController) getAllInvoices(w http.ResponseWriter, r *http.Request) {
+	invoices := []invoice.Invoice{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	userIdFromUrl, err := parser.GetUUID("userId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.InvoiceService.GetAllInvoices(&invoices, &totalCount, parser, userIdFromUrl, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, invoices)
+}
+
+// getInvoice downloads an invoice as a PDF, or as HTML or JSON when asked for with ?format=html or ?format=json.
+func (controller *InvoiceController) getInvoice(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	userIdFromUrl, err := parser.GetUUID("userId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	invoiceID, err := parser.GetUUID("invoiceId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid invoice ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	issued := &invoice.Invoice{}
+	if err = controller.InvoiceService.GetInvoice(issued, invoiceID, userIdFromUrl, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	fileName := strings.ReplaceAll(issued.Number, "/", "-")
+	switch parser.Form.Get("format") {
+	case invoiceRenderer.FormatJSON:
+		web.RespondJSON(w, http.StatusOK, issued)
+	case invoiceRenderer.FormatHTML:
+		html, err := invoiceRenderer.RenderHTML(issued)
+		if err != nil {
+			controller.log.Error(err.Error())
+			web.RespondError(w, errors.NewHTTPError("unable to render invoice", http.StatusInternalServerError))
+			return
+		}
+		web.RespondHTML(w, http.StatusOK, string(html))
+	case "", invoiceRenderer.FormatPDF:
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName + ".pdf"}))
+		w.WriteHeader(http.StatusOK)
+		w.Write(invoiceRenderer.RenderPDF(issued))
+	default:
+		web.RespondError(w, errors.NewValidationError("format must be pdf, html or json"))
+	}
+}
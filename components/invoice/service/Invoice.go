@@ -0,0 +1,193 @@
+package service
+
+import (
+	"strings"
+	"time"
+	"url-shortner-be/components/config"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/money"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/invoice"
+	"url-shortner-be/model/tax"
+	"url-shortner-be/model/transaction"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type InvoiceService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewInvoiceService(DB *gorm.DB, repo repository.Repository) *InvoiceService {
+	return &InvoiceService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+// IssueInvoice issues the invoice of a purchase in the purchase's unit of work, so that a purchase
+// that rolls back takes its invoice number with it and numbers stay gapless.
+func (service *InvoiceService) IssueInvoice(uow *repository.UnitOfWork, purchase *transaction.Transaction, lines []*invoice.InvoiceLine) (*invoice.Invoice, error) {
+
+	// Invoice numbers are sequential without gaps, so none is used up on a purchase with nothing to pay.
+	if purchase.Amount <= 0 {
+		return nil, errors.NewValidationError("invoices can only be issued for purchases with a positive total")
+	}
+
+	buyer := &user.User{}
+	if err := service.repository.GetRecordByID(uow, purchase.UserID, buyer); err != nil {
+		return nil, errors.NewNotFoundError("User not found")
+	}
+
+	issuedAt := time.Now()
+	number, err := service.nextNumber(uow, invoice.Series(issuedAt))
+	if err != nil {
+		return nil, err
+	}
+
+	issued := &invoice.Invoice{
+		Number:        number,
+		UserID:        buyer.ID,
+		TransactionID: purchase.ID,
+		Type:          purchase.Type,
+		IssuedAt:      issuedAt,
+		SellerName:    config.InvoiceSellerName.GetStringValue(),
+		SellerAddress: config.InvoiceSellerAddress.GetStringValue(),
+		SellerGSTIN:   config.InvoiceSellerGSTIN.GetStringValue(),
+		SellerState:   tax.StateLabel(config.InvoiceSellerState.GetStringValue()),
+		BuyerName:     strings.TrimSpace(buyer.FirstName + " " + buyer.LastName),
+		BuyerEmail:    buyer.Email,
+		BuyerPhone:    buyer.PhoneNo,
+		BuyerState:    tax.StateLabel(buyer.State),
+		BuyerGSTIN:    buyer.GSTIN,
+		PlaceOfSupply: tax.StateLabel(purchase.PlaceOfSupply),
+		Currency:      money.CurrencyINR,
+		Subtotal:      purchase.TaxableAmount,
+		Lines:         lines,
+		Taxes:         taxesOf(purchase),
+	}
+	issued.ID = uuid.NewV4()
+	issued.CreatedBy = buyer.ID
+
+	for _, line := range lines {
+		if line.SAC == "" {
+			line.SAC = config.InvoiceSACCode.GetStringValue()
+		}
+		line.CreatedBy = buyer.ID
+	}
+	for _, tax := range issued.Taxes {
+		tax.CreatedBy = buyer.ID
+		issued.TaxTotal += tax.Amount
+	}
+	issued.Total = issued.Subtotal + issued.TaxTotal
+
+	if issued.Total != purchase.Amount {
+		return nil, errors.NewValidationError("invoice total does not match the amount charged")
+	}
+
+	if err := service.repository.Add(uow, issued); err != nil {
+		return nil, errors.NewDatabaseError("unable to issue invoice")
+	}
+
+	return issued, nil
+}
+
+func (service *InvoiceService) GetAllInvoices(invoices *[]invoice.Invoice, totalCount *int, parser *web.Parser, userIdFromUrl, userIdFromToken uuid.UUID) error {
+
+	limit, offset := parser.ParseLimitAndOffset()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.checkAccess(uow, userIdFromUrl, userIdFromToken); err != nil {
+		return err
+	}
+
+	if err := service.repository.GetAll(uow, invoices,
+		repository.Filter("user_id = ?", userIdFromUrl),
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("issued_at desc"),
+	); err != nil {
+		return errors.NewDatabaseError("unable to fetch invoices")
+	}
+
+	return nil
+}
+
+func (service *InvoiceService) GetInvoice(issued *invoice.Invoice, invoiceID, userIdFromUrl, userIdFromToken uuid.UUID) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.checkAccess(uow, userIdFromUrl, userIdFromToken); err != nil {
+		return err
+	}
+
+	if err := service.repository.GetRecord(uow, issued,
+		repository.Filter("id = ? AND user_id = ?", invoiceID, userIdFromUrl),
+		repository.PreloadAssociations([]string{"Lines", "Taxes"}),
+	); err != nil {
+		return errors.NewNotFoundError("invoice not found")
+	}
+
+	return nil
+}
+
+// ---------------- Helpers ----------------
+
+// taxesOf lists the GST charged on the purchase, CGST and SGST are each charged at half the rate.
+func taxesOf(purchase *transaction.Transaction) []*invoice.InvoiceTax {
+	taxes := []*invoice.InvoiceTax{}
+	if purchase.IGST != 0 {
+		taxes = append(taxes, &invoice.InvoiceTax{Name: tax.NameIGST, RateBasisPoints: purchase.TaxRateBasisPoints, Amount: purchase.IGST})
+	}
+	if purchase.CGST != 0 || purchase.SGST != 0 {
+		taxes = append(taxes,
+			&invoice.InvoiceTax{Name: tax.NameCGST, RateBasisPoints: purchase.TaxRateBasisPoints / 2, Amount: purchase.CGST},
+			&invoice.InvoiceTax{Name: tax.NameSGST, RateBasisPoints: purchase.TaxRateBasisPoints / 2, Amount: purchase.SGST})
+	}
+	return taxes
+}
+
+// nextNumber takes the next number of the series, the sequence row stays locked until the purchase commits.
+func (service *InvoiceService) nextNumber(uow *repository.UnitOfWork, series string) (string, error) {
+	if err := uow.DB.Exec("INSERT IGNORE INTO invoice_sequences (series, last_number) VALUES (?, 0)", series).Error; err != nil {
+		return "", errors.NewDatabaseError("unable to open invoice series")
+	}
+
+	sequence := &invoice.InvoiceSequence{}
+	if err := service.repository.GetRecord(uow, sequence, repository.Filter("series = ?", series), repository.ForUpdate()); err != nil {
+		return "", errors.NewDatabaseError("unable to fetch invoice series")
+	}
+
+	sequence.LastNumber++
+	if err := service.repository.UpdateWithMap(uow, &invoice.InvoiceSequence{}, map[string]interface{}{
+		"last_number": sequence.LastNumber,
+	}, repository.Filter("series = ?", series)); err != nil {
+		return "", errors.NewDatabaseError("unable to number invoice")
+	}
+
+	return invoice.FormatNumber(series, sequence.LastNumber), nil
+}
+
+func (service *InvoiceService) checkAccess(uow *repository.UnitOfWork, userIdFromUrl, userIdFromToken uuid.UUID) error {
+	actualUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, userIdFromUrl, &actualUser); err != nil {
+		return errors.NewUnauthorizedError("invalid user making the request")
+	}
+
+	tokenUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, userIdFromToken, &tokenUser); err != nil {
+		return errors.NewUnauthorizedError("invalid user making the request")
+	}
+
+	isAdmin := tokenUser.IsAdmin != nil && *tokenUser.IsAdmin
+	if actualUser.ID != tokenUser.ID && !isAdmin {
+		return errors.NewUnauthorizedError("you are not authorized to view invoices of this user")
+	}
+	return nil
+}
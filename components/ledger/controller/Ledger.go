@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"net/http"
+	ledgerService "url-shortner-be/components/ledger/service"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/security"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/ledger"
+
+	"github.com/gorilla/mux"
+)
+
+type LedgerController struct {
+	log           log.Logger
+	LedgerService *ledgerService.LedgerService
+}
+
+func NewLedgerController(ledgerService *ledgerService.LedgerService, log log.Logger) *LedgerController {
+	return &LedgerController{
+		log:           log,
+		LedgerService: ledgerService,
+	}
+}
+
+func (ledgerController *LedgerController) RegisterRoutes(router *mux.Router) {
+
+	ledgerRouter := router.PathPrefix("/ledger").Subrouter()
+	adminguardedRouter := ledgerRouter.PathPrefix("/").Subrouter()
+
+	adminguardedRouter.HandleFunc("/reconciliation", ledgerController.getReconciliation).Methods(http.MethodGet)
+
+	adminguardedRouter.Use(security.MiddlewareAdmin)
+}
+
+func (controller *LedgerController) getReconciliation(w http.ResponseWriter, r *http.Request) {
+	report := ledger.ReconciliationReport{}
+
+	if err := controller.LedgerService.Reconcile(&report); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, report)
+}
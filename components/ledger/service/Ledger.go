@@ -0,0 +1,233 @@
+package service
+
+import (
+	"time"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
+	"url-shortner-be/model/ledger"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type LedgerService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewLedgerService(DB *gorm.DB, repo repository.Repository) *LedgerService {
+	return &LedgerService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+// PostWalletEntry records a balanced journal entry between the user's wallet and a system account
+// and applies it to the stored wallet balance in the same unit of work. A positive amount credits the wallet.
+func (service *LedgerService) PostWalletEntry(uow *repository.UnitOfWork, userID uuid.UUID, amount money.Money, counterAccountCode, entryType, note string) error {
+	if amount == 0 {
+		return nil
+	}
+
+	wallet, err := service.walletAccount(uow, userID)
+	if err != nil {
+		return err
+	}
+
+	counter, err := service.systemAccount(uow, counterAccountCode)
+	if err != nil {
+		return err
+	}
+
+	entry := ledger.NewWalletEntry(entryType, note, wallet, counter, amount)
+	entry.CreatedBy = userID
+	for _, posting := range entry.Postings {
+		posting.CreatedBy = userID
+	}
+
+	if err := service.repository.Add(uow, entry); err != nil {
+		return errors.NewDatabaseError("unable to record ledger entry")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &user.User{}, map[string]interface{}{
+		"wallet_paise": gorm.Expr("wallet_paise + ?", amount),
+		"updated_at":   time.Now(),
+	}, repository.Filter("id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to update wallet")
+	}
+
+	return nil
+}
+
+// PostWalletPurchase pays for a purchase from the user's wallet, crediting the taxable value to revenue
+// and the tax to the tax payable account in one journal entry.
+func (service *LedgerService) PostWalletPurchase(uow *repository.UnitOfWork, userID uuid.UUID, taxable, tax money.Money, entryType, note string) error {
+	if taxable+tax == 0 {
+		return nil
+	}
+
+	wallet, err := service.walletAccount(uow, userID)
+	if err != nil {
+		return err
+	}
+
+	revenue, err := service.systemAccount(uow, ledger.AccountRevenue)
+	if err != nil {
+		return err
+	}
+
+	taxPayable, err := service.systemAccount(uow, ledger.AccountTaxPayable)
+	if err != nil {
+		return err
+	}
+
+	entry := ledger.NewPurchaseEntry(entryType, note, wallet, revenue, taxPayable, taxable, tax)
+	entry.CreatedBy = userID
+	for _, posting := range entry.Postings {
+		posting.CreatedBy = userID
+	}
+
+	if err := service.repository.Add(uow, entry); err != nil {
+		return errors.NewDatabaseError("unable to record ledger entry")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &user.User{}, map[string]interface{}{
+		"wallet_paise": gorm.Expr("wallet_paise - ?", taxable+tax),
+		"updated_at":   time.Now(),
+	}, repository.Filter("id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to update wallet")
+	}
+
+	return nil
+}
+
+// PostSystemEntry records a balanced journal entry moving amount between two system accounts,
+// no wallet balance changes.
+func (service *LedgerService) PostSystemEntry(uow *repository.UnitOfWork, fromAccountCode, toAccountCode string, amount money.Money, entryType, note string, createdBy uuid.UUID) error {
+	if amount == 0 {
+		return nil
+	}
+
+	from, err := service.systemAccount(uow, fromAccountCode)
+	if err != nil {
+		return err
+	}
+
+	to, err := service.systemAccount(uow, toAccountCode)
+	if err != nil {
+		return err
+	}
+
+	entry := ledger.NewSystemEntry(entryType, note, from, to, amount)
+	entry.CreatedBy = createdBy
+	for _, posting := range entry.Postings {
+		posting.CreatedBy = createdBy
+	}
+
+	if err := service.repository.Add(uow, entry); err != nil {
+		return errors.NewDatabaseError("unable to record ledger entry")
+	}
+	return nil
+}
+
+// Reconcile reports every user whose stored wallet balance disagrees with their ledger
+// and every journal entry whose postings do not add up to zero.
+func (service *LedgerService) Reconcile(report *ledger.ReconciliationReport) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	report.CheckedAt = time.Now()
+	report.Mismatches = []ledger.BalanceMismatch{}
+	report.UnbalancedEntries = []ledger.UnbalancedEntry{}
+
+	if err := service.repository.GetRaw(uow, &report.Mismatches, repository.RawQuery(`
+		SELECT u.id AS user_id, u.wallet_paise AS stored_balance, COALESCE(SUM(p.amount_paise), 0) AS ledger_balance
+		FROM users u
+		LEFT JOIN ledger_accounts a ON a.user_id = u.id AND a.type = ? AND a.deleted_at IS NULL
+		LEFT JOIN ledger_postings p ON p.account_id = a.id AND p.deleted_at IS NULL
+		WHERE u.deleted_at IS NULL
+		GROUP BY u.id, u.wallet_paise
+		HAVING stored_balance <> ledger_balance
+	`, ledger.AccountTypeWallet)); err != nil {
+		return errors.NewDatabaseError("unable to reconcile wallet balances")
+	}
+
+	if err := service.repository.GetRaw(uow, &report.UnbalancedEntries, repository.RawQuery(`
+		SELECT journal_entry_id, SUM(amount_paise) AS total
+		FROM ledger_postings
+		WHERE deleted_at IS NULL
+		GROUP BY journal_entry_id
+		HAVING total <> 0
+	`)); err != nil {
+		return errors.NewDatabaseError("unable to reconcile journal entries")
+	}
+
+	return nil
+}
+
+// ReconcileBalances is the background job form of Reconcile, it only logs what it finds.
+func (service *LedgerService) ReconcileBalances() {
+	report := ledger.ReconciliationReport{}
+	if err := service.Reconcile(&report); err != nil {
+		log.GetLogger().Error("ledger reconciliation failed: ", err.Error())
+		return
+	}
+
+	for _, mismatch := range report.Mismatches {
+		log.GetLogger().Error("wallet balance mismatch for user ", mismatch.UserID.String(),
+			": stored ", mismatch.StoredBalance.String(), ", ledger ", mismatch.LedgerBalance.String())
+	}
+	for _, entry := range report.UnbalancedEntries {
+		log.GetLogger().Error("unbalanced journal entry ", entry.JournalEntryID.String(), ": total ", entry.Total.String())
+	}
+}
+
+// ---------------- Helpers ----------------
+
+func (service *LedgerService) walletAccount(uow *repository.UnitOfWork, userID uuid.UUID) (*ledger.Account, error) {
+	wallet := &ledger.Account{}
+	err := service.repository.GetRecord(uow, wallet, repository.Filter("code = ?", ledger.WalletAccountCode(userID)))
+	if err == nil {
+		return wallet, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, errors.NewDatabaseError("unable to fetch wallet account")
+	}
+
+	wallet = &ledger.Account{
+		Code:     ledger.WalletAccountCode(userID),
+		Type:     ledger.AccountTypeWallet,
+		UserID:   &userID,
+		Currency: money.CurrencyINR,
+	}
+	wallet.CreatedBy = userID
+	if err := service.repository.Add(uow, wallet); err != nil {
+		return nil, errors.NewDatabaseError("unable to open wallet account")
+	}
+	return wallet, nil
+}
+
+func (service *LedgerService) systemAccount(uow *repository.UnitOfWork, code string) (*ledger.Account, error) {
+	account := &ledger.Account{}
+	err := service.repository.GetRecord(uow, account, repository.Filter("code = ?", code))
+	if err == nil {
+		return account, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, errors.NewDatabaseError("unable to fetch ledger account")
+	}
+
+	account = &ledger.Account{
+		Code:     code,
+		Type:     ledger.AccountTypeSystem,
+		Currency: money.CurrencyINR,
+	}
+	if err := service.repository.Add(uow, account); err != nil {
+		return nil, errors.NewDatabaseError("unable to open ledger account")
+	}
+	return account, nil
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"url-shortner-be/components/config"
+)
+
+// Sender delivers a plain text email to a single recipient.
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+// NewSender returns the sender configured by MAIL_DRIVER, writing mails to disk unless smtp is selected.
+func NewSender() Sender {
+	from := config.MailFrom.GetStringValue()
+
+	if config.MailDriver.GetStringValue() == "smtp" {
+		return &SMTPSender{
+			Host:     config.SMTPHost.GetStringValue(),
+			Port:     config.SMTPPort.GetStringValue(),
+			Username: config.SMTPUser.GetStringValue(),
+			Password: config.SMTPPass.GetStringValue(),
+			From:     from,
+		}
+	}
+
+	dir := config.MailDir.GetStringValue()
+	if dir == "" {
+		dir = "mails"
+	}
+	return &FileSender{Dir: dir, From: from}
+}
+
+// FileSender writes every mail as an .eml file, a stand-in for smtp on local setups.
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+func (sender *FileSender) Send(to, subject, body string) error {
+	if err := os.MkdirAll(sender.Dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(to))
+	return os.WriteFile(filepath.Join(sender.Dir, name), buildMessage(sender.From, to, subject, body), 0644)
+}
+
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (sender *SMTPSender) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if sender.Username != "" {
+		auth = smtp.PlainAuth("", sender.Username, sender.Password, sender.Host)
+	}
+	return smtp.SendMail(sender.Host+":"+sender.Port, auth, sender.From, []string{to}, buildMessage(sender.From, to, subject, body))
+}
+
+func buildMessage(from, to, subject, body string) []byte {
+	var message strings.Builder
+	message.WriteString("From: " + from + "\r\n")
+	message.WriteString("To: " + to + "\r\n")
+	message.WriteString("Subject: " + subject + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	message.WriteString(body)
+	return []byte(message.String())
+}
@@ -0,0 +1,115 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CurrencyINR is the only currency wallets and prices are kept in today.
+const CurrencyINR = "INR"
+
+// Money is an exact amount in minor units (paise). It is stored as a bigint and
+// read and written in JSON as a decimal number of rupees, e.g. 120.50.
+type Money int64
+
+var ErrInvalidAmount = errors.New("invalid amount, use at most two decimal places")
+
+func FromPaise(paise int64) Money {
+	return Money(paise)
+}
+
+// FromRupees converts a whole rupee amount, it is meant for constants such as limits.
+func FromRupees(rupees int64) Money {
+	return Money(rupees * 100)
+}
+
+// Parse reads a decimal rupee amount such as "120", "120.5" or "-0.75" without going through floats.
+func Parse(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	if (whole == "" && fraction == "") || len(fraction) > 2 {
+		return 0, ErrInvalidAmount
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+
+	rupees, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || rupees < 0 || rupees > math.MaxInt64/100-1 {
+		return 0, ErrInvalidAmount
+	}
+	paise, err := strconv.ParseUint(fraction, 10, 8)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+
+	amount := Money(rupees*100 + int64(paise))
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+func (m Money) Paise() int64 {
+	return int64(m)
+}
+
+// Mul returns the amount for quantity units, e.g. the price of several visits.
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+func (m Money) String() string {
+	sign := ""
+	paise := int64(m)
+	if paise < 0 {
+		sign, paise = "-", -paise
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, paise/100, paise%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts the amount either as a JSON number or as a string.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" || value == "" {
+		*m = 0
+		return nil
+	}
+	amount, err := Parse(value)
+	if err != nil {
+		return err
+	}
+	*m = amount
+	return nil
+}
+
+// MigrateDecimalColumn moves a legacy decimal rupee column into its bigint paise column and drops it.
+// Running it again after the old column is gone does nothing, and a run that stopped before the
+// drop simply copies the same values again.
+func MigrateDecimalColumn(db *gorm.DB, table, decimalColumn, paiseColumn string) error {
+	if !db.Dialect().HasColumn(table, decimalColumn) {
+		return nil
+	}
+
+	if err := db.Exec(fmt.Sprintf("UPDATE %s SET %s = ROUND(COALESCE(%s, 0) * 100)",
+		table, paiseColumn, decimalColumn)).Error; err != nil {
+		return err
+	}
+
+	return db.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, decimalColumn)).Error
+}
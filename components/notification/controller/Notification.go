@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"net/http"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	notificationService "url-shortner-be/components/notification/service"
+	"url-shortner-be/components/security"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/notification"
+
+	"github.com/gorilla/mux"
+)
+
+type NotificationController struct {
+	log                 log.Logger
+	NotificationService *notificationService.NotificationService
+}
+
+func NewNotificationController(notificationService *notificationService.NotificationService, log log.Logger) *NotificationController {
+	return &NotificationController{
+		log:                 log,
+		NotificationService: notificationService,
+	}
+}
+
+func (notificationController *NotificationController) RegisterRoutes(router *mux.Router) {
+
+	notificationRouter := router.PathPrefix("/notifications").Subrouter()
+
+	notificationRouter.HandleFunc("", notificationController.getAllNotifications).Methods(http.MethodGet)
+	notificationRouter.HandleFunc("/read-all", notificationController.markAllAsRead).Methods(http.MethodPost)
+	notificationRouter.HandleFunc("/{notificationId}/read", notificationController.markAsRead).Methods(http.MethodPost)
+
+	notificationRouter.Use(security.MiddlewareCommon)
+}
+
+func (controller *NotificationController) getAllNotifications(w http.ResponseWriter, r *http.Request) {
+	notifications := []notification.Notification{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.NotificationService.GetAllNotifications(&notifications, &totalCount, parser, userIdFromToken); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, notifications)
+}
+
+func (controller *NotificationController) markAsRead(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	notificationID, err := parser.GetUUID("notificationId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid notification ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.NotificationService.MarkAsRead(notificationID, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Notification marked as read",
+	})
+}
+
+func (controller *NotificationController) markAllAsRead(w http.ResponseWriter, r *http.Request) {
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.NotificationService.MarkAllAsRead(userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "All notifications marked as read",
+	})
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"time"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/mail"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/credential"
+	"url-shortner-be/model/notification"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type NotificationService struct {
+	db         *gorm.DB
+	repository repository.Repository
+	sender     mail.Sender
+}
+
+func NewNotificationService(DB *gorm.DB, repo repository.Repository, sender mail.Sender) *NotificationService {
+	return &NotificationService{
+		db:         DB,
+		repository: repo,
+		sender:     sender,
+	}
+}
+
+// Notify stores an in-app notification for the user and emails it in the background.
+func (service *NotificationService) Notify(userID uuid.UUID, urlID *uuid.UUID, notificationType, title, message string) error {
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	newNotification := &notification.Notification{
+		UserID:  userID,
+		UrlID:   urlID,
+		Type:    notificationType,
+		Title:   title,
+		Message: message,
+	}
+	newNotification.CreatedBy = userID
+
+	if err := service.repository.Add(uow, newNotification); err != nil {
+		return errors.NewDatabaseError("unable to create notification")
+	}
+
+	userCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &userCredential, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("unable to find user email")
+	}
+
+	uow.Commit()
+
+	go func() {
+		if err := service.sender.Send(userCredential.Email, title, message); err != nil {
+			log.GetLogger().Error("unable to send notification mail to ", userCredential.Email, ": ", err.Error())
+		}
+	}()
+	return nil
+}
+
+func (service *NotificationService) GetAllNotifications(notifications *[]notification.Notification, totalCount *int, parser *web.Parser, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	limit, offset := parser.ParseLimitAndOffset()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	queryProcessors := []repository.QueryProcessor{repository.Filter("user_id = ?", userIdFromToken)}
+
+	if parser.Form.Get("unread") == "true" {
+		queryProcessors = append(queryProcessors, repository.Filter("is_read = ?", false))
+	}
+
+	queryProcessors = append(queryProcessors,
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("created_at desc"))
+
+	if err := service.repository.GetAll(uow, notifications, queryProcessors...); err != nil {
+		return errors.NewDatabaseError("unable to fetch notifications")
+	}
+
+	return nil
+}
+
+func (service *NotificationService) MarkAsRead(notificationID, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingNotification := notification.Notification{}
+	if err := service.repository.GetRecord(uow, &existingNotification,
+		repository.Filter("id = ? AND user_id = ?", notificationID, userIdFromToken)); err != nil {
+		return errors.NewValidationError("no notification found with given id")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &existingNotification, map[string]interface{}{
+		"is_read":    true,
+		"updated_by": userIdFromToken,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update notification")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *NotificationService) MarkAllAsRead(userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	if err := service.repository.UpdateWithMap(uow, &notification.Notification{}, map[string]interface{}{
+		"is_read":    true,
+		"updated_by": userIdFromToken,
+		"updated_at": time.Now(),
+	}, repository.Filter("user_id = ? AND is_read = ?", userIdFromToken, false)); err != nil {
+		return errors.NewDatabaseError("unable to update notifications")
+	}
+
+	uow.Commit()
+	return nil
+}
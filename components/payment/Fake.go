@@ -0,0 +1,109 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"url-shortner-be/components/money"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	fakeSignatureHeader = "X-Fake-Signature"
+	fakeKeyID           = "fake_key"
+)
+
+// FakeGateway accepts every order and payment and produces webhooks signed like a real gateway would,
+// it stands in for a real gateway locally and in tests.
+type FakeGateway struct {
+	webhookSecret string
+}
+
+type fakeWebhook struct {
+	ID        string `json:"id"`
+	Event     string `json:"event"`
+	OrderID   string `json:"orderId"`
+	PaymentID string `json:"paymentId"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+}
+
+func NewFakeGateway(webhookSecret string) *FakeGateway {
+	if webhookSecret == "" {
+		webhookSecret = "fake-webhook-secret"
+	}
+	return &FakeGateway{
+		webhookSecret: webhookSecret,
+	}
+}
+
+func (gateway *FakeGateway) Name() string {
+	return GatewayFake
+}
+
+func (gateway *FakeGateway) CreateOrder(ctx context.Context, amount money.Money, currency, receipt string) (*Order, error) {
+	return &Order{
+		ID:       "order_" + uuid.NewV4().String(),
+		Amount:   amount,
+		Currency: currency,
+		KeyID:    fakeKeyID,
+	}, nil
+}
+
+func (gateway *FakeGateway) CapturePayment(ctx context.Context, paymentID string, amount money.Money, currency string) error {
+	if !strings.HasPrefix(paymentID, "pay_") {
+		return errors.New("unknown fake payment " + paymentID)
+	}
+	return nil
+}
+
+func (gateway *FakeGateway) Refund(ctx context.Context, paymentID string, amount money.Money) (*Refund, error) {
+	if !strings.HasPrefix(paymentID, "pay_") {
+		return nil, errors.New("unknown fake payment " + paymentID)
+	}
+	return &Refund{ID: "rfnd_" + uuid.NewV4().String(), PaymentID: paymentID, Amount: amount}, nil
+}
+
+func (gateway *FakeGateway) ParseWebhook(body []byte, header http.Header) (*WebhookEvent, error) {
+	if err := verifySignature(body, header.Get(fakeSignatureHeader), gateway.webhookSecret); err != nil {
+		return nil, err
+	}
+
+	delivery := fakeWebhook{}
+	if err := json.Unmarshal(body, &delivery); err != nil {
+		return nil, fmt.Errorf("invalid fake webhook: %w", err)
+	}
+
+	return &WebhookEvent{
+		ID:        delivery.ID,
+		Type:      delivery.Event,
+		OrderID:   delivery.OrderID,
+		PaymentID: delivery.PaymentID,
+		Amount:    money.FromPaise(delivery.Amount),
+		Currency:  delivery.Currency,
+	}, nil
+}
+
+// Pay simulates an event for a payment of the order and returns the signed webhook the gateway would deliver.
+// Calling it again for the same order produces a second delivery for the same payment.
+func (gateway *FakeGateway) Pay(order *Order, eventType string) ([]byte, http.Header, error) {
+	body, err := json.Marshal(fakeWebhook{
+		ID:        "evt_" + uuid.NewV4().String(),
+		Event:     eventType,
+		OrderID:   order.ID,
+		PaymentID: "pay_" + strings.TrimPrefix(order.ID, "order_"),
+		Amount:    order.Amount.Paise(),
+		Currency:  order.Currency,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set(fakeSignatureHeader, Sign(body, gateway.webhookSecret))
+	return body, header, nil
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"io"
+	"net/http"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	paymentService "url-shortner-be/components/payment/service"
+	"url-shortner-be/components/security"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/payment"
+
+	"github.com/gorilla/mux"
+)
+
+const maxWebhookBytes = 1 << 20
+
+type PaymentController struct {
+	log            log.Logger
+	PaymentService *paymentService.PaymentService
+}
+
+func NewPaymentController(paymentService *paymentService.PaymentService, log log.Logger) *PaymentController {
+	return &PaymentController{
+		log:            log,
+		PaymentService: paymentService,
+	}
+}
+
+func (paymentController *PaymentController) RegisterRoutes(router *mux.Router) {
+
+	paymentRouter := router.PathPrefix("/payments").Subrouter()
+	unguardedRouter := paymentRouter.PathPrefix("/").Subrouter()
+	userguardedRouter := paymentRouter.PathPrefix("/").Subrouter()
+	adminguardedRouter := paymentRouter.PathPrefix("/").Subrouter()
+
+	unguardedRouter.HandleFunc("/webhook", paymentController.handleWebhook).Methods(http.MethodPost)
+
+	userguardedRouter.HandleFunc("/orders", paymentController.getAllOrders).Methods(http.MethodGet)
+	// Simulated payments credit the wallet without any money being paid, they only exist with the fake gateway.
+	if paymentController.PaymentService.CanSimulate() {
+		userguardedRouter.HandleFunc("/orders/{orderId}/simulate", paymentController.simulatePayment).Methods(http.MethodPost)
+	}
+
+	adminguardedRouter.HandleFunc("/orders/{orderId}/refund", paymentController.refundOrder).Methods(http.MethodPost)
+
+	userguardedRouter.Use(security.MiddlewareUser)
+	adminguardedRouter.Use(security.MiddlewareAdmin)
+}
+
+func (controller *PaymentController) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("Unable to read request body", http.StatusBadRequest))
+		return
+	}
+
+	if err := controller.PaymentService.HandleWebhook(body, r.Header); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Webhook processed",
+	})
+}
+
+func (controller *PaymentController) getAllOrders(w http.ResponseWriter, r *http.Request) {
+	orders := []payment.PaymentOrder{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.PaymentService.GetAllOrders(&orders, &totalCount, parser, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, orders)
+}
+
+// simulatePayment lets a local client complete checkout when the fake gateway is configured.
+func (controller *PaymentController) simulatePayment(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	orderID, err := parser.GetUUID("orderId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid order ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.PaymentService.SimulatePayment(orderID, userIdFromToken, parser.Form.Get("event")); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Payment simulated",
+	})
+}
+
+func (controller *PaymentController) refundOrder(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	orderID, err := parser.GetUUID("orderId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid order ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.PaymentService.RefundOrder(orderID, userIdFromToken); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Payment refunded successfully",
+	})
+}
@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"url-shortner-be/components/errors"
+	ledgerserv "url-shortner-be/components/ledger/service"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
+	"url-shortner-be/components/payment"
+	transactionserv "url-shortner-be/components/transaction/service"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/ledger"
+	paymentmodel "url-shortner-be/model/payment"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+const gatewayTimeout = 20 * time.Second
+
+type PaymentService struct {
+	db                 *gorm.DB
+	repository         repository.Repository
+	gateway            payment.Gateway
+	ledgerservice      *ledgerserv.LedgerService
+	transactionservice *transactionserv.TransactionService
+}
+
+func NewPaymentService(DB *gorm.DB, repo repository.Repository, gateway payment.Gateway) *PaymentService {
+	return &PaymentService{
+		db:                 DB,
+		repository:         repo,
+		gateway:            gateway,
+		ledgerservice:      ledgerserv.NewLedgerService(DB, repo),
+		transactionservice: transactionserv.NewTransactionService(DB, repo),
+	}
+}
+
+// CreateTopUpOrder opens a gateway order for a wallet top up, the wallet is only credited once the gateway confirms the payment.
+func (service *PaymentService) CreateTopUpOrder(userID uuid.UUID, order *paymentmodel.PaymentOrder) error {
+
+	if err := order.Validate(); err != nil {
+		return err
+	}
+
+	if err := user.Exists(service.db, userID); err != nil {
+		return err
+	}
+
+	order.ID = uuid.NewV4()
+	order.UserID = userID
+	order.Purpose = paymentmodel.PurposeWalletTopUp
+	order.Currency = money.CurrencyINR
+	order.Status = paymentmodel.StatusCreated
+	order.Gateway = service.gateway.Name()
+	order.CreatedBy = userID
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+	defer cancel()
+
+	gatewayOrder, err := service.gateway.CreateOrder(ctx, order.Amount, order.Currency, order.ID.String())
+	if err != nil {
+		log.GetLogger().Error("unable to create gateway order: ", err.Error())
+		return errors.NewHTTPError("unable to create payment order, please try again", http.StatusBadGateway)
+	}
+	order.GatewayOrderID = gatewayOrder.ID
+	order.GatewayKeyID = gatewayOrder.KeyID
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	if err := service.repository.Add(uow, order); err != nil {
+		return errors.NewDatabaseError("unable to save payment order")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *PaymentService) GetAllOrders(orders *[]paymentmodel.PaymentOrder, totalCount *int, parser *web.Parser, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	limit, offset := parser.ParseLimitAndOffset()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetAll(uow, orders,
+		repository.Filter("user_id = ?", userIdFromToken),
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("created_at desc"),
+	); err != nil {
+		return errors.NewDatabaseError("unable to fetch payment orders")
+	}
+
+	return nil
+}
+
+// HandleWebhook applies a signed gateway webhook. Deliveries are recorded by event id so that a
+// redelivered event is a no-op, and order status only moves forward so that late events cannot undo a payment.
+func (service *PaymentService) HandleWebhook(body []byte, header http.Header) error {
+
+	event, err := service.gateway.ParseWebhook(body, header)
+	if err == payment.ErrInvalidSignature {
+		return errors.NewHTTPError("invalid webhook signature", http.StatusUnauthorized)
+	}
+	if err != nil {
+		return errors.NewValidationError(err.Error())
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	delivery := &paymentmodel.WebhookDelivery{
+		Gateway:   service.gateway.Name(),
+		EventID:   event.ID,
+		EventType: event.Type,
+		OrderID:   event.OrderID,
+	}
+	if err := service.repository.Add(uow, delivery); err != nil {
+		if service.isDeliveryProcessed(event.ID) {
+			return nil
+		}
+		return errors.NewDatabaseError("unable to record webhook delivery")
+	}
+
+	switch event.Type {
+	case payment.EventPaymentAuthorized:
+		err = service.capturePayment(uow, event)
+	case payment.EventPaymentCaptured:
+		err = service.markPaid(uow, event)
+	case payment.EventPaymentFailed:
+		err = service.markFailed(uow, event)
+	case payment.EventRefundProcessed:
+		err = service.markRefunded(uow, event)
+	}
+	if err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// RefundOrder refunds a paid top up through the gateway and takes the amount back out of the wallet.
+func (service *PaymentService) RefundOrder(orderID, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	order := &paymentmodel.PaymentOrder{}
+	if err := service.repository.GetRecordByID(uow, orderID, order, repository.ForUpdate()); err != nil {
+		return errors.NewNotFoundError("payment order not found")
+	}
+
+	if order.Status != paymentmodel.StatusPaid {
+		return errors.NewValidationError("only paid orders can be refunded")
+	}
+
+	owner := &user.User{}
+	if err := service.repository.GetRecordByID(uow, order.UserID, owner, repository.ForUpdate()); err != nil {
+		return errors.NewNotFoundError("User not found")
+	}
+
+	// Part of the order may already have been refunded from the gateway dashboard.
+	remaining := order.Amount - order.RefundedAmount
+	if owner.Wallet < remaining {
+		return errors.NewValidationError("wallet balance is lower than the amount to refund")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+	defer cancel()
+
+	if _, err := service.gateway.Refund(ctx, order.GatewayPaymentID, remaining); err != nil {
+		log.GetLogger().Error("unable to refund payment ", order.GatewayPaymentID, ": ", err.Error())
+		return errors.NewHTTPError("unable to refund payment, please try again", http.StatusBadGateway)
+	}
+
+	if err := service.refund(uow, order, remaining); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// CanSimulate reports whether payments can be simulated, which is only the case with the fake gateway.
+func (service *PaymentService) CanSimulate() bool {
+	_, ok := service.gateway.(*payment.FakeGateway)
+	return ok
+}
+
+// SimulatePayment delivers a fake gateway webhook for the user's order, it only works with the fake gateway.
+func (service *PaymentService) SimulatePayment(orderID, userIdFromToken uuid.UUID, eventType string) error {
+
+	fakeGateway, ok := service.gateway.(*payment.FakeGateway)
+	if !ok {
+		return errors.NewHTTPError("payments can only be simulated with the fake gateway", http.StatusNotFound)
+	}
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	order := &paymentmodel.PaymentOrder{}
+	if err := service.repository.GetRecord(uow, order, repository.Filter("id = ? AND user_id = ?", orderID, userIdFromToken)); err != nil {
+		return errors.NewNotFoundError("payment order not found")
+	}
+
+	if eventType == "" {
+		eventType = payment.EventPaymentCaptured
+	}
+
+	body, header, err := fakeGateway.Pay(&payment.Order{ID: order.GatewayOrderID, Amount: order.Amount, Currency: order.Currency}, eventType)
+	if err != nil {
+		return errors.NewValidationError(err.Error())
+	}
+	return service.HandleWebhook(body, header)
+}
+
+// ---------------- Helpers ----------------
+
+func (service *PaymentService) capturePayment(uow *repository.UnitOfWork, event *payment.WebhookEvent) error {
+	order, err := service.lockOrder(uow, event.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.Status != paymentmodel.StatusCreated && order.Status != paymentmodel.StatusFailed {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+	defer cancel()
+
+	// The wallet is credited by the payment.captured event that follows.
+	if err := service.gateway.CapturePayment(ctx, event.PaymentID, order.Amount, order.Currency); err != nil {
+		log.GetLogger().Error("unable to capture payment ", event.PaymentID, ": ", err.Error())
+		return errors.NewHTTPError("unable to capture payment", http.StatusBadGateway)
+	}
+	return nil
+}
+
+func (service *PaymentService) markPaid(uow *repository.UnitOfWork, event *payment.WebhookEvent) error {
+	order, err := service.lockOrder(uow, event.OrderID)
+	if err != nil {
+		return err
+	}
+
+	// A capture after a failure is a successful retry, anything else has already been settled.
+	if order.Status != paymentmodel.StatusCreated && order.Status != paymentmodel.StatusFailed {
+		return nil
+	}
+
+	if event.Amount != order.Amount || (event.Currency != "" && event.Currency != order.Currency) {
+		log.GetLogger().Error("payment ", event.PaymentID, " of ", event.Amount.String(), " does not match order ", order.ID.String())
+		if err := service.repository.UpdateWithMap(uow, order, map[string]interface{}{
+			"status":             paymentmodel.StatusFailed,
+			"gateway_payment_id": event.PaymentID,
+			"failure_reason":     "paid amount does not match the order",
+		}); err != nil {
+			return errors.NewDatabaseError("unable to update payment order")
+		}
+		return nil
+	}
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, order, map[string]interface{}{
+		"status":             paymentmodel.StatusPaid,
+		"gateway_payment_id": event.PaymentID,
+		"failure_reason":     "",
+		"paid_at":            now,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update payment order")
+	}
+
+	var note = fmt.Sprintf("%s added in the wallet", order.Amount)
+
+	if err := service.ledgerservice.PostWalletEntry(uow, order.UserID, order.Amount, ledger.AccountFunding, ledger.EntryTypeCredit, note); err != nil {
+		return err
+	}
+
+	if err := service.transactionservice.CreateTransaction(uow, order.UserID, order.Amount, ledger.EntryTypeCredit, note); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
+	}
+	return nil
+}
+
+func (service *PaymentService) markFailed(uow *repository.UnitOfWork, event *payment.WebhookEvent) error {
+	order, err := service.lockOrder(uow, event.OrderID)
+	if err != nil {
+		return err
+	}
+
+	// A failure reported after the payment went through belongs to an earlier attempt.
+	if order.Status != paymentmodel.StatusCreated {
+		return nil
+	}
+
+	if err := service.repository.UpdateWithMap(uow, order, map[string]interface{}{
+		"status":             paymentmodel.StatusFailed,
+		"gateway_payment_id": event.PaymentID,
+		"failure_reason":     "payment failed at the gateway",
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update payment order")
+	}
+	return nil
+}
+
+// markRefunded handles refunds made from the gateway dashboard, refunds made through RefundOrder are already settled.
+// A partial refund only takes its own amount out of the wallet, a refund beyond what is left of the order is rejected.
+func (service *PaymentService) markRefunded(uow *repository.UnitOfWork, event *payment.WebhookEvent) error {
+	order := &paymentmodel.PaymentOrder{}
+	if err := service.repository.GetRecord(uow, order,
+		repository.Filter("gateway = ? AND gateway_payment_id = ?", service.gateway.Name(), event.PaymentID),
+		repository.ForUpdate()); err != nil {
+		return errors.NewNotFoundError("payment order not found")
+	}
+
+	if order.Status != paymentmodel.StatusPaid {
+		return nil
+	}
+
+	if event.Amount <= 0 || event.Amount > order.Amount-order.RefundedAmount || (event.Currency != "" && event.Currency != order.Currency) {
+		log.GetLogger().Error("refund of ", event.Amount.String(), " for payment ", event.PaymentID, " does not match order ", order.ID.String())
+		return errors.NewValidationError("refund amount does not match the payment order")
+	}
+	return service.refund(uow, order, event.Amount)
+}
+
+// refund takes amount out of the wallet and marks the order REFUNDED once all of it has been refunded.
+func (service *PaymentService) refund(uow *repository.UnitOfWork, order *paymentmodel.PaymentOrder, amount money.Money) error {
+	changes := map[string]interface{}{
+		"refunded_amount_paise": order.RefundedAmount + amount,
+	}
+	if order.RefundedAmount+amount == order.Amount {
+		changes["status"] = paymentmodel.StatusRefunded
+		changes["refunded_at"] = time.Now()
+	}
+	if err := service.repository.UpdateWithMap(uow, order, changes); err != nil {
+		return errors.NewDatabaseError("unable to update payment order")
+	}
+
+	var note = fmt.Sprintf("%s refunded from the wallet", amount)
+
+	if err := service.ledgerservice.PostWalletEntry(uow, order.UserID, -amount, ledger.AccountFunding, ledger.EntryTypeRefund, note); err != nil {
+		return err
+	}
+
+	if err := service.transactionservice.CreateTransaction(uow, order.UserID, amount, ledger.EntryTypeRefund, note); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
+	}
+	return nil
+}
+
+func (service *PaymentService) lockOrder(uow *repository.UnitOfWork, gatewayOrderID string) (*paymentmodel.PaymentOrder, error) {
+	order := &paymentmodel.PaymentOrder{}
+	if err := service.repository.GetRecord(uow, order,
+		repository.Filter("gateway = ? AND gateway_order_id = ?", service.gateway.Name(), gatewayOrderID),
+		repository.ForUpdate()); err != nil {
+		return nil, errors.NewNotFoundError("payment order not found")
+	}
+	return order, nil
+}
+
+func (service *PaymentService) isDeliveryProcessed(eventID string) bool {
+	var count int
+	service.db.Model(&paymentmodel.WebhookDelivery{}).
+		Where("gateway = ? AND event_id = ?", service.gateway.Name(), eventID).
+		Count(&count)
+	return count > 0
+}
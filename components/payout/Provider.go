@@ -0,0 +1,61 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"url-shortner-be/components/config"
+	"url-shortner-be/components/money"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const ProviderFake = "fake"
+
+// ErrNoProvider is returned by NewProvider when PAYOUT_PROVIDER is not set.
+var ErrNoProvider = errors.New("no payout provider is configured")
+
+// Payout is money sent from the platform to a user's bank account or UPI id.
+type Payout struct {
+	// ReferenceID is unique per withdrawal so that a provider can drop a payout sent twice.
+	ReferenceID       string
+	Amount            money.Money
+	Currency          string
+	AccountHolderName string
+	AccountNumber     string
+	IFSC              string
+	UpiID             string
+}
+
+// Provider executes approved withdrawals. A bank or payout API only has to implement this interface.
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, payout *Payout) (reference string, err error)
+}
+
+// NewProvider returns the provider configured by PAYOUT_PROVIDER. The fake provider pays nothing out,
+// so it is only used when asked for by name.
+func NewProvider() (Provider, error) {
+	switch name := config.PayoutProvider.GetStringValue(); name {
+	case ProviderFake:
+		return &FakeProvider{}, nil
+	case "":
+		return nil, ErrNoProvider
+	default:
+		return nil, fmt.Errorf("unknown payout provider %q, set PAYOUT_PROVIDER to %s", name, ProviderFake)
+	}
+}
+
+// FakeProvider accepts every payout without moving money, it stands in for a real provider locally and in tests.
+type FakeProvider struct{}
+
+func (provider *FakeProvider) Name() string {
+	return ProviderFake
+}
+
+func (provider *FakeProvider) Send(ctx context.Context, payout *Payout) (string, error) {
+	if payout.Amount <= 0 {
+		return "", errors.New("payout amount must be greater than zero")
+	}
+	return "payout_" + uuid.NewV4().String(), nil
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Store keeps token bucket state, a shared implementation lets several instances enforce one limit.
+type Store interface {
+	Take(key string, ratePerMinute, burst int, now time.Time) bool
+}
+
+type Limiter struct {
+	store Store
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{
+		store: store,
+	}
+}
+
+// Allow consumes a token for the key, a non positive rate disables the limit.
+func (limiter *Limiter) Allow(key string, ratePerMinute, burst int) bool {
+	if ratePerMinute <= 0 {
+		return true
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return limiter.store.Take(key, ratePerMinute, burst, time.Now())
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// MemoryStore is a process local Store.
+type MemoryStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	maxIdle time.Duration
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		maxIdle: 10 * time.Minute,
+	}
+}
+
+func (store *MemoryStore) Take(key string, ratePerMinute, burst int, now time.Time) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if len(store.buckets) > 10000 {
+		store.sweep(now)
+	}
+
+	b, ok := store.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), lastSeen: now}
+		store.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.lastSeen).Minutes()
+	b.tokens = math.Min(float64(burst), b.tokens+elapsed*float64(ratePerMinute))
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (store *MemoryStore) sweep(now time.Time) {
+	for key, b := range store.buckets {
+		if now.Sub(b.lastSeen) > store.maxIdle {
+			delete(store.buckets, key)
+		}
+	}
+}
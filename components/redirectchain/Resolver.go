@@ -0,0 +1,137 @@
+package redirectchain
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	urlNet "net/url"
+	"strings"
+	"time"
+	"url-shortner-be/components/canonical"
+)
+
+var (
+	ErrLoop        = errors.New("the url redirects in a loop")
+	ErrTooManyHops = errors.New("the url redirects too many times")
+)
+
+// OwnHostError is returned when the url or one of its hops points back at one of our own hosts.
+type OwnHostError struct {
+	Host string
+}
+
+func (e *OwnHostError) Error() string {
+	return fmt.Sprintf("urls pointing to %s cannot be shortened", e.Host)
+}
+
+// Chain lists every url visited from the start url to the final destination, both included.
+type Chain struct {
+	Hops []string
+}
+
+func (chain *Chain) FinalUrl() string {
+	return chain.Hops[len(chain.Hops)-1]
+}
+
+// Resolver follows redirects one hop at a time so that every hop can be checked.
+type Resolver struct {
+	client    *http.Client
+	maxHops   int
+	isOwnHost func(host string) bool
+}
+
+func NewResolver(maxHops int, isOwnHost func(host string) bool) *Resolver {
+	return &Resolver{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		maxHops:   maxHops,
+		isOwnHost: isOwnHost,
+	}
+}
+
+// CheckHost only checks the start url against our own hosts, without making any request.
+func (resolver *Resolver) CheckHost(startUrl string) error {
+	parsed, err := urlNet.Parse(startUrl)
+	if err != nil {
+		return err
+	}
+	if resolver.isOwnHost(strings.ToLower(parsed.Hostname())) {
+		return &OwnHostError{Host: parsed.Hostname()}
+	}
+	return nil
+}
+
+// Resolve follows the redirect chain of startUrl until a url answers without redirecting.
+// It fails on loops, on chains longer than the hop limit and on hops to our own hosts.
+func (resolver *Resolver) Resolve(startUrl string) (*Chain, error) {
+	chain := &Chain{}
+	visited := make(map[string]bool)
+	current := startUrl
+
+	for {
+		if err := resolver.CheckHost(current); err != nil {
+			return nil, err
+		}
+
+		key, err := canonical.Canonicalize(current, false)
+		if err != nil {
+			key = current
+		}
+		if visited[key] {
+			return nil, ErrLoop
+		}
+		visited[key] = true
+		chain.Hops = append(chain.Hops, current)
+
+		next, err := resolver.nextHop(current)
+		if err != nil {
+			return nil, err
+		}
+		if next == "" {
+			return chain, nil
+		}
+		if len(chain.Hops) > resolver.maxHops {
+			return nil, ErrTooManyHops
+		}
+		current = next
+	}
+}
+
+// nextHop returns the absolute location current redirects to, or "" when it does not redirect.
+func (resolver *Resolver) nextHop(current string) (string, error) {
+	resp, err := resolver.client.Head(current)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = resolver.client.Get(current)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 || resp.StatusCode == http.StatusNotModified {
+		return "", nil
+	}
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", nil
+	}
+
+	base, err := urlNet.Parse(current)
+	if err != nil {
+		return "", err
+	}
+	next, err := base.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	if next.Scheme != "http" && next.Scheme != "https" {
+		return "", fmt.Errorf("the url redirects to an unsupported %s url", next.Scheme)
+	}
+	return next.String(), nil
+}
@@ -0,0 +1,29 @@
+package referral
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"url-shortner-be/components/config"
+	"url-shortner-be/components/web"
+)
+
+// DeviceHeader is sent by the apps with an identifier that stays the same for the device.
+const DeviceHeader = "X-Device-Id"
+
+// SignupFingerprints hashes the network and device a registration came from. Browsers send no
+// device identifier, so only the network is compared for them. Raw IPs never leave this function.
+func SignupFingerprints(r *http.Request) (ipHash, deviceHash string) {
+	return hash("ip", web.ClientIP(r)), hash("device", strings.TrimSpace(r.Header.Get(DeviceHeader)))
+}
+
+func hash(kind, value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(config.VisitorSalt.GetStringValue()+":referral"))
+	mac.Write([]byte(kind + "|" + value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"net/http"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	referralService "url-shortner-be/components/referral/service"
+	"url-shortner-be/components/security"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/referral"
+
+	"github.com/gorilla/mux"
+)
+
+type ReferralController struct {
+	log             log.Logger
+	ReferralService *referralService.ReferralService
+}
+
+func NewReferralController(referralService *referralService.ReferralService, log log.Logger) *ReferralController {
+	return &ReferralController{
+		log:             log,
+		ReferralService: referralService,
+	}
+}
+
+// RegisterRoutes adds the referral dashboard under /users next to the other per user routes.
+func (referralController *ReferralController) RegisterRoutes(router *mux.Router) {
+
+	referralRouter := router.PathPrefix("/users").Subrouter()
+	commonRouter := referralRouter.PathPrefix("/").Subrouter()
+
+	commonRouter.HandleFunc("/{userId}/referrals", referralController.getDashboard).Methods(http.MethodGet)
+
+	commonRouter.Use(security.MiddlewareCommon)
+}
+
+// getDashboard returns the user's referral code and rewards, the referrals in it are paginated with limit and offset.
+func (controller *ReferralController) getDashboard(w http.ResponseWriter, r *http.Request) {
+	dashboard := &referral.ReferralDashboard{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	userIdFromUrl, err := parser.GetUUID("userId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.ReferralService.GetDashboard(dashboard, &totalCount, parser, userIdFromUrl, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, dashboard)
+}
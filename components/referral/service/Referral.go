@@ -0,0 +1,283 @@
+package service
+
+import (
+	"strings"
+	"time"
+	"url-shortner-be/components/config"
+	"url-shortner-be/components/errors"
+	ledgerserv "url-shortner-be/components/ledger/service"
+	"url-shortner-be/components/money"
+	transactionserv "url-shortner-be/components/transaction/service"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/ledger"
+	"url-shortner-be/model/referral"
+	"url-shortner-be/model/transaction"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Registrations from the same network or device as another referral of the same referrer
+// within this window are treated as one person signing up repeatedly.
+const duplicateSignupWindow = 30 * 24 * time.Hour
+
+type ReferralService struct {
+	db                 *gorm.DB
+	repository         repository.Repository
+	ledgerservice      *ledgerserv.LedgerService
+	transactionservice *transactionserv.TransactionService
+}
+
+func NewReferralService(DB *gorm.DB, repo repository.Repository) *ReferralService {
+	return &ReferralService{
+		db:                 DB,
+		repository:         repo,
+		ledgerservice:      ledgerserv.NewLedgerService(DB, repo),
+		transactionservice: transactionserv.NewTransactionService(DB, repo),
+	}
+}
+
+// NewCode returns a referral code no other user has.
+func (service *ReferralService) NewCode(uow *repository.UnitOfWork) (string, error) {
+	for attempt := 0; attempt < 10; attempt++ {
+		code := referral.GenerateCode()
+
+		var count int
+		if err := service.repository.GetCount(uow, &user.User{}, &count, repository.Filter("referral_code = ?", code)); err != nil {
+			return "", errors.NewDatabaseError("unable to generate referral code")
+		}
+		if count == 0 {
+			return code, nil
+		}
+	}
+	return "", errors.NewDatabaseError("unable to generate referral code")
+}
+
+// Refer records that the newly created user registered with the referral code in ReferredBy.
+// Registrations that look like the referrer signing up again are recorded as rejected instead of failing.
+func (service *ReferralService) Refer(uow *repository.UnitOfWork, referee *user.User) error {
+	code := strings.ToUpper(strings.TrimSpace(referee.ReferredBy))
+	if code == "" {
+		return nil
+	}
+
+	referrer := &user.User{}
+	if err := service.repository.GetRecord(uow, referrer, repository.Filter("referral_code = ?", code)); err != nil {
+		return errors.NewValidationError("invalid referral code")
+	}
+	if referrer.IsActive != nil && !*referrer.IsActive {
+		return errors.NewValidationError("invalid referral code")
+	}
+
+	newReferral := &referral.Referral{
+		ReferrerID:       referrer.ID,
+		RefereeID:        referee.ID,
+		Code:             code,
+		Status:           referral.StatusPending,
+		SignupIPHash:     referee.SignupIPHash,
+		SignupDeviceHash: referee.SignupDeviceHash,
+		ReferrerCredit:   money.FromRupees(config.ReferralReferrerCredit.GetInt64Value()),
+		RefereeCredit:    money.FromRupees(config.ReferralRefereeCredit.GetInt64Value()),
+		ReferrerFreeUrls: int(config.ReferralReferrerFreeUrls.GetInt64Value()),
+		RefereeFreeUrls:  int(config.ReferralRefereeFreeUrls.GetInt64Value()),
+		ReferredAt:       time.Now(),
+	}
+	newReferral.ID = uuid.NewV4()
+	newReferral.CreatedBy = referee.ID
+
+	reason, err := service.fraudReason(uow, referrer, referee)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		newReferral.Status = referral.StatusRejected
+		newReferral.RejectionReason = reason
+	}
+
+	if err := service.repository.Add(uow, newReferral); err != nil {
+		return errors.NewDatabaseError("unable to record referral")
+	}
+	return nil
+}
+
+// ConfirmReferral rewards both sides of the buyer's pending referral when the purchase is their first paid one,
+// in the purchase's unit of work.
+func (service *ReferralService) ConfirmReferral(uow *repository.UnitOfWork, purchase *transaction.Transaction) error {
+	if purchase.Amount <= 0 {
+		return nil
+	}
+
+	pending := &referral.Referral{}
+	err := service.repository.GetRecord(uow, pending,
+		repository.Filter("referee_id = ? AND status = ?", purchase.UserID, referral.StatusPending),
+		repository.ForUpdate(),
+	)
+	if gorm.IsRecordNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.NewDatabaseError("unable to fetch referral")
+	}
+
+	if err := service.reward(uow, pending.ReferrerID, pending.ReferrerCredit, pending.ReferrerFreeUrls, "Referral reward for inviting a new user"); err != nil {
+		return err
+	}
+	if err := service.reward(uow, pending.RefereeID, pending.RefereeCredit, pending.RefereeFreeUrls, "Referral reward for joining with code "+pending.Code); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, &referral.Referral{}, map[string]interface{}{
+		"status":         referral.StatusConfirmed,
+		"transaction_id": purchase.ID,
+		"confirmed_at":   &now,
+		"updated_by":     purchase.UserID,
+	}, repository.Filter("id = ?", pending.ID)); err != nil {
+		return errors.NewDatabaseError("unable to confirm referral")
+	}
+	return nil
+}
+
+// GetDashboard returns the user's referral code, the referrals made with it and the rewards earned.
+func (service *ReferralService) GetDashboard(dashboard *referral.ReferralDashboard, totalCount *int, parser *web.Parser, userIdFromUrl, userIdFromToken uuid.UUID) error {
+
+	limit, offset := parser.ParseLimitAndOffset()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	owner, err := service.checkAccess(uow, userIdFromUrl, userIdFromToken)
+	if err != nil {
+		return err
+	}
+	dashboard.ReferralCode = owner.ReferralCode
+
+	summaries := []referral.ReferralSummary{}
+	if err := service.repository.GetRaw(uow, &summaries, repository.RawQuery(`
+		SELECT status, COUNT(*) AS count, SUM(referrer_credit_paise) AS credit, SUM(referrer_free_urls) AS free_urls
+		FROM referrals
+		WHERE referrer_id = ? AND deleted_at IS NULL
+		GROUP BY status
+	`, owner.ID)); err != nil {
+		return errors.NewDatabaseError("unable to fetch referrals")
+	}
+	for _, summary := range summaries {
+		switch summary.Status {
+		case referral.StatusPending:
+			dashboard.Pending = summary.Count
+			dashboard.PendingCredit = summary.Credit
+		case referral.StatusConfirmed:
+			dashboard.Confirmed = summary.Count
+			dashboard.EarnedCredit = summary.Credit
+			dashboard.EarnedFreeUrls = summary.FreeUrls
+		case referral.StatusRejected:
+			dashboard.Rejected = summary.Count
+		}
+	}
+
+	dashboard.Referrals = []referral.Referral{}
+	if err := service.repository.GetAll(uow, &dashboard.Referrals,
+		repository.Filter("referrer_id = ?", owner.ID),
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("referred_at desc"),
+	); err != nil {
+		return errors.NewDatabaseError("unable to fetch referrals")
+	}
+
+	referredBy := &referral.Referral{}
+	err = service.repository.GetRecord(uow, referredBy, repository.Filter("referee_id = ?", owner.ID))
+	if err == nil {
+		dashboard.ReferredBy = referredBy
+	} else if !gorm.IsRecordNotFoundError(err) {
+		return errors.NewDatabaseError("unable to fetch referral")
+	}
+
+	return nil
+}
+
+// ---------------- Helpers ----------------
+
+// fraudReason says why the registration looks like the referrer referring themselves, or is empty when it does not.
+func (service *ReferralService) fraudReason(uow *repository.UnitOfWork, referrer, referee *user.User) (string, error) {
+	refereeEmail := referee.Email
+	if refereeEmail == "" && referee.Credentials != nil {
+		refereeEmail = referee.Credentials.Email
+	}
+	if strings.EqualFold(referrer.Email, refereeEmail) || (referrer.PhoneNo != "" && referrer.PhoneNo == referee.PhoneNo) {
+		return "self referral", nil
+	}
+
+	if referee.SignupIPHash != "" && referee.SignupIPHash == referrer.SignupIPHash {
+		return "registered from the referrer's network", nil
+	}
+	if referee.SignupDeviceHash != "" && referee.SignupDeviceHash == referrer.SignupDeviceHash {
+		return "registered from the referrer's device", nil
+	}
+
+	conditions := []string{}
+	values := []interface{}{referrer.ID, time.Now().Add(-duplicateSignupWindow)}
+	if referee.SignupIPHash != "" {
+		conditions = append(conditions, "signup_ip_hash = ?")
+		values = append(values, referee.SignupIPHash)
+	}
+	if referee.SignupDeviceHash != "" {
+		conditions = append(conditions, "signup_device_hash = ?")
+		values = append(values, referee.SignupDeviceHash)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	var count int
+	if err := service.repository.GetCount(uow, &referral.Referral{}, &count,
+		repository.Filter("referrer_id = ? AND referred_at >= ? AND ("+strings.Join(conditions, " OR ")+")", values...),
+	); err != nil {
+		return "", errors.NewDatabaseError("unable to fetch referrals")
+	}
+	if count > 0 {
+		return "registered from the same network or device as another referral", nil
+	}
+	return "", nil
+}
+
+// reward credits the user's wallet from the referral rewards account and adds free urls to their account.
+func (service *ReferralService) reward(uow *repository.UnitOfWork, userID uuid.UUID, credit money.Money, freeUrls int, note string) error {
+	if credit > 0 {
+		if err := service.ledgerservice.PostWalletEntry(uow, userID, credit, ledger.AccountReferralRewards, ledger.EntryTypeReferralReward, note); err != nil {
+			return err
+		}
+		if err := service.transactionservice.CreateTransaction(uow, userID, credit, ledger.EntryTypeReferralReward, note); err != nil {
+			return errors.NewDatabaseError("unable to create transaction")
+		}
+	}
+
+	if freeUrls > 0 {
+		if err := service.repository.UpdateWithMap(uow, &user.User{}, map[string]interface{}{
+			"url_count":  gorm.Expr("url_count + ?", freeUrls),
+			"updated_at": time.Now(),
+		}, repository.Filter("id = ?", userID)); err != nil {
+			return errors.NewDatabaseError("unable to add free urls")
+		}
+	}
+	return nil
+}
+
+func (service *ReferralService) checkAccess(uow *repository.UnitOfWork, userIdFromUrl, userIdFromToken uuid.UUID) (*user.User, error) {
+	actualUser := &user.User{}
+	if err := service.repository.GetRecordByID(uow, userIdFromUrl, actualUser); err != nil {
+		return nil, errors.NewUnauthorizedError("invalid user making the request")
+	}
+
+	tokenUser := &user.User{}
+	if err := service.repository.GetRecordByID(uow, userIdFromToken, tokenUser); err != nil {
+		return nil, errors.NewUnauthorizedError("invalid user making the request")
+	}
+
+	isAdmin := tokenUser.IsAdmin != nil && *tokenUser.IsAdmin
+	if actualUser.ID != tokenUser.ID && !isAdmin {
+		return nil, errors.NewUnauthorizedError("you are not authorized to view referrals of this user")
+	}
+	return actualUser, nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"url-shortner-be/components/config"
+)
+
+// File is an opened stored object, seekable so that it can answer range requests.
+type File interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// Storage keeps uploaded files by key. Local disk is the only driver for now,
+// an S3-compatible store only has to implement this interface.
+type Storage interface {
+	Save(key string, content io.Reader) (int64, error)
+	Open(key string) (File, error)
+	Delete(key string) error
+}
+
+// NewStorage returns the storage configured by STORAGE_DRIVER, files are kept below STORAGE_DIR on local disk.
+func NewStorage() Storage {
+	dir := config.StorageDir.GetStringValue()
+	if dir == "" {
+		dir = "uploads"
+	}
+	return NewLocalStorage(dir)
+}
+
+// LocalStorage stores every key as a file below Dir.
+type LocalStorage struct {
+	Dir string
+}
+
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{Dir: dir}
+}
+
+func (storage *LocalStorage) Save(key string, content io.Reader) (int64, error) {
+	path, err := storage.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return written, nil
+}
+
+func (storage *LocalStorage) Open(key string) (File, error) {
+	path, err := storage.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (storage *LocalStorage) Delete(key string) error {
+	path, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (storage *LocalStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if key == "" || strings.Contains(key, "..") {
+		return "", errors.New("invalid storage key")
+	}
+	return filepath.Join(storage.Dir, cleaned), nil
+}
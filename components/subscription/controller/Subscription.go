@@ -11,6 +11,7 @@ import (
 	"url-shortner-be/model/subscription"
 
 	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
 )
 
 type SubscriptionController struct {
@@ -32,12 +33,24 @@ func (SubscriptionController *SubscriptionController) RegisterRoutes(router *mux
 	commonRouter := subscriptionRouter.PathPrefix("/").Subrouter()
 
 	commonRouter.HandleFunc("/subscription", SubscriptionController.getSubscriptionPrice).Methods(http.MethodGet)
+	commonRouter.HandleFunc("/plans", SubscriptionController.getPlans).Methods(http.MethodGet)
 
 	guardedRouter.HandleFunc("/subscription", SubscriptionController.setSubscriptionPrice).Methods(http.MethodPost)
 	guardedRouter.HandleFunc("/subscription/update", SubscriptionController.updateSubscriptionPrice).Methods(http.MethodPut)
+	guardedRouter.HandleFunc("/subscription/plans", SubscriptionController.getAllPlans).Methods(http.MethodGet)
 
 	guardedRouter.Use(security.MiddlewareAdmin)
 	commonRouter.Use(security.MiddlewareCommon)
+
+	// The plan a user is on sits under /users next to the other per user routes.
+	userRouter := router.PathPrefix("/users").Subrouter()
+	userPlanRouter := userRouter.PathPrefix("/").Subrouter()
+
+	userPlanRouter.HandleFunc("/{userId}/plan", SubscriptionController.getUserPlan).Methods(http.MethodGet)
+	userPlanRouter.HandleFunc("/{userId}/plan/upgrade", SubscriptionController.upgradePlan).Methods(http.MethodPost)
+	userPlanRouter.HandleFunc("/{userId}/plan/downgrade", SubscriptionController.downgradePlan).Methods(http.MethodPost)
+
+	userPlanRouter.Use(security.MiddlewareCommon)
 }
 
 func (controller *SubscriptionController) setSubscriptionPrice(w http.ResponseWriter, r *http.Request) {
@@ -147,3 +160,101 @@ func (controller *SubscriptionController) updateSubscriptionPrice(w http.Respons
 
 	web.RespondJSON(w, http.StatusOK, "Subscription Prices updated successfully")
 }
+
+// getPlans lists the plans users can switch to.
+func (controller *SubscriptionController) getPlans(w http.ResponseWriter, r *http.Request) {
+	plans := []subscription.Subscription{}
+
+	if err := controller.SubscriptionService.GetAllPlans(&plans, false); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, plans)
+}
+
+// getAllPlans lists every plan for admins, including the ones that can no longer be switched to.
+func (controller *SubscriptionController) getAllPlans(w http.ResponseWriter, r *http.Request) {
+	plans := []subscription.Subscription{}
+
+	if err := controller.SubscriptionService.GetAllPlans(&plans, true); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, plans)
+}
+
+func (controller *SubscriptionController) getUserPlan(w http.ResponseWriter, r *http.Request) {
+	userPlan := subscription.UserPlan{}
+	parser := web.NewParser(r)
+
+	userIdFromUrl, err := parser.GetUUID("userId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.SubscriptionService.GetUserPlan(&userPlan, userIdFromUrl, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, userPlan)
+}
+
+// upgradePlan moves the user to a dearer plan straight away, charging the pro-rated difference to their wallet.
+func (controller *SubscriptionController) upgradePlan(w http.ResponseWriter, r *http.Request) {
+	controller.changePlan(w, r, controller.SubscriptionService.UpgradePlan)
+}
+
+// downgradePlan moves the user to a cheaper plan when the period they paid for ends.
+func (controller *SubscriptionController) downgradePlan(w http.ResponseWriter, r *http.Request) {
+	controller.changePlan(w, r, controller.SubscriptionService.DowngradePlan)
+}
+
+func (controller *SubscriptionController) changePlan(w http.ResponseWriter, r *http.Request,
+	change func(*subscription.UserPlan, uuid.UUID, uuid.UUID, uuid.UUID) error) {
+
+	userPlan := subscription.UserPlan{}
+	parser := web.NewParser(r)
+
+	userIdFromUrl, err := parser.GetUUID("userId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	body := struct {
+		SubscriptionID uuid.UUID `json:"subscriptionId"`
+	}{}
+	if err := web.UnmarshalJSON(r, &body); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+	if uuid.Equal(body.SubscriptionID, uuid.Nil) {
+		web.RespondError(w, errors.NewValidationError("subscriptionId must be specified"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := change(&userPlan, body.SubscriptionID, userIdFromUrl, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, userPlan)
+}
@@ -1,9 +1,20 @@
 package service
 
 import (
+	"fmt"
 	"time"
 	"url-shortner-be/components/errors"
+	invoiceserv "url-shortner-be/components/invoice/service"
+	ledgerserv "url-shortner-be/components/ledger/service"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
+	referralserv "url-shortner-be/components/referral/service"
+	taxserv "url-shortner-be/components/tax/service"
+	transactionserv "url-shortner-be/components/transaction/service"
+	"url-shortner-be/model/invoice"
+	"url-shortner-be/model/ledger"
 	"url-shortner-be/model/subscription"
+	"url-shortner-be/model/transaction"
 	"url-shortner-be/model/user"
 	"url-shortner-be/module/repository"
 
@@ -12,20 +23,31 @@ import (
 )
 
 type SubscriptionService struct {
-	db         *gorm.DB
-	repository repository.Repository
+	db                 *gorm.DB
+	repository         repository.Repository
+	ledgerservice      *ledgerserv.LedgerService
+	transactionservice *transactionserv.TransactionService
+	invoiceservice     *invoiceserv.InvoiceService
+	taxservice         *taxserv.TaxService
+	referralservice    *referralserv.ReferralService
 }
 
 func NewSubscriptionService(DB *gorm.DB, repo repository.Repository) *SubscriptionService {
 	return &SubscriptionService{
-		db:         DB,
-		repository: repo,
+		db:                 DB,
+		repository:         repo,
+		ledgerservice:      ledgerserv.NewLedgerService(DB, repo),
+		transactionservice: transactionserv.NewTransactionService(DB, repo),
+		invoiceservice:     invoiceserv.NewInvoiceService(DB, repo),
+		taxservice:         taxserv.NewTaxService(DB, repo),
+		referralservice:    referralserv.NewReferralService(DB, repo),
 	}
 }
 
+// SetSubscriptionPrice adds a plan, the first plan added is the default one new users are put on.
 func (service *SubscriptionService) SetSubscriptionPrice(subscriptionPrices *subscription.Subscription, userIdFromToken uuid.UUID) error {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return err
 	}
 
@@ -39,8 +61,27 @@ func (service *SubscriptionService) SetSubscriptionPrice(subscriptionPrices *sub
 		return errors.NewDatabaseError("Unable to count subscriptions.")
 	}
 
-	if subscriptionCount >= 1 {
-		return errors.NewValidationError("Subscription price already set. You can update it.")
+	if err := service.isNameTaken(uow, subscriptionPrices.Name, uuid.Nil); err != nil {
+		return err
+	}
+
+	if subscriptionCount == 0 {
+		isDefault := true
+		subscriptionPrices.IsDefault = &isDefault
+	}
+	if subscriptionPrices.IsActive == nil {
+		isActive := true
+		subscriptionPrices.IsActive = &isActive
+	}
+	subscriptionPrices.ID = uuid.NewV4()
+
+	if subscriptionPrices.IsDefault != nil && *subscriptionPrices.IsDefault {
+		if !*subscriptionPrices.IsActive {
+			return errors.NewValidationError("an inactive plan cannot be the default plan")
+		}
+		if err := service.clearDefault(uow); err != nil {
+			return err
+		}
 	}
 
 	if err := service.repository.Add(uow, &subscriptionPrices); err != nil {
@@ -52,27 +93,51 @@ func (service *SubscriptionService) SetSubscriptionPrice(subscriptionPrices *sub
 	return nil
 }
 
+// GetPrice returns the plan of the user making the request.
 func (service *SubscriptionService) GetPrice(latest *subscription.Subscription, userIdFromToken uuid.UUID) error {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return err
 	}
 
 	uow := repository.NewUnitOfWork(service.db, true)
 	defer uow.RollBack()
 
-	err := service.repository.GetAll(uow, &latest)
-	if err != nil {
-		return errors.NewDatabaseError("unable to fetch subscription details")
+	tokenUser := &user.User{}
+	if err := service.repository.GetRecordByID(uow, userIdFromToken, tokenUser); err != nil {
+		return errors.NewDatabaseError("unable to get user record")
+	}
+
+	if err := service.PlanFor(uow, tokenUser, latest); err != nil {
+		return err
 	}
 
 	// uow.Commit()
 	return nil
 }
 
+// GetAllPlans lists the plans users can switch to, admins can ask for the inactive ones as well.
+func (service *SubscriptionService) GetAllPlans(plans *[]subscription.Subscription, includeInactive bool) error {
+
+	var queryProcessors []repository.QueryProcessor
+	if !includeInactive {
+		queryProcessors = append(queryProcessors, repository.Filter("is_active = ?", true))
+	}
+	queryProcessors = append(queryProcessors, repository.Order("monthly_price_paise, name"))
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetAll(uow, plans, queryProcessors...); err != nil {
+		return errors.NewDatabaseError("unable to fetch plans")
+	}
+
+	return nil
+}
+
 func (service *SubscriptionService) UpdateSubscriptionPrice(prices *subscription.Subscription, userIdFromToken uuid.UUID) error {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return err
 	}
 
@@ -88,9 +153,72 @@ func (service *SubscriptionService) UpdateSubscriptionPrice(prices *subscription
 		return errors.NewUnauthorizedError("Inactive users cannot update subscription")
 	}
 
+	existingPlan := &subscription.Subscription{}
+	if err := service.repository.GetRecordByID(uow, prices.ID, existingPlan); err != nil {
+		return errors.NewNotFoundError("plan not found")
+	}
+
+	if err := service.isNameTaken(uow, prices.Name, prices.ID); err != nil {
+		return err
+	}
+
+	// There is always a default plan, another plan has to be made the default instead.
+	if existingPlan.IsDefault != nil && *existingPlan.IsDefault {
+		if prices.IsDefault != nil && !*prices.IsDefault {
+			return errors.NewValidationError("make another plan the default instead")
+		}
+		if prices.IsActive != nil && !*prices.IsActive {
+			return errors.NewValidationError("the default plan cannot be deactivated")
+		}
+	}
+	isActive := existingPlan.IsActive == nil || *existingPlan.IsActive
+	if prices.IsActive != nil {
+		isActive = *prices.IsActive
+	}
+	if prices.IsDefault != nil && *prices.IsDefault {
+		if !isActive {
+			return errors.NewValidationError("an inactive plan cannot be the default plan")
+		}
+		if err := service.clearDefault(uow); err != nil {
+			return err
+		}
+	}
+
 	prices.UpdatedAt = time.Now()
 
-	if err := service.repository.Update(uow, prices); err != nil {
+	// Prices and limits are written even when they are zero, that is how a plan is made free.
+	// The flags, currency and file settings are left as they are when they are not sent.
+	updates := map[string]interface{}{
+		"name":                    prices.Name,
+		"description":             prices.Description,
+		"monthly_price_paise":     prices.MonthlyPrice,
+		"free_short_urls":         prices.FreeShortUrls,
+		"free_visits":             prices.FreeVisits,
+		"new_url_price_paise":     prices.NewUrlPrice,
+		"extra_visit_price_paise": prices.ExtraVisitPrice,
+		"updated_by":              prices.UpdatedBy,
+		"updated_at":              prices.UpdatedAt,
+	}
+	if prices.IsDefault != nil {
+		updates["is_default"] = *prices.IsDefault
+	}
+	if prices.IsActive != nil {
+		updates["is_active"] = *prices.IsActive
+	}
+	if prices.TransferConsumesUrlCount != nil {
+		updates["transfer_consumes_url_count"] = *prices.TransferConsumesUrlCount
+	}
+	if prices.Currency != "" {
+		updates["currency"] = prices.Currency
+	}
+	if prices.MaxFileSizeMB != 0 {
+		updates["max_file_size_mb"] = prices.MaxFileSizeMB
+	}
+	if prices.AllowedFileTypes != "" {
+		updates["allowed_file_types"] = prices.AllowedFileTypes
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &subscription.Subscription{}, updates, repository.Filter("id = ?", prices.ID)); err != nil {
 		return errors.NewDatabaseError("unable to update Subscription Price")
 	}
 
@@ -98,12 +226,352 @@ func (service *SubscriptionService) UpdateSubscriptionPrice(prices *subscription
 	return nil
 }
 
+// PlanFor loads the plan of the user, the default plan for users that have none.
+func (service *SubscriptionService) PlanFor(uow *repository.UnitOfWork, planUser *user.User, plan *subscription.Subscription) error {
+	if planUser.SubscriptionID == nil {
+		return service.DefaultPlan(uow, plan)
+	}
+
+	if err := service.repository.GetRecordByID(uow, *planUser.SubscriptionID, plan); err != nil {
+		return errors.NewDatabaseError("unable to fetch subscription details")
+	}
+	return nil
+}
+
+// freePlan loads the plan users who cannot pay for a renewal are moved to, the default plan when it is free
+// and otherwise the first active free plan. Without a free plan the renewal stays due and is tried again.
+func (service *SubscriptionService) freePlan(uow *repository.UnitOfWork, plan *subscription.Subscription) error {
+	if err := service.repository.GetRecord(uow, plan, repository.Filter("monthly_price_paise = 0 AND is_active = ?", true),
+		repository.Order("is_default desc, name")); err != nil {
+		return errors.NewDatabaseError("there is no free plan to move users who cannot pay for their plan to")
+	}
+	return nil
+}
+
+// DefaultPlan loads the plan new users are put on.
+func (service *SubscriptionService) DefaultPlan(uow *repository.UnitOfWork, plan *subscription.Subscription) error {
+	if err := service.repository.GetRecord(uow, plan, repository.Filter("is_default = ?", true)); err != nil {
+		return errors.NewDatabaseError("Admin has not set subscription price yet, please contact admin")
+	}
+	return nil
+}
+
+func (service *SubscriptionService) GetUserPlan(userPlan *subscription.UserPlan, userIdFromUrl, userIdFromToken uuid.UUID) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	planUser, err := service.checkAccess(uow, userIdFromUrl, userIdFromToken)
+	if err != nil {
+		return err
+	}
+
+	return service.loadUserPlan(uow, planUser, userPlan)
+}
+
+// UpgradePlan moves the user to a plan that costs at least as much straight away. The new plan is charged
+// from the wallet for the rest of the current period less what is left of the old plan, a user on a free
+// plan or whose period has ended pays for a full period from now. The free urls the new plan has over
+// the old one are added to the user's account.
+func (service *SubscriptionService) UpgradePlan(userPlan *subscription.UserPlan, planID, userID, userIdFromToken uuid.UUID) error {
+
+	if userID != userIdFromToken {
+		return errors.NewUnauthorizedError("you are not authorized to change the plan of this user")
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	planUser, current, target, err := service.loadPlanChange(uow, planID, userID)
+	if err != nil {
+		return err
+	}
+	if target.ID == current.ID {
+		return errors.NewValidationError("you are already on the " + current.Name + " plan")
+	}
+	if target.MonthlyPrice < current.MonthlyPrice {
+		return errors.NewValidationError(fmt.Sprintf("%s costs less than %s, downgrade to it instead", target.Name, current.Name))
+	}
+
+	now := time.Now()
+	price := target.MonthlyPrice
+	var renewsAt *time.Time
+	if !target.IsFree() {
+		nextPeriod := now.Add(subscription.PlanPeriod)
+		renewsAt = &nextPeriod
+	}
+	description := fmt.Sprintf("%s plan for %d days", target.Name, int(subscription.PlanPeriod.Hours()/24))
+	if !current.IsFree() && planUser.PlanRenewsAt != nil && planUser.PlanRenewsAt.After(now) {
+		price = subscription.Prorate(target.MonthlyPrice-current.MonthlyPrice, *planUser.PlanRenewsAt, now)
+		renewsAt = planUser.PlanRenewsAt
+		description = fmt.Sprintf("%s plan, pro-rated upgrade from %s until %s", target.Name, current.Name, renewsAt.Format("02 Jan 2006"))
+	}
+
+	if err := service.purchase(uow, planUser, price, fmt.Sprintf("Upgraded to %s plan", target.Name), description); err != nil {
+		return err
+	}
+
+	changes := map[string]interface{}{
+		"subscription_id":         target.ID,
+		"pending_subscription_id": nil,
+		"plan_renews_at":          renewsAt,
+		"updated_by":              userID,
+	}
+	if extraUrls := target.FreeShortUrls - current.FreeShortUrls; extraUrls > 0 {
+		changes["url_count"] = gorm.Expr("url_count + ?", extraUrls)
+	}
+	if err := service.repository.UpdateWithMap(uow, &user.User{}, changes, repository.Filter("id = ?", planUser.ID)); err != nil {
+		return errors.NewDatabaseError("unable to change plan")
+	}
+
+	planUser.SubscriptionID, planUser.PendingSubscriptionID, planUser.PlanRenewsAt = &target.ID, nil, renewsAt
+	if err := service.loadUserPlan(uow, planUser, userPlan); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// DowngradePlan moves the user to a cheaper plan once the period they have paid for ends, nothing is refunded.
+// Downgrading to the plan the user is on cancels a downgrade that is still waiting.
+func (service *SubscriptionService) DowngradePlan(userPlan *subscription.UserPlan, planID, userID, userIdFromToken uuid.UUID) error {
+
+	if userID != userIdFromToken {
+		return errors.NewUnauthorizedError("you are not authorized to change the plan of this user")
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	planUser, current, target, err := service.loadPlanChange(uow, planID, userID)
+	if err != nil {
+		return err
+	}
+
+	changes := map[string]interface{}{
+		"updated_by": userID,
+	}
+	switch {
+	case target.ID == current.ID && planUser.PendingSubscriptionID == nil:
+		return errors.NewValidationError("you are already on the " + current.Name + " plan")
+	case target.ID == current.ID:
+		changes["pending_subscription_id"] = nil
+		planUser.PendingSubscriptionID = nil
+	case target.MonthlyPrice >= current.MonthlyPrice:
+		return errors.NewValidationError(fmt.Sprintf("%s does not cost less than %s, upgrade to it instead", target.Name, current.Name))
+	case planUser.PlanRenewsAt != nil && planUser.PlanRenewsAt.After(time.Now()):
+		changes["pending_subscription_id"] = target.ID
+		planUser.PendingSubscriptionID = &target.ID
+	default:
+		changes["subscription_id"] = target.ID
+		changes["pending_subscription_id"] = nil
+		changes["plan_renews_at"] = nil
+		planUser.SubscriptionID, planUser.PendingSubscriptionID, planUser.PlanRenewsAt = &target.ID, nil, nil
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &user.User{}, changes, repository.Filter("id = ?", planUser.ID)); err != nil {
+		return errors.NewDatabaseError("unable to change plan")
+	}
+
+	if err := service.loadUserPlan(uow, planUser, userPlan); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// RenewPlans charges the users whose paid period has ended for the next one, moving them to the plan they
+// downgraded to first. Users who cannot pay are moved to the default plan.
+func (service *SubscriptionService) RenewPlans() {
+	users := []user.User{}
+	if err := service.db.Where("plan_renews_at <= ?", time.Now()).Find(&users).Error; err != nil {
+		log.GetLogger().Error("unable to fetch plans due for renewal: ", err.Error())
+		return
+	}
+
+	for _, due := range users {
+		if err := service.renewPlan(due.ID); err != nil {
+			log.GetLogger().Error("plan renewal failed for user ", due.ID, ": ", err.Error())
+		}
+	}
+}
+
 // ---------------- Helpers ----------------
 
-func (service *SubscriptionService) doesUserExist(ID uuid.UUID) error {
-	var u user.User
-	if err := service.db.First(&u, "id = ?", ID).Error; err != nil {
-		return errors.NewValidationError("Admin Doesn't exists")
+func (service *SubscriptionService) renewPlan(userID uuid.UUID) error {
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	planUser := &user.User{}
+	if err := service.repository.GetRecordByID(uow, userID, planUser, repository.ForUpdate()); err != nil {
+		return errors.NewNotFoundError("User not found")
+	}
+
+	now := time.Now()
+	if planUser.PlanRenewsAt == nil || planUser.PlanRenewsAt.After(now) {
+		return nil
+	}
+
+	next := &subscription.Subscription{}
+	if planUser.PendingSubscriptionID != nil {
+		if err := service.repository.GetRecordByID(uow, *planUser.PendingSubscriptionID, next); err != nil {
+			return errors.NewDatabaseError("unable to fetch subscription details")
+		}
+	} else if err := service.PlanFor(uow, planUser, next); err != nil {
+		return err
+	}
+
+	var renewsAt *time.Time
+	if !next.IsFree() {
+		nextPeriod := planUser.PlanRenewsAt.Add(subscription.PlanPeriod)
+		if !nextPeriod.After(now) {
+			nextPeriod = now.Add(subscription.PlanPeriod)
+		}
+		renewsAt = &nextPeriod
+
+		description := fmt.Sprintf("%s plan for %d days", next.Name, int(subscription.PlanPeriod.Hours()/24))
+		err := service.purchase(uow, planUser, next.MonthlyPrice, fmt.Sprintf("Renewed %s plan", next.Name), description)
+		if err != nil {
+			if err := service.freePlan(uow, next); err != nil {
+				return err
+			}
+			renewsAt = nil
+			log.GetLogger().Info("plan of user ", planUser.ID, " moved to ", next.Name, ": ", err.Error())
+		}
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &user.User{}, map[string]interface{}{
+		"subscription_id":         next.ID,
+		"pending_subscription_id": nil,
+		"plan_renews_at":          renewsAt,
+	}, repository.Filter("id = ?", planUser.ID)); err != nil {
+		return errors.NewDatabaseError("unable to renew plan")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// purchase charges a plan to the buyer's wallet with GST and invoices it, nothing is charged for a zero price.
+func (service *SubscriptionService) purchase(uow *repository.UnitOfWork, buyer *user.User, price money.Money, note, description string) error {
+	if price <= 0 {
+		return nil
+	}
+
+	breakdown, err := service.taxservice.Quote(uow, buyer, price)
+	if err != nil {
+		return err
+	}
+
+	if buyer.Wallet < breakdown.Total {
+		return errors.NewValidationError("insufficient balance in wallet, please add money to wallet")
+	}
+
+	transactionType := ledger.EntryTypePlanPurchase
+	if err := service.ledgerservice.PostWalletPurchase(uow, buyer.ID, breakdown.Taxable, breakdown.Tax(), transactionType, note); err != nil {
+		return err
+	}
+
+	purchase := transaction.NewPurchase(buyer.ID, transactionType, note, breakdown)
+	if err := service.transactionservice.RecordTransaction(uow, purchase); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
+	}
+
+	if err := service.referralservice.ConfirmReferral(uow, purchase); err != nil {
+		return err
+	}
+
+	if _, err := service.invoiceservice.IssueInvoice(uow, purchase, []*invoice.InvoiceLine{
+		invoice.NewLine(description, 1, price),
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// loadPlanChange locks the user and loads their plan and the active plan they want to move to.
+func (service *SubscriptionService) loadPlanChange(uow *repository.UnitOfWork, planID, userID uuid.UUID) (*user.User, *subscription.Subscription, *subscription.Subscription, error) {
+	planUser := &user.User{}
+	if err := service.repository.GetRecordByID(uow, userID, planUser, repository.ForUpdate()); err != nil {
+		return nil, nil, nil, errors.NewNotFoundError("User not found")
+	}
+
+	if !*planUser.IsActive {
+		return nil, nil, nil, errors.NewValidationError("Inactive users cannot change plans")
+	}
+
+	current := &subscription.Subscription{}
+	if err := service.PlanFor(uow, planUser, current); err != nil {
+		return nil, nil, nil, err
+	}
+
+	target := &subscription.Subscription{}
+	if err := service.repository.GetRecordByID(uow, planID, target); err != nil {
+		return nil, nil, nil, errors.NewNotFoundError("plan not found")
+	}
+
+	if target.ID != current.ID && target.IsActive != nil && !*target.IsActive {
+		return nil, nil, nil, errors.NewValidationError("the " + target.Name + " plan is no longer available")
+	}
+	return planUser, current, target, nil
+}
+
+func (service *SubscriptionService) loadUserPlan(uow *repository.UnitOfWork, planUser *user.User, userPlan *subscription.UserPlan) error {
+	userPlan.Plan = &subscription.Subscription{}
+	if err := service.PlanFor(uow, planUser, userPlan.Plan); err != nil {
+		return err
+	}
+
+	userPlan.PendingPlan = nil
+	if planUser.PendingSubscriptionID != nil {
+		userPlan.PendingPlan = &subscription.Subscription{}
+		if err := service.repository.GetRecordByID(uow, *planUser.PendingSubscriptionID, userPlan.PendingPlan); err != nil {
+			return errors.NewDatabaseError("unable to fetch subscription details")
+		}
+	}
+
+	userPlan.RenewsAt = planUser.PlanRenewsAt
+	return nil
+}
+
+func (service *SubscriptionService) isNameTaken(uow *repository.UnitOfWork, name string, excludeID uuid.UUID) error {
+	var count int
+	if err := service.repository.GetCount(uow, &subscription.Subscription{}, &count,
+		repository.Filter("name = ? AND id != ?", name, excludeID),
+	); err != nil {
+		return errors.NewDatabaseError("Unable to count subscriptions.")
+	}
+	if count > 0 {
+		return errors.NewValidationError("a plan named " + name + " already exists")
+	}
+	return nil
+}
+
+func (service *SubscriptionService) clearDefault(uow *repository.UnitOfWork) error {
+	if err := service.repository.UpdateWithMap(uow, &subscription.Subscription{}, map[string]interface{}{
+		"is_default": false,
+	}, repository.Filter("is_default = ?", true)); err != nil {
+		return errors.NewDatabaseError("unable to change the default plan")
 	}
 	return nil
 }
+
+func (service *SubscriptionService) checkAccess(uow *repository.UnitOfWork, userIdFromUrl, userIdFromToken uuid.UUID) (*user.User, error) {
+	actualUser := &user.User{}
+	if err := service.repository.GetRecordByID(uow, userIdFromUrl, actualUser); err != nil {
+		return nil, errors.NewUnauthorizedError("invalid user making the request")
+	}
+
+	tokenUser := &user.User{}
+	if err := service.repository.GetRecordByID(uow, userIdFromToken, tokenUser); err != nil {
+		return nil, errors.NewUnauthorizedError("invalid user making the request")
+	}
+
+	isAdmin := tokenUser.IsAdmin != nil && *tokenUser.IsAdmin
+	if actualUser.ID != tokenUser.ID && !isAdmin {
+		return nil, errors.NewUnauthorizedError("you are not authorized to view the plan of this user")
+	}
+	return actualUser, nil
+}
@@ -0,0 +1,44 @@
+package tax
+
+import (
+	"url-shortner-be/components/money"
+	"url-shortner-be/model/tax"
+)
+
+// Compute splits a purchase price into taxable value and GST under the setting. The place of supply
+// is the buyer's state, or the seller's when the buyer has not told us theirs.
+func Compute(setting *tax.TaxSetting, price money.Money, sellerState, buyerState string) tax.Breakdown {
+	breakdown := tax.Breakdown{
+		RateBasisPoints: setting.RateBasisPoints,
+		Inclusive:       setting.IsInclusive(),
+		PlaceOfSupply:   buyerState,
+	}
+	if breakdown.PlaceOfSupply == "" {
+		breakdown.PlaceOfSupply = sellerState
+	}
+
+	rate := int64(setting.RateBasisPoints)
+	var gst money.Money
+	if breakdown.Inclusive {
+		breakdown.Taxable = money.FromPaise(divideRounded(price.Paise()*10000, 10000+rate))
+		gst = price - breakdown.Taxable
+	} else {
+		breakdown.Taxable = price
+		gst = money.FromPaise(divideRounded(price.Paise()*rate, 10000))
+	}
+
+	if sellerState == "" || breakdown.PlaceOfSupply == sellerState {
+		breakdown.CGST = money.FromPaise(gst.Paise() / 2)
+		breakdown.SGST = gst - breakdown.CGST
+	} else {
+		breakdown.IGST = gst
+	}
+
+	breakdown.Total = breakdown.Taxable + gst
+	return breakdown
+}
+
+// divideRounded divides rounding half up, amounts are never negative.
+func divideRounded(numerator, denominator int64) int64 {
+	return (numerator + denominator/2) / denominator
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/security"
+	taxService "url-shortner-be/components/tax/service"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/tax"
+
+	"github.com/gorilla/mux"
+)
+
+type TaxController struct {
+	log        log.Logger
+	TaxService *taxService.TaxService
+}
+
+func NewTaxController(taxService *taxService.TaxService, log log.Logger) *TaxController {
+	return &TaxController{
+		log:        log,
+		TaxService: taxService,
+	}
+}
+
+func (taxController *TaxController) RegisterRoutes(router *mux.Router) {
+
+	taxRouter := router.PathPrefix("/taxes").Subrouter()
+	commonRouter := taxRouter.PathPrefix("/").Subrouter()
+	adminguardedRouter := taxRouter.PathPrefix("/").Subrouter()
+
+	commonRouter.HandleFunc("/current", taxController.getCurrentSetting).Methods(http.MethodGet)
+
+	adminguardedRouter.HandleFunc("/", taxController.getAllSettings).Methods(http.MethodGet)
+	adminguardedRouter.HandleFunc("/", taxController.addSetting).Methods(http.MethodPost)
+	adminguardedRouter.HandleFunc("/report", taxController.getMonthlyReport).Methods(http.MethodGet)
+
+	commonRouter.Use(security.MiddlewareCommon)
+	adminguardedRouter.Use(security.MiddlewareAdmin)
+}
+
+func (controller *TaxController) getCurrentSetting(w http.ResponseWriter, r *http.Request) {
+	setting := &tax.TaxSetting{}
+
+	if err := controller.TaxService.GetSetting(setting); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, setting)
+}
+
+func (controller *TaxController) getAllSettings(w http.ResponseWriter, r *http.Request) {
+	settings := []tax.TaxSetting{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	if err := controller.TaxService.GetAllSettings(&settings, &totalCount, parser); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, settings)
+}
+
+func (controller *TaxController) addSetting(w http.ResponseWriter, r *http.Request) {
+	setting := &tax.TaxSetting{}
+
+	if err := web.UnmarshalJSON(r, setting); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.TaxService.AddSetting(setting, userIdFromToken); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, setting)
+}
+
+// getMonthlyReport returns the GST collected in each month of ?year=, the current year by default.
+func (controller *TaxController) getMonthlyReport(w http.ResponseWriter, r *http.Request) {
+	reports := []tax.MonthlyTaxReport{}
+
+	year := time.Now().Year()
+	if yearStr := r.URL.Query().Get("year"); yearStr != "" {
+		var err error
+		if year, err = strconv.Atoi(yearStr); err != nil {
+			web.RespondError(w, errors.NewValidationError("Invalid year"))
+			return
+		}
+	}
+
+	if err := controller.TaxService.GetMonthlyReport(&reports, year); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, reports)
+}
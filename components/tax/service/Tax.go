@@ -0,0 +1,139 @@
+package service
+
+import (
+	"url-shortner-be/components/config"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/money"
+	taxengine "url-shortner-be/components/tax"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/tax"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type TaxService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewTaxService(DB *gorm.DB, repo repository.Repository) *TaxService {
+	return &TaxService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+// Quote computes the GST on a purchase by the buyer under the current tax setting.
+func (service *TaxService) Quote(uow *repository.UnitOfWork, buyer *user.User, price money.Money) (tax.Breakdown, error) {
+	setting := &tax.TaxSetting{}
+	if err := service.currentSetting(uow, setting); err != nil {
+		return tax.Breakdown{}, err
+	}
+
+	buyerState := buyer.State
+	if buyerState == "" && len(buyer.GSTIN) >= 2 {
+		buyerState = buyer.GSTIN[:2]
+	}
+	return taxengine.Compute(setting, price, sellerState(), buyerState), nil
+}
+
+// AddSetting makes a new tax setting the current one.
+func (service *TaxService) AddSetting(setting *tax.TaxSetting, userIdFromToken uuid.UUID) error {
+
+	if err := setting.Validate(); err != nil {
+		return err
+	}
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	setting.ID = uuid.NewV4()
+	setting.CreatedBy = userIdFromToken
+	if setting.Inclusive == nil {
+		inclusive := false
+		setting.Inclusive = &inclusive
+	}
+
+	if err := service.repository.Add(uow, setting); err != nil {
+		return errors.NewDatabaseError("unable to save tax setting")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// GetSetting returns the current tax setting, no tax at all until an admin adds one.
+func (service *TaxService) GetSetting(setting *tax.TaxSetting) error {
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	return service.currentSetting(uow, setting)
+}
+
+func (service *TaxService) GetAllSettings(settings *[]tax.TaxSetting, totalCount *int, parser *web.Parser) error {
+
+	limit, offset := parser.ParseLimitAndOffset()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetAll(uow, settings,
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("created_at desc"),
+	); err != nil {
+		return errors.NewDatabaseError("unable to fetch tax settings")
+	}
+
+	return nil
+}
+
+// GetMonthlyReport sums the GST charged on purchases in each month of the year by place of supply and rate.
+func (service *TaxService) GetMonthlyReport(reports *[]tax.MonthlyTaxReport, year int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetRaw(uow, reports, repository.RawQuery(`
+		SELECT MONTH(created_at) AS month, place_of_supply, tax_rate_basis_points AS rate_basis_points,
+		 COUNT(*) AS purchases, SUM(taxable_amount_paise) AS taxable,
+		 SUM(cgst_paise) AS cgst, SUM(sgst_paise) AS sgst, SUM(igst_paise) AS igst, SUM(amount_paise) AS total
+		FROM transactions
+		WHERE YEAR(created_at) = ?
+		 AND type IN ('URLRENEWAL', 'VISITSRENEWAL', 'PLANPURCHASE')
+		 AND deleted_at IS NULL
+		GROUP BY MONTH(created_at), place_of_supply, tax_rate_basis_points
+		ORDER BY MONTH(created_at), place_of_supply, tax_rate_basis_points
+	`, year)); err != nil {
+		return errors.NewDatabaseError("unable to fetch tax report")
+	}
+
+	return nil
+}
+
+// ---------------- Helpers ----------------
+
+// sellerState is the GST state code purchases are supplied from.
+func sellerState() string {
+	return config.InvoiceSellerState.GetStringValue()
+}
+
+func (service *TaxService) currentSetting(uow *repository.UnitOfWork, setting *tax.TaxSetting) error {
+	err := service.repository.GetRecord(uow, setting, repository.Order("created_at desc"))
+	if err == nil {
+		return nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return errors.NewDatabaseError("unable to fetch tax setting")
+	}
+
+	inclusive := false
+	*setting = tax.TaxSetting{Inclusive: &inclusive}
+	return nil
+}
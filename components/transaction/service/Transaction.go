@@ -1,6 +1,7 @@
 package service
 
 import (
+	"url-shortner-be/components/money"
 	"url-shortner-be/model/transaction"
 	"url-shortner-be/model/user"
 	"url-shortner-be/module/repository"
@@ -23,21 +24,30 @@ func NewTransactionService(db *gorm.DB, repo repository.Repository) *Transaction
 	}
 }
 
-func (service *TransactionService) CreateTransaction(uow *repository.UnitOfWork, userId uuid.UUID, amount float32, transactionType, note string) error {
+func (service *TransactionService) CreateTransaction(uow *repository.UnitOfWork, userId uuid.UUID, amount money.Money, transactionType, note string) error {
+	return service.RecordTransaction(uow, &transaction.Transaction{
+		Amount: amount,
+		Type:   transactionType,
+		Note:   note,
+		UserID: userId,
+	})
+}
+
+// RecordTransaction saves a transaction built by the caller, which keeps its ID, e.g. to invoice it.
+func (service *TransactionService) RecordTransaction(uow *repository.UnitOfWork, transaction *transaction.Transaction) error {
 
 	user := &user.User{}
-	err := service.repository.GetRecord(uow, user, repository.Filter("id = ?", userId))
+	err := service.repository.GetRecord(uow, user, repository.Filter("id = ?", transaction.UserID))
 	if err != nil {
 		return err
 	}
 
-	transaction := &transaction.Transaction{
-		Amount: amount,
-		Type:   transactionType,
-		Note:   note,
-		UserID: user.ID,
+	if transaction.ID == uuid.Nil {
+		transaction.ID = uuid.NewV4()
 	}
-	transaction.CreatedBy = userId
+	transaction.Currency = money.CurrencyINR
+	transaction.UserID = user.ID
+	transaction.CreatedBy = user.ID
 
 	err = service.repository.Add(uow, transaction)
 	if err != nil {
@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"net/http"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/security"
+	transferService "url-shortner-be/components/transfer/service"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/transfer"
+
+	"github.com/gorilla/mux"
+)
+
+type TransferController struct {
+	log             log.Logger
+	TransferService *transferService.TransferService
+}
+
+func NewTransferController(transferService *transferService.TransferService, log log.Logger) *TransferController {
+	return &TransferController{
+		log:             log,
+		TransferService: transferService,
+	}
+}
+
+func (transferController *TransferController) RegisterRoutes(router *mux.Router) {
+
+	transferRouter := router.PathPrefix("/transfers").Subrouter()
+
+	transferRouter.HandleFunc("", transferController.initiateTransfer).Methods(http.MethodPost)
+	transferRouter.HandleFunc("", transferController.getAllTransfers).Methods(http.MethodGet)
+	transferRouter.HandleFunc("/{transferId}/accept", transferController.acceptTransfer).Methods(http.MethodPost)
+	transferRouter.HandleFunc("/{transferId}/reject", transferController.rejectTransfer).Methods(http.MethodPost)
+	transferRouter.HandleFunc("/{transferId}/cancel", transferController.cancelTransfer).Methods(http.MethodPost)
+
+	transferRouter.Use(security.MiddlewareUser)
+}
+
+func (controller *TransferController) initiateTransfer(w http.ResponseWriter, r *http.Request) {
+	newTransfer := transfer.UrlTransfer{}
+
+	err := web.UnmarshalJSON(r, &newTransfer)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := newTransfer.Validate(); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+	newTransfer.FromUserID = userIdFromToken
+
+	if err = controller.TransferService.InitiateTransfer(&newTransfer); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newTransfer)
+}
+
+func (controller *TransferController) getAllTransfers(w http.ResponseWriter, r *http.Request) {
+	transfers := []transfer.UrlTransfer{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.TransferService.GetAllTransfers(&transfers, &totalCount, parser, userIdFromToken); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, transfers)
+}
+
+func (controller *TransferController) acceptTransfer(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	transferID, err := parser.GetUUID("transferId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid transfer ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.TransferService.AcceptTransfer(transferID, userIdFromToken); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Url transfer accepted successfully",
+	})
+}
+
+func (controller *TransferController) rejectTransfer(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	transferID, err := parser.GetUUID("transferId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid transfer ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.TransferService.RejectTransfer(transferID, userIdFromToken); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Url transfer rejected successfully",
+	})
+}
+
+func (controller *TransferController) cancelTransfer(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	transferID, err := parser.GetUUID("transferId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid transfer ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.TransferService.CancelTransfer(transferID, userIdFromToken); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Url transfer cancelled successfully",
+	})
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"fmt"
+	"time"
+	"url-shortner-be/components/errors"
+	subscriptionserv "url-shortner-be/components/subscription/service"
+	transactionserv "url-shortner-be/components/transaction/service"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/credential"
+	"url-shortner-be/model/subscription"
+	"url-shortner-be/model/transfer"
+	"url-shortner-be/model/url"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type TransferService struct {
+	db                  *gorm.DB
+	repository          repository.Repository
+	transactionservice  *transactionserv.TransactionService
+	subscriptionservice *subscriptionserv.SubscriptionService
+}
+
+func NewTransferService(DB *gorm.DB, repo repository.Repository, txService *transactionserv.TransactionService) *TransferService {
+	return &TransferService{
+		db:                  DB,
+		repository:          repo,
+		transactionservice:  txService,
+		subscriptionservice: subscriptionserv.NewSubscriptionService(DB, repo),
+	}
+}
+
+func (service *TransferService) InitiateTransfer(newTransfer *transfer.UrlTransfer) error {
+
+	if err := user.Exists(service.db, newTransfer.FromUserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	sender := user.User{}
+	if err := service.repository.GetRecordByID(uow, newTransfer.FromUserID, &sender); err != nil {
+		return errors.NewDatabaseError("unable to get user record")
+	}
+
+	if !*sender.IsActive {
+		return errors.NewValidationError("Inactive user cannot transfer urls")
+	}
+
+	recipientCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &recipientCredential, repository.Filter("email = ?", newTransfer.ToEmail)); err != nil {
+		return errors.NewValidationError("no user is registered with the given email")
+	}
+
+	recipient := user.User{}
+	if err := service.repository.GetRecordByID(uow, recipientCredential.UserID, &recipient); err != nil {
+		return errors.NewValidationError("no user is registered with the given email")
+	}
+
+	if recipient.ID == sender.ID {
+		return errors.NewValidationError("you cannot transfer urls to yourself")
+	}
+
+	if !*recipient.IsActive || (recipient.IsAdmin != nil && *recipient.IsAdmin) {
+		return errors.NewValidationError("urls can only be transferred to an active user")
+	}
+
+	urlIDs := uniqueIDs(newTransfer.UrlIDs)
+	if len(urlIDs) == 0 {
+		return errors.NewValidationError("At least one url must be selected for transfer")
+	}
+
+	var ownedCount int
+	if err := service.repository.GetCount(uow, &url.Url{}, &ownedCount,
+		repository.Filter("id IN (?) AND user_id = ?", urlIDs, sender.ID)); err != nil {
+		return errors.NewDatabaseError("unable to fetch urls for transfer")
+	}
+	if ownedCount != len(urlIDs) {
+		return errors.NewValidationError("one or more urls do not belong to you")
+	}
+
+	var customDomainCount int
+	if err := service.repository.GetCount(uow, &url.Url{}, &customDomainCount,
+		repository.Filter("id IN (?) AND domain_id IS NOT NULL", urlIDs)); err != nil {
+		return errors.NewDatabaseError("unable to fetch urls for transfer")
+	}
+	if customDomainCount > 0 {
+		return errors.NewValidationError("urls on a custom domain cannot be transferred")
+	}
+
+	var pendingCount int
+	if err := service.repository.GetCount(uow, &transfer.UrlTransferItem{}, &pendingCount,
+		repository.Filter("url_id IN (?) AND transfer_id IN (SELECT id FROM url_transfers WHERE status = ? AND deleted_at IS NULL)",
+			urlIDs, transfer.StatusPending)); err != nil {
+		return errors.NewDatabaseError("unable to check pending transfers")
+	}
+	if pendingCount > 0 {
+		return errors.NewValidationError("one or more urls already have a pending transfer")
+	}
+
+	newTransfer.ToUserID = recipient.ID
+	newTransfer.Status = transfer.StatusPending
+	newTransfer.CreatedBy = sender.ID
+	newTransfer.Items = make([]*transfer.UrlTransferItem, 0, len(urlIDs))
+	for _, urlID := range urlIDs {
+		item := &transfer.UrlTransferItem{UrlID: urlID}
+		item.CreatedBy = sender.ID
+		newTransfer.Items = append(newTransfer.Items, item)
+	}
+
+	if err := service.repository.Add(uow, newTransfer); err != nil {
+		return errors.NewDatabaseError("unable to create url transfer")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *TransferService) AcceptTransfer(transferID, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	pendingTransfer, err := service.getPendingTransfer(uow, transferID)
+	if err != nil {
+		return err
+	}
+
+	if pendingTransfer.ToUserID != userIdFromToken {
+		return errors.NewUnauthorizedError("you are not authorized to accept this transfer")
+	}
+
+	recipient := user.User{}
+	if err := service.repository.GetRecordByID(uow, pendingTransfer.ToUserID, &recipient, repository.ForUpdate()); err != nil {
+		return errors.NewDatabaseError("unable to get user record")
+	}
+
+	if !*recipient.IsActive {
+		return errors.NewValidationError("Inactive user cannot accept url transfers")
+	}
+
+	urlIDs := make([]uuid.UUID, 0, len(pendingTransfer.Items))
+	for _, item := range pendingTransfer.Items {
+		urlIDs = append(urlIDs, item.UrlID)
+	}
+
+	urlsToTransfer := []url.Url{}
+	if err := service.repository.GetAll(uow, &urlsToTransfer,
+		repository.Filter("id IN (?) AND user_id = ?", urlIDs, pendingTransfer.FromUserID)); err != nil {
+		return errors.NewDatabaseError("unable to fetch urls for transfer")
+	}
+	if len(urlsToTransfer) != len(urlIDs) {
+		return errors.NewValidationError("one or more urls are no longer available for transfer")
+	}
+
+	for _, urlToTransfer := range urlsToTransfer {
+		if urlToTransfer.CanonicalUrl == "" {
+			continue
+		}
+		var duplicateCount int
+		if err := service.repository.GetCount(uow, &url.Url{}, &duplicateCount,
+			repository.Filter("canonical_url = ? AND user_id = ?", urlToTransfer.CanonicalUrl, recipient.ID)); err != nil {
+			return errors.NewDatabaseError("unable to check urls for transfer")
+		}
+		if duplicateCount > 0 {
+			return errors.NewValidationError(fmt.Sprintf("you already have a short url for %s", urlToTransfer.LongUrl))
+		}
+	}
+
+	subscription := &subscription.Subscription{}
+	if err := service.subscriptionservice.PlanFor(uow, &recipient, subscription); err != nil {
+		return err
+	}
+
+	if subscription.TransferConsumesUrlCount != nil && *subscription.TransferConsumesUrlCount {
+		if recipient.UrlCount < len(urlsToTransfer) {
+			return errors.NewValidationError("insufficient url count to accept this transfer, purchase more urls")
+		}
+
+		if err := service.repository.UpdateWithMap(uow, &recipient, map[string]interface{}{
+			"url_count":  recipient.UrlCount - len(urlsToTransfer),
+			"updated_by": recipient.ID,
+		}); err != nil {
+			return errors.NewDatabaseError("unable to update user url count")
+		}
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &url.Url{}, map[string]interface{}{
+		"user_id":    recipient.ID,
+		"updated_by": recipient.ID,
+		"updated_at": time.Now(),
+	}, repository.Filter("id IN (?)", urlIDs)); err != nil {
+		return errors.NewDatabaseError("unable to transfer urls")
+	}
+
+	if err := service.updateStatus(uow, pendingTransfer, transfer.StatusAccepted, recipient.ID); err != nil {
+		return err
+	}
+
+	//transaction--------------------------------------------------------------------------------------------------
+	var outNote = fmt.Sprintf("%d url transferred to %s", len(urlsToTransfer), pendingTransfer.ToEmail)
+	if err := service.transactionservice.CreateTransaction(uow, pendingTransfer.FromUserID, 0, "URLTRANSFEROUT", outNote); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
+	}
+
+	var inNote = fmt.Sprintf("%d url received by transfer %s", len(urlsToTransfer), pendingTransfer.ID)
+	if err := service.transactionservice.CreateTransaction(uow, recipient.ID, 0, "URLTRANSFERIN", inNote); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *TransferService) RejectTransfer(transferID, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	pendingTransfer, err := service.getPendingTransfer(uow, transferID)
+	if err != nil {
+		return err
+	}
+
+	if pendingTransfer.ToUserID != userIdFromToken {
+		return errors.NewUnauthorizedError("you are not authorized to reject this transfer")
+	}
+
+	if err := service.updateStatus(uow, pendingTransfer, transfer.StatusRejected, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *TransferService) CancelTransfer(transferID, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	pendingTransfer, err := service.getPendingTransfer(uow, transferID)
+	if err != nil {
+		return err
+	}
+
+	if pendingTransfer.FromUserID != userIdFromToken {
+		return errors.NewUnauthorizedError("you are not authorized to cancel this transfer")
+	}
+
+	if err := service.updateStatus(uow, pendingTransfer, transfer.StatusCancelled, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *TransferService) GetAllTransfers(transfers *[]transfer.UrlTransfer, totalCount *int, parser *web.Parser, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	limit, offset := parser.ParseLimitAndOffset()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	var queryProcessors []repository.QueryProcessor
+
+	switch parser.Form.Get("direction") {
+	case "incoming":
+		queryProcessors = append(queryProcessors, repository.Filter("to_user_id = ?", userIdFromToken))
+	case "outgoing":
+		queryProcessors = append(queryProcessors, repository.Filter("from_user_id = ?", userIdFromToken))
+	default:
+		queryProcessors = append(queryProcessors, repository.Filter("(to_user_id = ? OR from_user_id = ?)", userIdFromToken, userIdFromToken))
+	}
+
+	if status := parser.Form.Get("status"); status != "" {
+		queryProcessors = append(queryProcessors, repository.Filter("status = ?", status))
+	}
+
+	queryProcessors = append(queryProcessors,
+		repository.PreloadAssociations([]string{"Items"}),
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("created_at desc"))
+
+	if err := service.repository.GetAll(uow, transfers, queryProcessors...); err != nil {
+		return errors.NewDatabaseError("unable to fetch url transfers")
+	}
+
+	return nil
+}
+
+// ---------------- Helpers ----------------
+
+func (service *TransferService) getPendingTransfer(uow *repository.UnitOfWork, transferID uuid.UUID) (*transfer.UrlTransfer, error) {
+	pendingTransfer := &transfer.UrlTransfer{}
+	if err := service.repository.GetRecordByID(uow, transferID, pendingTransfer,
+		repository.PreloadAssociations([]string{"Items"})); err != nil {
+		return nil, errors.NewValidationError("no url transfer found with given id")
+	}
+
+	if pendingTransfer.Status != transfer.StatusPending {
+		return nil, errors.NewValidationError("url transfer is already " + pendingTransfer.Status)
+	}
+
+	return pendingTransfer, nil
+}
+
+func (service *TransferService) updateStatus(uow *repository.UnitOfWork, pendingTransfer *transfer.UrlTransfer, status string, updatedBy uuid.UUID) error {
+	if err := service.repository.UpdateWithMap(uow, &transfer.UrlTransfer{}, map[string]interface{}{
+		"status":     status,
+		"updated_by": updatedBy,
+		"updated_at": time.Now(),
+	}, repository.Filter("id = ? AND status = ?", pendingTransfer.ID, transfer.StatusPending)); err != nil {
+		return errors.NewDatabaseError("unable to update url transfer")
+	}
+	pendingTransfer.Status = status
+	return nil
+}
+
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if id == uuid.Nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/security"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/page"
+)
+
+// publicPageTemplate renders a link-in-bio page, every link goes through the short url redirect
+// so that visits are counted and analysed like any other click.
+var publicPageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Page.Title}}</title>
+<meta property="og:title" content="{{.Page.Title}}">
+<meta property="og:description" content="{{.Page.Bio}}">
+{{if .Page.AvatarUrl}}<meta property="og:image" content="{{.Page.AvatarUrl}}">{{end}}
+<style>
+body{font-family:sans-serif;max-width:480px;margin:40px auto;padding:0 16px;text-align:center}
+img{width:96px;height:96px;border-radius:50%;object-fit:cover}
+a{display:block;margin:12px 0;padding:14px;border:1px solid #333;border-radius:8px;color:#333;text-decoration:none}
+</style>
+</head>
+<body>
+{{if .Page.AvatarUrl}}<img src="{{.Page.AvatarUrl}}" alt="{{.Page.Title}}">{{end}}
+<h1>{{.Page.Title}}</h1>
+{{if .Page.Bio}}<p>{{.Page.Bio}}</p>{{end}}
+{{range .Links}}<a href="/redirect/{{.ShortUrl}}" rel="noopener">{{.Title}}</a>
+{{end}}
+</body>
+</html>
+`))
+
+func (controller *UrlController) createPage(w http.ResponseWriter, r *http.Request) {
+	newPage := &page.Page{}
+
+	err := web.UnmarshalJSON(r, newPage)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := newPage.Validate(); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	newPage.UserID, err = security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.CreatePage(newPage); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newPage)
+}
+
+func (controller *UrlController) getAllPages(w http.ResponseWriter, r *http.Request) {
+	pages := []page.Page{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.GetAllPages(&pages, &totalCount, parser, userIdFromToken); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, pages)
+}
+
+func (controller *UrlController) getPage(w http.ResponseWriter, r *http.Request) {
+	targetPage := &page.Page{}
+	parser := web.NewParser(r)
+
+	pageID, err := parser.GetUUID("pageId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid page ID format"))
+		return
+	}
+	targetPage.ID = pageID
+
+	targetPage.UserID, err = security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.GetPage(targetPage); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, targetPage)
+}
+
+func (controller *UrlController) updatePage(w http.ResponseWriter, r *http.Request) {
+	targetPage := &page.Page{}
+	parser := web.NewParser(r)
+
+	err := web.UnmarshalJSON(r, targetPage)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := targetPage.Validate(); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	pageID, err := parser.GetUUID("pageId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid page ID format"))
+		return
+	}
+	targetPage.ID = pageID
+
+	targetPage.UserID, err = security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.UpdatePage(targetPage); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Page updated successfully",
+	})
+}
+
+func (controller *UrlController) deletePage(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	pageID, err := parser.GetUUID("pageId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid page ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.DeletePage(pageID, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Page deleted successfully",
+	})
+}
+
+func (controller *UrlController) viewPage(w http.ResponseWriter, r *http.Request) {
+	publicPage := &page.Page{}
+	links := []page.PublicLink{}
+	parser := web.NewParser(r)
+
+	slug, err := parser.GetString("slug")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid page slug"))
+		return
+	}
+	publicPage.Slug = slug
+
+	if err = controller.UrlService.GetPublicPage(publicPage, &links); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	var rendered bytes.Buffer
+	if err := publicPageTemplate.Execute(&rendered, map[string]interface{}{
+		"Page":  publicPage,
+		"Links": links,
+	}); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, errors.NewHTTPError("unable to render page", http.StatusInternalServerError))
+		return
+	}
+
+	web.RespondHTML(w, http.StatusOK, rendered.String())
+}
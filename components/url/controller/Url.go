@@ -1,12 +1,23 @@
 package controller
 
 import (
+	"fmt"
+	"html"
+	"mime"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"url-shortner-be/components/bot"
+	"url-shortner-be/components/config"
 	"url-shortner-be/components/errors"
+	"url-shortner-be/components/idempotency"
 	"url-shortner-be/components/log"
 	"url-shortner-be/components/security"
 	urlService "url-shortner-be/components/url/service"
 	"url-shortner-be/components/web"
+	"url-shortner-be/model/click"
+	"url-shortner-be/model/domain"
+	"url-shortner-be/model/stats"
 	"url-shortner-be/model/url"
 	"url-shortner-be/model/user"
 
@@ -14,14 +25,16 @@ import (
 )
 
 type UrlController struct {
-	log        log.Logger
-	UrlService *urlService.UrlService
+	log         log.Logger
+	UrlService  *urlService.UrlService
+	idempotency *idempotency.Guard
 }
 
-func NewUrlController(urlService *urlService.UrlService, log log.Logger) *UrlController {
+func NewUrlController(urlService *urlService.UrlService, idempotencyGuard *idempotency.Guard, log log.Logger) *UrlController {
 	return &UrlController{
-		log:        log,
-		UrlService: urlService,
+		log:         log,
+		UrlService:  urlService,
+		idempotency: idempotencyGuard,
 	}
 }
 
@@ -36,17 +49,35 @@ func (urlController *UrlController) RegisterRoutes(router *mux.Router) {
 	urlRouter := router.PathPrefix("/url").Subrouter()
 	commonRouter := router.PathPrefix("/url").Subrouter()
 	redirectRouter := router.PathPrefix("/redirect").Subrouter()
+	publicPageRouter := router.PathPrefix("/p").Subrouter()
 
 	urlRouter.HandleFunc("/register", urlController.registerUrl).Methods(http.MethodPost)
+	urlRouter.HandleFunc("/upload", urlController.uploadFile).Methods(http.MethodPost)
+	urlRouter.HandleFunc("/import", urlController.importUrls).Methods(http.MethodPost)
+	urlRouter.HandleFunc("/pages", urlController.createPage).Methods(http.MethodPost)
+	urlRouter.HandleFunc("/pages", urlController.getAllPages).Methods(http.MethodGet)
+	urlRouter.HandleFunc("/pages/{pageId}", urlController.getPage).Methods(http.MethodGet)
+	urlRouter.HandleFunc("/pages/{pageId}", urlController.updatePage).Methods(http.MethodPut)
+	urlRouter.HandleFunc("/pages/{pageId}", urlController.deletePage).Methods(http.MethodDelete)
 	urlRouter.HandleFunc("/short-url", urlController.getUrlByShortUrl).Methods(http.MethodPost)
 	urlRouter.HandleFunc("/{urlId}", urlController.getUrlById).Methods(http.MethodGet)
 	urlRouter.HandleFunc("/{urlId}", urlController.updateUrlById).Methods(http.MethodPut)
 	urlRouter.HandleFunc("/{urlId}", urlController.deleteUrlById).Methods(http.MethodDelete)
-	urlRouter.HandleFunc("/{urlId}/renew-visits", urlController.renewUrlVisits).Methods(http.MethodPost)
+	urlRouter.Handle("/{urlId}/renew-visits", urlController.idempotency.Wrap(urlController.renewUrlVisits)).Methods(http.MethodPost)
+	urlRouter.HandleFunc("/{urlId}/auto-renew", urlController.updateAutoRenew).Methods(http.MethodPut)
+	urlRouter.HandleFunc("/{urlId}/low-visit-threshold", urlController.updateLowVisitThreshold).Methods(http.MethodPut)
+	urlRouter.HandleFunc("/{urlId}/rate-limit", urlController.updateRateLimit).Methods(http.MethodPut)
+	urlRouter.HandleFunc("/{urlId}/bot-policy", urlController.updateBotPolicy).Methods(http.MethodPut)
+	urlRouter.HandleFunc("/{urlId}/billing-mode", urlController.updateBillingMode).Methods(http.MethodPut)
+	urlRouter.HandleFunc("/{urlId}/analytics", urlController.getUrlAnalytics).Methods(http.MethodGet)
+	urlRouter.HandleFunc("/{urlId}/geo-rules", urlController.getGeoRules).Methods(http.MethodGet)
+	urlRouter.HandleFunc("/{urlId}/geo-rules", urlController.updateGeoRules).Methods(http.MethodPut)
 
 	commonRouter.HandleFunc("/user/{userId}", urlController.getAllUrlsByUserId).Methods(http.MethodGet)
 
-	redirectRouter.HandleFunc("/{short-url}", urlController.redirectUrl).Methods(http.MethodGet)
+	publicPageRouter.HandleFunc("/{slug}", urlController.viewPage).Methods(http.MethodGet)
+
+	redirectRouter.HandleFunc("/{short-url}", urlController.redirectUrl).Methods(http.MethodGet, http.MethodHead, http.MethodPost)
 
 	commonRouter.Use(security.MiddlewareCommon)
 	urlRouter.Use(security.MiddlewareUser)
@@ -64,12 +95,17 @@ func (controller *UrlController) registerUrl(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	err = newUrl.Validate(newUrl.LongUrl, newUrl.ShortUrl)
+	err = newUrl.Validate()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if err := newUrl.ValidateLinkType(); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
 	// userIdFromURL, err := parser.GetUUID("userId")
 	// if err != nil {
 	// 	web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
@@ -94,6 +130,89 @@ func (controller *UrlController) registerUrl(w http.ResponseWriter, r *http.Requ
 	web.RespondJSON(w, http.StatusCreated, newUrl)
 }
 
+func (controller *UrlController) uploadFile(w http.ResponseWriter, r *http.Request) {
+	UrlOwner := &user.User{}
+
+	maxUploadMB := config.MaxUploadMB.GetInt64Value()
+	if maxUploadMB <= 0 {
+		maxUploadMB = 100
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadMB<<20)
+
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		web.RespondError(w, errors.NewHTTPError(fmt.Sprintf("upload must be a multipart form of at most %d MB", maxUploadMB), http.StatusBadRequest))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("file is required", http.StatusBadRequest))
+		return
+	}
+	defer file.Close()
+
+	newUrl := &url.Url{ShortUrl: r.FormValue("shortUrl")}
+	if len(newUrl.ShortUrl) != 5 {
+		web.RespondError(w, errors.NewHTTPError("short url must have 5 characters", http.StatusBadRequest))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+	UrlOwner.ID = userIdFromToken
+	newUrl.CreatedBy = userIdFromToken
+
+	if err = controller.UrlService.CreateFileUrl(userIdFromToken, UrlOwner, newUrl, file, header.Filename); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+	web.RespondJSON(w, http.StatusCreated, newUrl)
+}
+
+// importUrls accepts a CSV or JSON export of another shortener as the multipart "file", the format is taken
+// from the "format" field or the file extension.
+func (controller *UrlController) importUrls(w http.ResponseWriter, r *http.Request) {
+	report := url.ImportReport{Rows: []*url.ImportRow{}}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		web.RespondError(w, errors.NewHTTPError("import must be a multipart form of at most 10 MB", http.StatusBadRequest))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("file is required", http.StatusBadRequest))
+		return
+	}
+	defer file.Close()
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.ImportUrls(userIdFromToken, format, file, &report); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, report)
+}
+
 // ----------------------------------------------------------------------------
 
 func (controller *UrlController) redirectUrl(w http.ResponseWriter, r *http.Request) {
@@ -108,17 +227,98 @@ func (controller *UrlController) redirectUrl(w http.ResponseWriter, r *http.Requ
 	}
 	urlToRedirect.ShortUrl = shortUrlFromPrams
 
-	if err = controller.UrlService.RedirectToUrl(&urlToRedirect); err != nil {
+	isBot := bot.IsBot(r)
+	visit := click.Click{
+		IsBot:     &isBot,
+		UserAgent: truncate(r.UserAgent(), 255),
+		Referer:   truncate(r.Referer(), 255),
+		Host:      truncate(domain.NormalizeHost(r.Host), 255),
+		ClientIP:  web.ClientIP(r),
+		IsRange:   r.Header.Get("Range") != "",
+	}
+
+	// Secret links are only consumed by the POST sent from the confirmation page.
+	confirmed := r.Method == http.MethodPost
+
+	if err = controller.UrlService.RedirectToUrl(&urlToRedirect, &visit, confirmed); err != nil {
+		if httpError, ok := err.(*errors.HTTPError); ok && httpError.HTTPStatus == http.StatusPreconditionRequired {
+			w.Header().Set("Cache-Control", "no-store")
+			web.RespondHTML(w, http.StatusOK, confirmationPage())
+			return
+		}
 		controller.log.Print(err.Error())
 		web.RespondError(w, err)
 		return
 	}
 
+	if urlToRedirect.IsSecret() {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+	}
+
+	if isBot && urlToRedirect.BotPolicy == url.BotPolicyMetadata {
+		web.RespondHTML(w, http.StatusOK, metadataPage(&urlToRedirect))
+		return
+	}
+
+	if urlToRedirect.IsFile() {
+		controller.serveFile(w, r, &urlToRedirect)
+		return
+	}
+
 	// http.Redirect(w, r, urlToRedirect.LongUrl, http.StatusSeeOther)
 
 	web.RespondJSON(w, http.StatusOK, urlToRedirect)
 }
 
+// metadataPage describes the destination for link unfurlers without sending them to it.
+func metadataPage(targetUrl *url.Url) string {
+	destination := html.EscapeString(targetUrl.LongUrl)
+	return "<!DOCTYPE html><html><head><meta charset=\"utf-8\">" +
+		"<title>" + destination + "</title>" +
+		"<meta property=\"og:url\" content=\"" + destination + "\">" +
+		"<meta property=\"og:title\" content=\"" + destination + "\">" +
+		"<link rel=\"canonical\" href=\"" + destination + "\">" +
+		"</head><body></body></html>"
+}
+
+// serveFile streams the file of a FILE short url, answering range requests so large files can be resumed.
+func (controller *UrlController) serveFile(w http.ResponseWriter, r *http.Request, fileUrl *url.Url) {
+	file, err := controller.UrlService.OpenFile(fileUrl)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+	defer file.Close()
+
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "true" {
+		disposition = "attachment"
+	}
+
+	w.Header().Set("Content-Type", fileUrl.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fileUrl.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, fileUrl.FileName, fileUrl.CreatedAt, file)
+}
+
+// confirmationPage asks a person to open a secret link, the form posts back to the same short url.
+func confirmationPage() string {
+	return "<!DOCTYPE html><html><head><meta charset=\"utf-8\">" +
+		"<meta name=\"robots\" content=\"noindex, nofollow\">" +
+		"<title>Open link</title></head><body>" +
+		"<p>This link can only be opened a limited number of times.</p>" +
+		"<form method=\"post\"><button type=\"submit\">Open link</button></form>" +
+		"</body></html>"
+}
+
+func truncate(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+	return value
+}
+
 // ---------------------------------------------------------------------------
 
 func (controller *UrlController) getAllUrlsByUserId(w http.ResponseWriter, r *http.Request) {
@@ -235,7 +435,7 @@ func (controller *UrlController) updateUrlById(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if err := targetUrl.Validate(targetUrl.LongUrl, targetUrl.ShortUrl); err != nil {
+	if err := targetUrl.Validate(); err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
 		return
@@ -335,3 +535,290 @@ func (controller *UrlController) renewUrlVisits(w http.ResponseWriter, r *http.R
 		"message": "Url Visits Renewed Successfully",
 	})
 }
+
+func (controller *UrlController) updateAutoRenew(w http.ResponseWriter, r *http.Request) {
+	urlSettings := &url.Url{}
+	parser := web.NewParser(r)
+
+	err := web.UnmarshalJSON(r, &urlSettings)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := urlSettings.ValidateAutoRenew(); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	urlIdFromURL, err := parser.GetUUID("urlId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid URL ID format"))
+		return
+	}
+	urlSettings.ID = urlIdFromURL
+
+	urlSettings.UserID, err = security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.UpdateAutoRenew(urlSettings); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Url auto renew updated successfully",
+	})
+}
+
+func (controller *UrlController) updateLowVisitThreshold(w http.ResponseWriter, r *http.Request) {
+	urlSettings := &url.Url{}
+	parser := web.NewParser(r)
+
+	err := web.UnmarshalJSON(r, &urlSettings)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := urlSettings.ValidateLowVisitThreshold(); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	urlIdFromURL, err := parser.GetUUID("urlId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid URL ID format"))
+		return
+	}
+	urlSettings.ID = urlIdFromURL
+
+	urlSettings.UserID, err = security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.UpdateLowVisitThreshold(urlSettings); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Url low visit threshold updated successfully",
+	})
+}
+
+func (controller *UrlController) updateRateLimit(w http.ResponseWriter, r *http.Request) {
+	urlSettings := &url.Url{}
+	parser := web.NewParser(r)
+
+	err := web.UnmarshalJSON(r, &urlSettings)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := urlSettings.ValidateRateLimit(); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	urlIdFromURL, err := parser.GetUUID("urlId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid URL ID format"))
+		return
+	}
+	urlSettings.ID = urlIdFromURL
+
+	urlSettings.UserID, err = security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.UpdateRateLimit(urlSettings); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Url rate limit updated successfully",
+	})
+}
+
+func (controller *UrlController) updateBotPolicy(w http.ResponseWriter, r *http.Request) {
+	urlSettings := &url.Url{}
+	parser := web.NewParser(r)
+
+	err := web.UnmarshalJSON(r, &urlSettings)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := urlSettings.ValidateBotPolicy(); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	urlIdFromURL, err := parser.GetUUID("urlId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid URL ID format"))
+		return
+	}
+	urlSettings.ID = urlIdFromURL
+
+	urlSettings.UserID, err = security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.UpdateBotPolicy(urlSettings); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Url bot policy updated successfully",
+	})
+}
+
+func (controller *UrlController) getUrlAnalytics(w http.ResponseWriter, r *http.Request) {
+	analytics := stats.UrlAnalytics{}
+	parser := web.NewParser(r)
+
+	urlIdFromURL, err := parser.GetUUID("urlId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid URL ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.GetUrlAnalytics(&analytics, parser, urlIdFromURL, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, analytics)
+}
+
+func (controller *UrlController) updateBillingMode(w http.ResponseWriter, r *http.Request) {
+	urlSettings := &url.Url{}
+	parser := web.NewParser(r)
+
+	err := web.UnmarshalJSON(r, &urlSettings)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := urlSettings.ValidateBillingMode(); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	urlIdFromURL, err := parser.GetUUID("urlId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid URL ID format"))
+		return
+	}
+	urlSettings.ID = urlIdFromURL
+
+	urlSettings.UserID, err = security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.UpdateBillingMode(urlSettings); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Url billing mode updated successfully",
+	})
+}
+
+func (controller *UrlController) getGeoRules(w http.ResponseWriter, r *http.Request) {
+	rules := []url.GeoRule{}
+	parser := web.NewParser(r)
+
+	urlIdFromURL, err := parser.GetUUID("urlId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid URL ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.GetGeoRules(&rules, urlIdFromURL, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, rules)
+}
+
+func (controller *UrlController) updateGeoRules(w http.ResponseWriter, r *http.Request) {
+	rules := []url.GeoRule{}
+	parser := web.NewParser(r)
+
+	err := web.UnmarshalJSON(r, &rules)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := url.ValidateGeoRules(rules); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	urlIdFromURL, err := parser.GetUUID("urlId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid URL ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.UrlService.UpdateGeoRules(rules, urlIdFromURL, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, rules)
+}
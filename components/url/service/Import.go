@@ -0,0 +1,158 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/importer"
+	"url-shortner-be/model/subscription"
+	"url-shortner-be/model/url"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var shortCodePattern = regexp.MustCompile(`^[A-Za-z0-9]{5}$`)
+
+// ImportUrls creates short urls from the export of another shortener and fills the report row by row.
+// Original codes are kept when they are free and fit our format, other links get a new code.
+// Destinations are not fetched during an import, broken ones are flagged by the destination check.
+func (service *UrlService) ImportUrls(userId uuid.UUID, format string, content io.Reader, report *url.ImportReport) error {
+
+	if err := user.Exists(service.db, userId); err != nil {
+		return err
+	}
+
+	links, err := importer.Parse(format, content)
+	if err != nil {
+		return errors.NewValidationError(err.Error())
+	}
+	if len(links) == 0 {
+		return errors.NewValidationError("export does not contain any links")
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	// The owner stays locked for the whole import, so urls bought meanwhile are not lost when the count is written back.
+	urlOwner := &user.User{}
+	if err := service.repository.GetRecordByID(uow, userId, urlOwner, repository.ForUpdate()); err != nil {
+		return errors.NewDatabaseError("unable to get user record")
+	}
+
+	if !*urlOwner.IsActive {
+		return errors.NewValidationError("Inactive user cannot import urls")
+	}
+
+	subscription := &subscription.Subscription{}
+	if err := service.subscriptionservice.PlanFor(uow, urlOwner, subscription); err != nil {
+		return err
+	}
+
+	state := &importState{
+		remainingUrls: urlOwner.UrlCount,
+		usedCodes:     make(map[string]bool),
+		usedUrls:      make(map[string]bool),
+	}
+
+	for _, link := range links {
+		row := &url.ImportRow{
+			Row:          link.Row,
+			LongUrl:      link.LongUrl,
+			OriginalCode: link.ShortCode,
+			VisitCount:   link.Clicks,
+		}
+		if err := service.importUrl(uow, urlOwner, subscription, link, row, state); err != nil {
+			return err
+		}
+		report.Add(row)
+	}
+
+	if err := service.repository.UpdateWithMap(uow, urlOwner, map[string]interface{}{
+		"url_count":  state.remainingUrls,
+		"updated_by": userId,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update user url count")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+type importState struct {
+	remainingUrls int
+	usedCodes     map[string]bool
+	usedUrls      map[string]bool
+}
+
+// importUrl records why a link is skipped or failed on its row, only database errors abort the import.
+func (service *UrlService) importUrl(uow *repository.UnitOfWork, urlOwner *user.User, subscription *subscription.Subscription,
+	link importer.Link, row *url.ImportRow, state *importState) error {
+
+	row.Status = url.ImportStatusFailed
+
+	if link.LongUrl == "" {
+		row.Reason = "long url is missing"
+		return nil
+	}
+
+	newUrl := &url.Url{LongUrl: link.LongUrl, LinkType: url.LinkTypeStandard}
+	if err := newUrl.SetCanonicalUrl(); err != nil {
+		row.Reason = err.Error()
+		return nil
+	}
+
+	if err := service.chainResolver.CheckHost(newUrl.LongUrl); err != nil {
+		row.Reason = err.Error()
+		return nil
+	}
+
+	if state.usedUrls[newUrl.CanonicalUrl] || service.doesLongUrlExistsForCurrentUser(newUrl.CanonicalUrl, urlOwner.ID, uuid.Nil) != nil {
+		row.Status = url.ImportStatusSkipped
+		row.Reason = "long url is already shortened"
+		return nil
+	}
+
+	if state.remainingUrls <= 0 {
+		row.Reason = "maximum url creation limit is reached, purchase more for importing urls"
+		return nil
+	}
+
+	row.Status = url.ImportStatusImported
+	newUrl.ShortUrl = link.ShortCode
+
+	switch {
+	case link.ShortCode == "":
+		row.Status, row.Reason = url.ImportStatusRemapped, "export has no short code"
+	case !shortCodePattern.MatchString(link.ShortCode):
+		row.Status, row.Reason = url.ImportStatusRemapped, "short code must have 5 letters or digits"
+	case state.usedCodes[link.ShortCode] || service.doesShortUrlExists(link.ShortCode, nil) != nil:
+		row.Status, row.Reason = url.ImportStatusRemapped, "short code is already taken"
+	}
+
+	if row.Status == url.ImportStatusRemapped {
+		for {
+			newUrl.ShortUrl = url.GenerateShortUrl()
+			if !state.usedCodes[newUrl.ShortUrl] && service.doesShortUrlExists(newUrl.ShortUrl, nil) == nil {
+				break
+			}
+		}
+	}
+
+	newUrl.UserID = urlOwner.ID
+	newUrl.RemainingVisits = subscription.FreeVisits
+	newUrl.VisitCount = link.Clicks
+	newUrl.CreatedBy = urlOwner.ID
+
+	if err := service.repository.Add(uow, newUrl); err != nil {
+		return errors.NewDatabaseError(fmt.Sprintf("unable to import url of row %d", row.Row))
+	}
+
+	state.remainingUrls--
+	state.usedCodes[newUrl.ShortUrl] = true
+	state.usedUrls[newUrl.CanonicalUrl] = true
+	row.ShortUrl = newUrl.ShortUrl
+	return nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"sort"
+	"time"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/page"
+	"url-shortner-be/model/url"
+	"url-shortner-be/model/user"
+	"url-shortner-be/module/repository"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func (service *UrlService) CreatePage(newPage *page.Page) error {
+
+	if err := user.Exists(service.db, newPage.UserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	pageOwner := user.User{}
+	if err := service.repository.GetRecordByID(uow, newPage.UserID, &pageOwner); err != nil {
+		return errors.NewDatabaseError("unable to get user record")
+	}
+
+	if !*pageOwner.IsActive {
+		return errors.NewValidationError("Inactive user cannot create pages")
+	}
+
+	if err := service.doesSlugExist(newPage.Slug, uuid.Nil); err != nil {
+		return err
+	}
+
+	if err := service.validatePageLinks(uow, newPage); err != nil {
+		return err
+	}
+
+	newPage.CreatedBy = newPage.UserID
+	for _, link := range newPage.Links {
+		link.CreatedBy = newPage.UserID
+	}
+
+	if err := service.repository.Add(uow, newPage); err != nil {
+		return errors.NewDatabaseError("unable to create page")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *UrlService) GetAllPages(pages *[]page.Page, totalCount *int, parser *web.Parser, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	limit, offset := parser.ParseLimitAndOffset()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetAll(uow, pages, repository.Filter("user_id = ?", userIdFromToken),
+		repository.PreloadAssociations([]string{"Links"}),
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("created_at desc")); err != nil {
+		return errors.NewDatabaseError("unable to fetch pages")
+	}
+
+	for i := range *pages {
+		sortPageLinks(&(*pages)[i])
+	}
+	return nil
+}
+
+func (service *UrlService) GetPage(targetPage *page.Page) error {
+
+	if err := user.Exists(service.db, targetPage.UserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetRecord(uow, targetPage, repository.Filter("id = ? AND user_id = ?", targetPage.ID, targetPage.UserID),
+		repository.PreloadAssociations([]string{"Links"})); err != nil {
+		return errors.NewValidationError("no page found for this user with given page id")
+	}
+
+	sortPageLinks(targetPage)
+	return nil
+}
+
+// UpdatePage updates the page details and replaces its links with the given ones.
+func (service *UrlService) UpdatePage(targetPage *page.Page) error {
+
+	if err := user.Exists(service.db, targetPage.UserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingPage := &page.Page{}
+	if err := service.repository.GetRecord(uow, existingPage, repository.Filter("id = ? AND user_id = ?", targetPage.ID, targetPage.UserID)); err != nil {
+		return errors.NewValidationError("no page found for this user with given page id")
+	}
+
+	if err := service.doesSlugExist(targetPage.Slug, existingPage.ID); err != nil {
+		return err
+	}
+
+	if err := service.validatePageLinks(uow, targetPage); err != nil {
+		return err
+	}
+
+	if err := service.repository.UpdateWithMap(uow, existingPage, map[string]interface{}{
+		"slug":         targetPage.Slug,
+		"title":        targetPage.Title,
+		"bio":          targetPage.Bio,
+		"avatar_url":   targetPage.AvatarUrl,
+		"is_published": targetPage.IsPublished == nil || *targetPage.IsPublished,
+		"updated_by":   targetPage.UserID,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update page")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &page.PageLink{}, map[string]interface{}{
+		"deleted_at": time.Now(),
+		"deleted_by": targetPage.UserID,
+	}, repository.Filter("page_id = ?", existingPage.ID)); err != nil {
+		return errors.NewDatabaseError("unable to remove existing page links")
+	}
+
+	for _, link := range targetPage.Links {
+		link.ID = uuid.Nil
+		link.PageID = existingPage.ID
+		link.CreatedBy = targetPage.UserID
+		if err := service.repository.Add(uow, link); err != nil {
+			return errors.NewDatabaseError("unable to save page links")
+		}
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *UrlService) DeletePage(pageID, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingPage := &page.Page{}
+	if err := service.repository.GetRecord(uow, existingPage, repository.Filter("id = ? AND user_id = ?", pageID, userIdFromToken)); err != nil {
+		return errors.NewValidationError("no page found for this user with given page id")
+	}
+
+	// The slug is released so that it can be claimed again.
+	if err := service.repository.UpdateWithMap(uow, existingPage, map[string]interface{}{
+		"slug":       existingPage.ID.String(),
+		"deleted_at": time.Now(),
+		"deleted_by": userIdFromToken,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to delete page")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// GetPublicPage loads a published page by slug together with the links that can still be visited.
+func (service *UrlService) GetPublicPage(publicPage *page.Page, links *[]page.PublicLink) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetRecord(uow, publicPage, repository.Filter("slug = ? AND is_published = ?", publicPage.Slug, true)); err != nil {
+		return errors.NewNotFoundError("page not found")
+	}
+
+	linkQuery := `
+		SELECT page_links.title, urls.short_url
+		FROM page_links
+		JOIN urls ON urls.id = page_links.url_id
+		WHERE page_links.page_id = ? AND page_links.deleted_at IS NULL
+		  AND urls.deleted_at IS NULL AND urls.consumed_at IS NULL AND urls.link_type <> ?
+		ORDER BY page_links.position
+	`
+	if err := service.repository.GetRaw(uow, links, repository.RawQuery(linkQuery, publicPage.ID, url.LinkTypeSecret)); err != nil {
+		return errors.NewDatabaseError("unable to fetch page links")
+	}
+
+	return nil
+}
+
+// ---------------- Helpers ----------------
+
+// validatePageLinks checks that every link points to one of the user's urls and numbers the links in order.
+func (service *UrlService) validatePageLinks(uow *repository.UnitOfWork, targetPage *page.Page) error {
+	if len(targetPage.Links) == 0 {
+		return nil
+	}
+
+	urlIDs := make([]uuid.UUID, 0, len(targetPage.Links))
+	for _, link := range targetPage.Links {
+		urlIDs = append(urlIDs, link.UrlID)
+	}
+
+	var ownedCount int
+	if err := service.repository.GetCount(uow, &url.Url{}, &ownedCount,
+		repository.Filter("id IN (?) AND user_id = ? AND link_type <> ?", urlIDs, targetPage.UserID, url.LinkTypeSecret)); err != nil {
+		return errors.NewDatabaseError("unable to fetch urls of page")
+	}
+	if ownedCount != len(urlIDs) {
+		return errors.NewValidationError("one or more links do not refer to your urls, secret links cannot be listed")
+	}
+
+	sortPageLinks(targetPage)
+	for i, link := range targetPage.Links {
+		link.Position = i
+	}
+	return nil
+}
+
+func (service *UrlService) doesSlugExist(slug string, pageID uuid.UUID) error {
+	var count int
+	if err := service.db.Model(&page.Page{}).Where("slug = ? AND id <> ?", slug, pageID).Count(&count).Error; err != nil {
+		return errors.NewDatabaseError("unable to check page slug")
+	}
+	if count > 0 {
+		return errors.NewValidationError("This slug is already taken, try another one")
+	}
+	return nil
+}
+
+func sortPageLinks(targetPage *page.Page) {
+	sort.SliceStable(targetPage.Links, func(i, j int) bool {
+		return targetPage.Links[i].Position < targetPage.Links[j].Position
+	})
+}
@@ -1,14 +1,45 @@
 package service
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	urlNet "net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"url-shortner-be/components/config"
+	couponserv "url-shortner-be/components/coupon/service"
 	"url-shortner-be/components/errors"
+	"url-shortner-be/components/geoip"
+	"url-shortner-be/components/hll"
+	invoiceserv "url-shortner-be/components/invoice/service"
+	ledgerserv "url-shortner-be/components/ledger/service"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/mail"
+	notificationserv "url-shortner-be/components/notification/service"
+	"url-shortner-be/components/ratelimit"
+	"url-shortner-be/components/redirectchain"
+	referralserv "url-shortner-be/components/referral/service"
+	"url-shortner-be/components/storage"
+	subscriptionserv "url-shortner-be/components/subscription/service"
+	taxserv "url-shortner-be/components/tax/service"
 	transactionserv "url-shortner-be/components/transaction/service"
+	"url-shortner-be/components/visitor"
 	"url-shortner-be/components/web"
+	"url-shortner-be/model/click"
+	"url-shortner-be/model/coupon"
+	"url-shortner-be/model/domain"
+	"url-shortner-be/model/invoice"
+	"url-shortner-be/model/ledger"
+	"url-shortner-be/model/notification"
+	"url-shortner-be/model/stats"
 	"url-shortner-be/model/subscription"
+	"url-shortner-be/model/transaction"
 	"url-shortner-be/model/url"
 	"url-shortner-be/model/user"
 	"url-shortner-be/module/repository"
@@ -17,28 +48,67 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
+// A range request for a file within this long of the visitor's counted visit resumes that download.
+const rangeContinuationWindow = 10 * time.Minute
+
 type UrlService struct {
-	db                 *gorm.DB
-	repository         repository.Repository
-	transactionservice *transactionserv.TransactionService
+	db                  *gorm.DB
+	repository          repository.Repository
+	transactionservice  *transactionserv.TransactionService
+	ledgerservice       *ledgerserv.LedgerService
+	invoiceservice      *invoiceserv.InvoiceService
+	taxservice          *taxserv.TaxService
+	couponservice       *couponserv.CouponService
+	referralservice     *referralserv.ReferralService
+	subscriptionservice *subscriptionserv.SubscriptionService
+	notificationservice *notificationserv.NotificationService
+	limiter             *ratelimit.Limiter
+	geoResolver         geoip.Resolver
+	storage             storage.Storage
+	chainResolver       *redirectchain.Resolver
+	autoRenewals        sync.Map
 }
 
 func NewUrlService(DB *gorm.DB, repo repository.Repository) *UrlService {
 
 	var transactionService = transactionserv.NewTransactionService(DB, repo)
-	return &UrlService{
-		db:                 DB,
-		repository:         repo,
-		transactionservice: transactionService,
+	var notificationService = notificationserv.NewNotificationService(DB, repo, mail.NewSender())
+	service := &UrlService{
+		db:                  DB,
+		repository:          repo,
+		transactionservice:  transactionService,
+		ledgerservice:       ledgerserv.NewLedgerService(DB, repo),
+		invoiceservice:      invoiceserv.NewInvoiceService(DB, repo),
+		taxservice:          taxserv.NewTaxService(DB, repo),
+		couponservice:       couponserv.NewCouponService(DB, repo),
+		referralservice:     referralserv.NewReferralService(DB, repo),
+		subscriptionservice: subscriptionserv.NewSubscriptionService(DB, repo),
+		notificationservice: notificationService,
+		limiter:             ratelimit.NewLimiter(ratelimit.NewMemoryStore()),
+		geoResolver:         geoip.Default(),
+		storage:             storage.NewStorage(),
+	}
+
+	maxHops := int(config.RedirectChainMaxHops.GetInt64Value())
+	if maxHops <= 0 {
+		maxHops = 10
 	}
+	service.chainResolver = redirectchain.NewResolver(maxHops, service.isOwnHost)
+	return service
 }
 
 func (service *UrlService) CreateUrl(userId uuid.UUID, urlOwner *user.User, newUrl *url.Url) error {
 
-	if err := service.doesUserExist(userId); err != nil {
+	if err := user.Exists(service.db, userId); err != nil {
 		return err
 	}
 
+	if !newUrl.IsFile() {
+		if err := service.resolveRedirectChain(newUrl); err != nil {
+			return err
+		}
+	}
+
 	uow := repository.NewUnitOfWork(service.db, false)
 	defer uow.RollBack()
 
@@ -51,11 +121,28 @@ func (service *UrlService) CreateUrl(userId uuid.UUID, urlOwner *user.User, newU
 		return errors.NewValidationError("Inactive user cannot create short url")
 	}
 
-	if err := service.doesLongUrlExistsForCurrentUser(newUrl.LongUrl, userId); err != nil {
-		return err
+	// The same invite can be shared as many secret links, each consumed on its own,
+	// and file links have no long url of their own.
+	if !newUrl.IsFile() {
+		if err := newUrl.SetCanonicalUrl(); err != nil {
+			return err
+		}
+	}
+	if !newUrl.IsSecret() && !newUrl.IsFile() {
+		if err := service.doesLongUrlExistsForCurrentUser(newUrl.CanonicalUrl, userId, uuid.Nil); err != nil {
+			return err
+		}
 	}
 
-	if err := service.doesShortUrlExists(newUrl.ShortUrl); err != nil {
+	if newUrl.DomainID != nil {
+		customDomain := domain.Domain{}
+		if err := service.repository.GetRecord(uow, &customDomain, repository.Filter("id = ? AND user_id = ? AND is_verified = ?",
+			*newUrl.DomainID, userId, true)); err != nil {
+			return errors.NewValidationError("domain must be one of your verified domains")
+		}
+	}
+
+	if err := service.doesShortUrlExists(newUrl.ShortUrl, newUrl.DomainID); err != nil {
 		return err
 	}
 
@@ -70,8 +157,8 @@ func (service *UrlService) CreateUrl(userId uuid.UUID, urlOwner *user.User, newU
 	}
 
 	subscription := &subscription.Subscription{}
-	if err := service.repository.GetRecord(uow, &subscription, repository.Order("created_at desc")); err != nil {
-		return errors.NewDatabaseError("unable to fetch subscription details")
+	if err := service.subscriptionservice.PlanFor(uow, foundUser, subscription); err != nil {
+		return err
 	}
 
 	newUrl.UserID = foundUser.ID
@@ -82,6 +169,9 @@ func (service *UrlService) CreateUrl(userId uuid.UUID, urlOwner *user.User, newU
 	}
 	newUrl.UserID = foundUser.ID
 	newUrl.RemainingVisits = subscription.FreeVisits
+	if newUrl.IsSecret() {
+		newUrl.RemainingVisits = newUrl.MaxOpens
+	}
 
 	// for {
 
@@ -111,6 +201,80 @@ func (service *UrlService) CreateUrl(userId uuid.UUID, urlOwner *user.User, newU
 	return nil
 }
 
+// CreateFileUrl stores an uploaded file within the size and type limits of the subscription
+// and creates a FILE short url serving it.
+func (service *UrlService) CreateFileUrl(userId uuid.UUID, urlOwner *user.User, newUrl *url.Url, content io.Reader, fileName string) error {
+
+	if err := user.Exists(service.db, userId); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	fileOwner := &user.User{}
+	if err := service.repository.GetRecordByID(uow, userId, fileOwner); err != nil {
+		return errors.NewDatabaseError("unable to get user record")
+	}
+
+	subscription := &subscription.Subscription{}
+	if err := service.subscriptionservice.PlanFor(uow, fileOwner, subscription); err != nil {
+		return err
+	}
+
+	// The content type is sniffed from the file itself, the client supplied header is not trusted.
+	head := make([]byte, 512)
+	headLength, err := io.ReadFull(content, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return errors.NewHTTPError("unable to read uploaded file", http.StatusBadRequest)
+	}
+	if headLength == 0 {
+		return errors.NewValidationError("uploaded file is empty")
+	}
+	head = head[:headLength]
+
+	contentType := strings.TrimSpace(strings.Split(http.DetectContentType(head), ";")[0])
+	if !subscription.AllowsFileType(contentType) {
+		return errors.NewValidationError(fmt.Sprintf("files of type %s are not allowed", contentType))
+	}
+
+	maxSize := int64(subscription.MaxFileSizeMB) << 20
+	key := uuid.NewV4().String() + strings.ToLower(filepath.Ext(fileName))
+
+	written, err := service.storage.Save(key, io.LimitReader(io.MultiReader(bytes.NewReader(head), content), maxSize+1))
+	if err != nil {
+		log.GetLogger().Error("unable to store uploaded file: ", err.Error())
+		return errors.NewDatabaseError("unable to store uploaded file")
+	}
+	if written > maxSize {
+		service.storage.Delete(key)
+		return errors.NewValidationError(fmt.Sprintf("file exceeds the %d MB limit of your subscription", subscription.MaxFileSizeMB))
+	}
+
+	newUrl.LinkType = url.LinkTypeFile
+	newUrl.FileKey = key
+	newUrl.FileName = filepath.Base(fileName)
+	newUrl.FileSize = written
+	newUrl.ContentType = contentType
+	newUrl.LongUrl = newUrl.FileName
+
+	if err := service.CreateUrl(userId, urlOwner, newUrl); err != nil {
+		service.storage.Delete(key)
+		return err
+	}
+	return nil
+}
+
+// OpenFile opens the stored file of a FILE short url.
+func (service *UrlService) OpenFile(fileUrl *url.Url) (storage.File, error) {
+	file, err := service.storage.Open(fileUrl.FileKey)
+	if err != nil {
+		log.GetLogger().Error("unable to open stored file: ", err.Error())
+		return nil, errors.NewNotFoundError("file of this short url is no longer available")
+	}
+	return file, nil
+}
+
 // func (service *UrlService) RedirectToUrl(shortUrl string) (string, error) {
 
 // 	uow := repository.NewUnitOfWork(service.db, false)
@@ -140,48 +304,772 @@ func (service *UrlService) CreateUrl(userId uuid.UUID, urlOwner *user.User, newU
 // 	return url.LongUrl, nil
 // }
 
-func (service *UrlService) RedirectToUrl(urlToRedirect *url.Url) error {
+// RedirectToUrl resolves the short url for a visit. Secret links are only opened when confirmed is true,
+// so that link previews and prefetchers cannot consume them.
+func (service *UrlService) RedirectToUrl(urlToRedirect *url.Url, visit *click.Click, confirmed bool) error {
 	uow := repository.NewUnitOfWork(service.db, true)
 	defer uow.RollBack()
 
-	if err := service.repository.GetRecord(uow, urlToRedirect, repository.Filter("short_url = ?", urlToRedirect.ShortUrl)); err != nil {
+	if err := service.findByShortUrl(uow, urlToRedirect, visit.Host); err != nil {
 		return errors.NewDatabaseError("no short url matches the given short url")
 	}
 
+	if err := service.checkRateLimits(urlToRedirect, visit.ClientIP); err != nil {
+		return err
+	}
+
+	visit.UrlID = urlToRedirect.ID
+	service.locate(visit)
+
+	if urlToRedirect.FinalUrl != "" {
+		urlToRedirect.LongUrl = urlToRedirect.FinalUrl
+	}
+
+	// Geo and bot rules apply to every kind of link, secret links included.
+	destination := urlToRedirect.LongUrl
+	if err := service.applyGeoRules(uow, urlToRedirect, visit); err != nil {
+		return err
+	}
+	geoDestination := urlToRedirect.LongUrl
+
+	var previousVisitorHash string
+	visit.VisitorHash, previousVisitorHash = visitor.Fingerprints(visit.ClientIP, visit.UserAgent, time.Now())
+
+	isBot := visit.IsBot != nil && *visit.IsBot
+	if isBot && urlToRedirect.BotPolicy == url.BotPolicyBlock {
+		return service.recordBotVisit(uow, urlToRedirect, visit)
+	}
+
+	if urlToRedirect.IsSecret() {
+		if err := service.openSecretLink(urlToRedirect, visit, confirmed); err != nil {
+			return err
+		}
+		if geoDestination != destination {
+			urlToRedirect.LongUrl = geoDestination
+		}
+		return nil
+	}
+
+	if isBot {
+		return service.recordBotVisit(uow, urlToRedirect, visit)
+	}
+
 	if urlToRedirect.RemainingVisits == 0 {
 		uow.RollBack()
+		service.triggerAutoRenew(urlToRedirect)
 		return errors.NewHTTPError("no. of visits reacheed it's limit, please renew the visits", http.StatusForbidden)
 	}
 
+	// Range requests of a file the visitor started downloading moments ago continue that visit.
+	if visit.IsRange && urlToRedirect.IsFile() &&
+		service.isRangeContinuation(uow, urlToRedirect, visit.VisitorHash, previousVisitorHash) {
+		return nil
+	}
+
+	isRepeat := urlToRedirect.BillingMode == url.BillingModeUnique &&
+		service.isRepeatVisit(uow, urlToRedirect, visit.VisitorHash, previousVisitorHash)
+	visit.IsRepeat = &isRepeat
+
+	if isRepeat {
+		return service.recordRepeatVisit(uow, urlToRedirect, visit)
+	}
+
 	urlToRedirect.RemainingVisits--
 	urlToRedirect.VisitCount++
 
-	if err := service.repository.UpdateWithMap(uow, urlToRedirect, map[string]interface{}{
-		"remainingVisits": urlToRedirect.RemainingVisits,
-		"visitCount":      urlToRedirect.VisitCount}); err != nil {
+	// Counters are updated relative to the stored value so a concurrent renewal is never overwritten.
+	if err := service.repository.UpdateWithMap(uow, &url.Url{}, map[string]interface{}{
+		"remaining_visits": gorm.Expr("remaining_visits - ?", 1),
+		"visit_count":      gorm.Expr("visit_count + ?", 1)},
+		repository.Filter("id = ? AND remaining_visits > 0", urlToRedirect.ID)); err != nil {
 		uow.RollBack()
 		return errors.NewDatabaseError("unable to update visits count")
 	}
 
+	service.recordClick(uow, visit)
+	service.recordVisitorSketch(visit)
+
+	if urlToRedirect.RemainingVisits == 0 {
+		service.triggerAutoRenew(urlToRedirect)
+	}
+	service.notifyRemainingVisits(urlToRedirect)
+
 	// uow.Commit()
 	return nil
 }
 
+// GetGeoRules returns the country and region rules configured for the user's url.
+func (service *UrlService) GetGeoRules(rules *[]url.GeoRule, urlID, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	existingUrl := &url.Url{}
+	if err := service.repository.GetRecord(uow, existingUrl, repository.Filter("id = ? AND user_id = ?", urlID, userIdFromToken)); err != nil {
+		return errors.NewValidationError("no url found for this user with given url id")
+	}
+
+	if err := service.repository.GetAll(uow, rules, repository.Filter("url_id = ?", existingUrl.ID),
+		repository.Order("country_code, region")); err != nil {
+		return errors.NewDatabaseError("unable to fetch geo rules")
+	}
+
+	return nil
+}
+
+// UpdateGeoRules replaces all geo rules of the user's url with the given rules.
+func (service *UrlService) UpdateGeoRules(rules []url.GeoRule, urlID, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingUrl := &url.Url{}
+	if err := service.repository.GetRecord(uow, existingUrl, repository.Filter("id = ? AND user_id = ?", urlID, userIdFromToken)); err != nil {
+		return errors.NewValidationError("no url found for this user with given url id")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &url.GeoRule{}, map[string]interface{}{
+		"deleted_at": time.Now(),
+		"deleted_by": userIdFromToken,
+	}, repository.Filter("url_id = ?", existingUrl.ID)); err != nil {
+		return errors.NewDatabaseError("unable to remove existing geo rules")
+	}
+
+	for i := range rules {
+		rules[i].ID = uuid.Nil
+		rules[i].UrlID = existingUrl.ID
+		rules[i].CreatedBy = userIdFromToken
+		if err := service.repository.Add(uow, &rules[i]); err != nil {
+			return errors.NewDatabaseError("unable to save geo rules")
+		}
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *UrlService) UpdateBotPolicy(urlSettings *url.Url) error {
+
+	if err := user.Exists(service.db, urlSettings.UserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingUrl := &url.Url{}
+	if err := service.repository.GetRecord(uow, existingUrl, repository.Filter("id = ? AND user_id = ?", urlSettings.ID, urlSettings.UserID)); err != nil {
+		return errors.NewValidationError("no url found for this user with given url id")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, existingUrl, map[string]interface{}{
+		"bot_policy": urlSettings.BotPolicy,
+		"updated_by": urlSettings.UserID,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update bot policy")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *UrlService) GetUrlAnalytics(analytics *stats.UrlAnalytics, parser *web.Parser, urlID, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	existingUrl := &url.Url{}
+	if err := service.repository.GetRecord(uow, existingUrl, repository.Filter("id = ? AND user_id = ?", urlID, userIdFromToken)); err != nil {
+		return errors.NewValidationError("no url found for this user with given url id")
+	}
+
+	days := 30
+	if value, err := strconv.Atoi(parser.Form.Get("days")); err == nil && value > 0 && value <= 366 {
+		days = value
+	}
+
+	analytics.UrlID = existingUrl.ID.String()
+	analytics.HumanClicks = existingUrl.VisitCount
+	analytics.BotClicks = existingUrl.BotVisitCount
+	analytics.Daily = []stats.DailyClick{}
+
+	dailyQuery := `
+		SELECT DATE_FORMAT(created_at, '%Y-%m-%d') AS day,
+		       SUM(CASE WHEN is_bot = 0 THEN 1 ELSE 0 END) AS human_clicks,
+		       SUM(CASE WHEN is_bot = 1 THEN 1 ELSE 0 END) AS bot_clicks
+		FROM clicks
+		WHERE url_id = ? AND created_at >= ? AND deleted_at IS NULL
+		GROUP BY DATE_FORMAT(created_at, '%Y-%m-%d')
+		ORDER BY day
+	`
+	if err := service.repository.GetRaw(uow, &analytics.Daily,
+		repository.RawQuery(dailyQuery, existingUrl.ID, time.Now().AddDate(0, 0, -days))); err != nil {
+		return errors.NewDatabaseError("unable to fetch url analytics")
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+
+	countryQuery := `
+		SELECT country_code, COUNT(*) AS clicks
+		FROM clicks
+		WHERE url_id = ? AND created_at >= ? AND is_bot = 0 AND country_code <> '' AND deleted_at IS NULL
+		GROUP BY country_code
+		ORDER BY clicks DESC
+	`
+	analytics.Countries = []stats.GeoClick{}
+	if err := service.repository.GetRaw(uow, &analytics.Countries, repository.RawQuery(countryQuery, existingUrl.ID, since)); err != nil {
+		return errors.NewDatabaseError("unable to fetch url analytics by country")
+	}
+
+	cityQuery := `
+		SELECT country_code, city, COUNT(*) AS clicks
+		FROM clicks
+		WHERE url_id = ? AND created_at >= ? AND is_bot = 0 AND city <> '' AND deleted_at IS NULL
+		GROUP BY country_code, city
+		ORDER BY clicks DESC
+		LIMIT 50
+	`
+	analytics.Cities = []stats.GeoClick{}
+	if err := service.repository.GetRaw(uow, &analytics.Cities, repository.RawQuery(cityQuery, existingUrl.ID, since)); err != nil {
+		return errors.NewDatabaseError("unable to fetch url analytics by city")
+	}
+
+	sketches := []click.VisitorSketch{}
+	if err := service.repository.GetAll(uow, &sketches, repository.Filter("url_id = ? AND day >= ?",
+		existingUrl.ID, time.Now().AddDate(0, 0, -days).Format("2006-01-02"))); err != nil {
+		return errors.NewDatabaseError("unable to fetch unique visitors")
+	}
+
+	uniqueVisitors := hll.New()
+	for _, sketch := range sketches {
+		uniqueVisitors.Merge(hll.FromBytes(sketch.Registers))
+	}
+	analytics.UniqueVisitors = uniqueVisitors.Estimate()
+
+	return nil
+}
+
+func (service *UrlService) UpdateBillingMode(urlSettings *url.Url) error {
+
+	if err := user.Exists(service.db, urlSettings.UserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingUrl := &url.Url{}
+	if err := service.repository.GetRecord(uow, existingUrl, repository.Filter("id = ? AND user_id = ?", urlSettings.ID, urlSettings.UserID)); err != nil {
+		return errors.NewValidationError("no url found for this user with given url id")
+	}
+
+	updates := map[string]interface{}{
+		"billing_mode": urlSettings.BillingMode,
+		"updated_by":   urlSettings.UserID,
+	}
+	if urlSettings.BillingMode == url.BillingModeUnique {
+		updates["unique_window_hours"] = urlSettings.UniqueWindowHours
+	}
+
+	if err := service.repository.UpdateWithMap(uow, existingUrl, updates); err != nil {
+		return errors.NewDatabaseError("unable to update billing mode")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *UrlService) UpdateRateLimit(urlSettings *url.Url) error {
+
+	if err := user.Exists(service.db, urlSettings.UserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingUrl := &url.Url{}
+	if err := service.repository.GetRecord(uow, existingUrl, repository.Filter("id = ? AND user_id = ?", urlSettings.ID, urlSettings.UserID)); err != nil {
+		return errors.NewValidationError("no url found for this user with given url id")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, existingUrl, map[string]interface{}{
+		"rate_limit_per_minute": urlSettings.RateLimitPerMinute,
+		"rate_limit_burst":      urlSettings.RateLimitBurst,
+		"updated_by":            urlSettings.UserID,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update rate limit")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *UrlService) UpdateLowVisitThreshold(urlSettings *url.Url) error {
+
+	if err := user.Exists(service.db, urlSettings.UserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingUrl := &url.Url{}
+	if err := service.repository.GetRecord(uow, existingUrl, repository.Filter("id = ? AND user_id = ?", urlSettings.ID, urlSettings.UserID)); err != nil {
+		return errors.NewValidationError("no url found for this user with given url id")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, existingUrl, map[string]interface{}{
+		"low_visit_threshold": urlSettings.LowVisitThreshold,
+		"updated_by":          urlSettings.UserID,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update low visit threshold")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// CheckDestinations probes every long url and notifies owners when a destination starts failing.
+func (service *UrlService) CheckDestinations() {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	allUrls := []url.Url{}
+	if err := service.repository.GetAll(uow, &allUrls, repository.Filter("consumed_at IS NULL AND link_type <> ?", url.LinkTypeFile)); err != nil {
+		log.GetLogger().Error("unable to fetch urls for destination check: ", err.Error())
+		return
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	for i := range allUrls {
+		checkedUrl := &allUrls[i]
+		isBroken := !isDestinationReachable(client, checkedUrl.LongUrl)
+		wasBroken := checkedUrl.IsBroken != nil && *checkedUrl.IsBroken
+
+		if err := service.repository.UpdateWithMap(uow, &url.Url{}, map[string]interface{}{
+			"is_broken":       isBroken,
+			"last_checked_at": time.Now(),
+		}, repository.Filter("id = ?", checkedUrl.ID)); err != nil {
+			log.GetLogger().Error("unable to update destination status for url ", checkedUrl.ID, ": ", err.Error())
+			continue
+		}
+
+		if isBroken && !wasBroken {
+			service.notify(checkedUrl, notification.TypeDestinationBroken, "Short url destination is broken",
+				fmt.Sprintf("The destination %s of your short url %s is not reachable.", checkedUrl.LongUrl, checkedUrl.ShortUrl))
+		}
+	}
+}
+
+func (service *UrlService) UpdateAutoRenew(urlSettings *url.Url) error {
+
+	if err := user.Exists(service.db, urlSettings.UserID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingUrl := &url.Url{}
+	if err := service.repository.GetRecord(uow, existingUrl, repository.Filter("id = ? AND user_id = ?", urlSettings.ID, urlSettings.UserID)); err != nil {
+		return errors.NewValidationError("no url found for this user with given url id")
+	}
+
+	if existingUrl.IsSecret() {
+		return errors.NewValidationError("secret links cannot be auto renewed")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, existingUrl, map[string]interface{}{
+		"auto_renew":                         urlSettings.AutoRenew,
+		"auto_renew_visits":                  urlSettings.AutoRenewVisits,
+		"auto_renew_max_monthly_spend_paise": urlSettings.AutoRenewMaxMonthlySpend,
+		"updated_by":                         urlSettings.UserID,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update auto renew settings")
+	}
+
+	uow.Commit()
+
+	existingUrl.AutoRenew = urlSettings.AutoRenew
+	if existingUrl.RemainingVisits == 0 {
+		service.triggerAutoRenew(existingUrl)
+	}
+	return nil
+}
+
+// AutoRenewUrlVisits tops up an exhausted url from its owner's wallet using the url's auto renew settings.
+func (service *UrlService) AutoRenewUrlVisits(urlID uuid.UUID) error {
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingUrl := &url.Url{}
+	if err := service.repository.GetRecordByID(uow, urlID, existingUrl); err != nil {
+		return errors.NewDatabaseError("unable to find url to auto renew")
+	}
+
+	if existingUrl.AutoRenew == nil || !*existingUrl.AutoRenew || existingUrl.RemainingVisits > 0 {
+		return nil
+	}
+
+	urlOwner := &user.User{}
+	if err := service.repository.GetRecordByID(uow, existingUrl.UserID, urlOwner, repository.ForUpdate()); err != nil {
+		return errors.NewDatabaseError("unable to find url owner")
+	}
+
+	if !*urlOwner.IsActive {
+		return errors.NewValidationError("Inactive user cannot auto renew url visits")
+	}
+
+	subscription := &subscription.Subscription{}
+	if err := service.subscriptionservice.PlanFor(uow, urlOwner, subscription); err != nil {
+		return err
+	}
+
+	breakdown, err := service.taxservice.Quote(uow, urlOwner, subscription.ExtraVisitPrice.Mul(existingUrl.AutoRenewVisits))
+	if err != nil {
+		return err
+	}
+	totalPriceToRenew := breakdown.Total
+
+	period := time.Now().Format("2006-01")
+	spent := existingUrl.AutoRenewSpent
+	if existingUrl.AutoRenewPeriod != period {
+		spent = 0
+	}
+
+	if spent+totalPriceToRenew > existingUrl.AutoRenewMaxMonthlySpend {
+		return errors.NewValidationError("auto renew maximum monthly spend reached for this url")
+	}
+
+	if urlOwner.Wallet < totalPriceToRenew {
+		return errors.NewValidationError("insufficient balance in wallet to auto renew url visits")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &url.Url{}, map[string]interface{}{
+		"remaining_visits":       gorm.Expr("remaining_visits + ?", existingUrl.AutoRenewVisits),
+		"auto_renew_spent_paise": spent + totalPriceToRenew,
+		"auto_renew_period":      period,
+	}, repository.Filter("id = ?", existingUrl.ID)); err != nil {
+		return errors.NewDatabaseError("unable to auto renew url visits")
+	}
+
+	//transaction--------------------------------------------------------------------------------------------------
+	var transactionType = ledger.EntryTypeVisitsRenewal
+	var note = fmt.Sprintf("%d visits auto renewed for %s per visit price", existingUrl.AutoRenewVisits, subscription.ExtraVisitPrice)
+
+	if err := service.ledgerservice.PostWalletPurchase(uow, urlOwner.ID, breakdown.Taxable, breakdown.Tax(), transactionType, note); err != nil {
+		return err
+	}
+
+	purchase := transaction.NewPurchase(urlOwner.ID, transactionType, note, breakdown)
+	if err := service.transactionservice.RecordTransaction(uow, purchase); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
+	}
+
+	if err := service.referralservice.ConfirmReferral(uow, purchase); err != nil {
+		return err
+	}
+
+	if purchase.Amount > 0 {
+		if _, err := service.invoiceservice.IssueInvoice(uow, purchase, []*invoice.InvoiceLine{
+			invoice.NewLine("Visits auto renewed for "+existingUrl.ShortUrl, existingUrl.AutoRenewVisits, subscription.ExtraVisitPrice),
+		}); err != nil {
+			return err
+		}
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// triggerAutoRenew charges the wallet in the background so the redirect never waits on it.
+func (service *UrlService) triggerAutoRenew(exhaustedUrl *url.Url) {
+	if exhaustedUrl.AutoRenew == nil || !*exhaustedUrl.AutoRenew {
+		return
+	}
+
+	if _, inProgress := service.autoRenewals.LoadOrStore(exhaustedUrl.ID, true); inProgress {
+		return
+	}
+
+	go func(urlID uuid.UUID) {
+		defer service.autoRenewals.Delete(urlID)
+		if err := service.AutoRenewUrlVisits(urlID); err != nil {
+			log.GetLogger().Error("auto renew failed for url ", urlID, ": ", err.Error())
+			service.notify(exhaustedUrl, notification.TypeAutoRenewFailed, "Auto renew failed",
+				fmt.Sprintf("Visits of your short url %s could not be auto renewed: %s", exhaustedUrl.ShortUrl, err.Error()))
+		}
+	}(exhaustedUrl.ID)
+}
+
+// recordBotVisit applies the url's bot policy, bot visits are never taken from the remaining visits.
+func (service *UrlService) recordBotVisit(uow *repository.UnitOfWork, visitedUrl *url.Url, visit *click.Click) error {
+	service.recordClick(uow, visit)
+
+	if visitedUrl.BotPolicy == url.BotPolicyBlock {
+		return errors.NewHTTPError("automated clients are not allowed to open this short url", http.StatusForbidden)
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &url.Url{}, map[string]interface{}{
+		"bot_visit_count": gorm.Expr("bot_visit_count + ?", 1),
+	}, repository.Filter("id = ?", visitedUrl.ID)); err != nil {
+		return errors.NewDatabaseError("unable to update bot visits count")
+	}
+	visitedUrl.BotVisitCount++
+
+	return nil
+}
+
+// isRepeatVisit reports whether the visitor already had a billed visit to the url within its unique window.
+func (service *UrlService) isRepeatVisit(uow *repository.UnitOfWork, visitedUrl *url.Url, visitorHashes ...string) bool {
+	windowHours := visitedUrl.UniqueWindowHours
+	if windowHours <= 0 {
+		windowHours = 24
+	}
+
+	var billedVisits int
+	if err := service.repository.GetCount(uow, &click.Click{}, &billedVisits,
+		repository.Filter("url_id = ? AND visitor_hash IN (?) AND is_bot = ? AND is_repeat = ? AND created_at >= ?",
+			visitedUrl.ID, visitorHashes, false, false, time.Now().Add(-time.Duration(windowHours)*time.Hour))); err != nil {
+		log.GetLogger().Error("unable to check repeat visit for url ", visitedUrl.ID, ": ", err.Error())
+		return false
+	}
+	return billedVisits > 0
+}
+
+// isRangeContinuation reports whether the visitor had a counted visit to the file within the last few minutes,
+// in which case a range request resumes that download instead of being a new visit.
+func (service *UrlService) isRangeContinuation(uow *repository.UnitOfWork, fileUrl *url.Url, visitorHashes ...string) bool {
+	var recentVisits int
+	if err := service.repository.GetCount(uow, &click.Click{}, &recentVisits,
+		repository.Filter("url_id = ? AND visitor_hash IN (?) AND is_bot = ? AND created_at >= ?",
+			fileUrl.ID, visitorHashes, false, time.Now().Add(-rangeContinuationWindow))); err != nil {
+		log.GetLogger().Error("unable to check range continuation for url ", fileUrl.ID, ": ", err.Error())
+		return false
+	}
+	return recentVisits > 0
+}
+
+// recordRepeatVisit counts the hit without taking it from the remaining visits.
+func (service *UrlService) recordRepeatVisit(uow *repository.UnitOfWork, visitedUrl *url.Url, visit *click.Click) error {
+	if err := service.repository.UpdateWithMap(uow, &url.Url{}, map[string]interface{}{
+		"visit_count": gorm.Expr("visit_count + ?", 1),
+	}, repository.Filter("id = ?", visitedUrl.ID)); err != nil {
+		return errors.NewDatabaseError("unable to update visits count")
+	}
+	visitedUrl.VisitCount++
+
+	service.recordClick(uow, visit)
+	service.recordVisitorSketch(visit)
+	return nil
+}
+
+// recordVisitorSketch adds the visitor to the url's HyperLogLog sketch of the day. Visitor hashes rotate with
+// the salt, so a returning visitor is only recognised within one salt period, see visitor.Fingerprints.
+func (service *UrlService) recordVisitorSketch(visit *click.Click) {
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	day := time.Now().Format("2006-01-02")
+
+	sketch := &click.VisitorSketch{}
+	err := service.repository.GetRecord(uow, sketch, repository.Filter("url_id = ? AND day = ?", visit.UrlID, day), repository.ForUpdate())
+	isNew := err != nil
+	if isNew {
+		sketch = &click.VisitorSketch{UrlID: visit.UrlID, Day: day}
+	}
+
+	registers := hll.FromBytes(sketch.Registers)
+	registers.Add(visitor.Hash64(visit.VisitorHash))
+
+	if isNew {
+		sketch.Registers = registers.Bytes()
+		err = service.repository.Add(uow, sketch)
+	} else {
+		err = service.repository.UpdateWithMap(uow, sketch, map[string]interface{}{"registers": registers.Bytes()})
+	}
+	if err != nil {
+		log.GetLogger().Error("unable to record visitor sketch for url ", visit.UrlID, ": ", err.Error())
+		return
+	}
+
+	uow.Commit()
+}
+
+// locate enriches the click with the client's country, region and city when a GeoIP database is loaded.
+func (service *UrlService) locate(visit *click.Click) {
+	if service.geoResolver == nil {
+		return
+	}
+
+	ip := net.ParseIP(visit.ClientIP)
+	if ip == nil {
+		return
+	}
+
+	location, err := service.geoResolver.Lookup(ip)
+	if err != nil {
+		log.GetLogger().Error("unable to locate client ip: ", err.Error())
+		return
+	}
+	if location == nil {
+		return
+	}
+
+	visit.CountryCode = location.CountryCode
+	visit.Region = location.Region
+	visit.City = location.City
+}
+
+// openSecretLink consumes one open of a secret link. Once the last open is used the link is tombstoned:
+// it stays reserved, but its destination is wiped and every later visit gets 410 Gone.
+func (service *UrlService) openSecretLink(urlToRedirect *url.Url, visit *click.Click, confirmed bool) error {
+	if urlToRedirect.ConsumedAt != nil || urlToRedirect.RemainingVisits <= 0 {
+		return errors.NewHTTPError("this link has already been used", http.StatusGone)
+	}
+
+	if !confirmed || (visit.IsBot != nil && *visit.IsBot) {
+		return errors.NewHTTPError("confirmation is required to open this link", http.StatusPreconditionRequired)
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	secretUrl := &url.Url{}
+	if err := service.repository.GetRecord(uow, secretUrl, repository.Filter("id = ? AND consumed_at IS NULL", urlToRedirect.ID),
+		repository.ForUpdate()); err != nil || secretUrl.RemainingVisits <= 0 {
+		return errors.NewHTTPError("this link has already been used", http.StatusGone)
+	}
+
+	destination := secretUrl.LongUrl
+	updates := map[string]interface{}{
+		"remaining_visits": secretUrl.RemainingVisits - 1,
+		"visit_count":      secretUrl.VisitCount + 1,
+	}
+	if secretUrl.RemainingVisits == 1 {
+		updates["consumed_at"] = time.Now()
+		updates["long_url"] = ""
+	}
+
+	if err := service.repository.UpdateWithMap(uow, secretUrl, updates); err != nil {
+		return errors.NewDatabaseError("unable to update visits count")
+	}
+
+	service.recordClick(uow, visit)
+	uow.Commit()
+
+	*urlToRedirect = *secretUrl
+	urlToRedirect.LongUrl = destination
+	return nil
+}
+
+// applyGeoRules points the url at the destination configured for the visitor's location,
+// or rejects the visit when the visitor's country is blocked for this url.
+func (service *UrlService) applyGeoRules(uow *repository.UnitOfWork, visitedUrl *url.Url, visit *click.Click) error {
+	if visit.CountryCode == "" {
+		return nil
+	}
+
+	rules := []url.GeoRule{}
+	if err := service.repository.GetAll(uow, &rules, repository.Filter("url_id = ? AND country_code = ?", visitedUrl.ID, visit.CountryCode)); err != nil {
+		return errors.NewDatabaseError("unable to fetch geo rules")
+	}
+
+	rule := url.MatchGeoRule(rules, visit.CountryCode, visit.Region)
+	if rule == nil {
+		return nil
+	}
+
+	if rule.IsBlocked != nil && *rule.IsBlocked {
+		return errors.NewHTTPError("this link is not available in your country", http.StatusUnavailableForLegalReasons)
+	}
+
+	visitedUrl.LongUrl = rule.DestinationUrl
+	return nil
+}
+
+func (service *UrlService) recordClick(uow *repository.UnitOfWork, visit *click.Click) {
+	if err := service.repository.Add(uow, visit); err != nil {
+		log.GetLogger().Error("unable to record click for url ", visit.UrlID, ": ", err.Error())
+	}
+}
+
+// checkRateLimits rejects the visit before it is counted when the client or the link is over its limit.
+func (service *UrlService) checkRateLimits(visitedUrl *url.Url, clientIP string) error {
+	// The client's bucket is shared by every link, so spreading requests over links does not raise its limit.
+	if !service.limiter.Allow("ip:"+clientIP,
+		int(config.RedirectIPRatePerMinute.GetInt64Value()), int(config.RedirectIPBurst.GetInt64Value())) {
+		return errors.NewHTTPError("too many requests, please try again later", http.StatusTooManyRequests)
+	}
+
+	ratePerMinute, burst := visitedUrl.RateLimitPerMinute, visitedUrl.RateLimitBurst
+	if ratePerMinute == 0 {
+		ratePerMinute = int(config.RedirectLinkRatePerMinute.GetInt64Value())
+		burst = int(config.RedirectLinkBurst.GetInt64Value())
+	}
+
+	if !service.limiter.Allow("url:"+visitedUrl.ID.String(), ratePerMinute, burst) {
+		return errors.NewHTTPError("short url is receiving too many requests, please try again later", http.StatusTooManyRequests)
+	}
+	return nil
+}
+
+func (service *UrlService) notifyRemainingVisits(visitedUrl *url.Url) {
+	threshold := visitedUrl.LowVisitThreshold
+	if threshold == 0 {
+		threshold = int(config.LowVisitThreshold.GetInt64Value())
+	}
+
+	autoRenew := visitedUrl.AutoRenew != nil && *visitedUrl.AutoRenew
+
+	switch {
+	case visitedUrl.RemainingVisits == 0 && !autoRenew:
+		go service.notify(visitedUrl, notification.TypeVisitsExhausted, "Short url visits exhausted",
+			fmt.Sprintf("Your short url %s has no remaining visits, renew the visits to keep it working.", visitedUrl.ShortUrl))
+	case threshold > 0 && visitedUrl.RemainingVisits == threshold:
+		go service.notify(visitedUrl, notification.TypeLowVisits, "Short url running low on visits",
+			fmt.Sprintf("Your short url %s has only %d visits remaining.", visitedUrl.ShortUrl, visitedUrl.RemainingVisits))
+	}
+}
+
+func (service *UrlService) notify(targetUrl *url.Url, notificationType, title, message string) {
+	urlID := targetUrl.ID
+	if err := service.notificationservice.Notify(targetUrl.UserID, &urlID, notificationType, title, message); err != nil {
+		log.GetLogger().Error("unable to notify user ", targetUrl.UserID, ": ", err.Error())
+	}
+}
+
 func (service *UrlService) RenewUrlVisits(urlToRenew *url.Url) error {
 
-	if err := service.doesUserExist(urlToRenew.UpdatedBy); err != nil {
+	if err := user.Exists(service.db, urlToRenew.UpdatedBy); err != nil {
 		return err
 	}
 
 	uow := repository.NewUnitOfWork(service.db, false)
 	defer uow.RollBack()
 
-	// if urlToRenew.RemainingVisits <= 0 {
-	// 	return errors.NewValidationError("number of visits should be a positive integer")
-	// }
+	if urlToRenew.RemainingVisits <= 0 {
+		return errors.NewValidationError("number of visits should be a positive integer")
+	}
 
 	urlOwner := &user.User{}
-	if err := service.repository.GetRecordByID(uow, urlToRenew.UserID, urlOwner); err != nil {
+	if err := service.repository.GetRecordByID(uow, urlToRenew.UserID, urlOwner, repository.ForUpdate()); err != nil {
 		return errors.NewDatabaseError("unable to find url owner")
 	}
 
@@ -194,27 +1082,37 @@ func (service *UrlService) RenewUrlVisits(urlToRenew *url.Url) error {
 		return errors.NewValidationError("no url found for this user with given url id")
 	}
 
+	if existingUrl.IsSecret() {
+		return errors.NewValidationError("secret links cannot be renewed")
+	}
+
 	subscription := &subscription.Subscription{}
-	if err := service.repository.GetRecord(uow, &subscription, repository.Order("created_at desc")); err != nil {
-		return errors.NewDatabaseError("unable to fetch subscription details")
+	if err := service.subscriptionservice.PlanFor(uow, urlOwner, subscription); err != nil {
+		return err
 	}
 
-	totalPriceToRenew := float32(urlToRenew.RemainingVisits) * subscription.ExtraVisitPrice
+	price := subscription.ExtraVisitPrice.Mul(urlToRenew.RemainingVisits)
+	redemption, err := service.couponservice.Redeem(uow, urlToRenew.CouponCode, urlOwner.ID, coupon.TypeVisitsRenewal, price)
+	if err != nil {
+		return err
+	}
+	bonusVisits := 0
+	if redemption != nil {
+		price -= redemption.Discount
+		bonusVisits = redemption.BonusQuantity
+	}
+
+	breakdown, err := service.taxservice.Quote(uow, urlOwner, price)
+	if err != nil {
+		return err
+	}
+	totalPriceToRenew := breakdown.Total
 
 	if urlOwner.Wallet < totalPriceToRenew {
 		return errors.NewValidationError("insufficient balance in wallet, please add money to wallet")
 	}
 
-	urlOwner.Wallet -= totalPriceToRenew
-
-	newVisitCount := existingUrl.RemainingVisits + urlToRenew.RemainingVisits
-
-	if err := service.repository.UpdateWithMap(uow, urlOwner, map[string]interface{}{
-		"wallet": urlOwner.Wallet,
-	}); err != nil {
-		uow.RollBack()
-		return errors.NewDatabaseError("unable to update wallet balance")
-	}
+	newVisitCount := existingUrl.RemainingVisits + urlToRenew.RemainingVisits + bonusVisits
 
 	if err := service.repository.UpdateWithMap(uow, existingUrl, map[string]interface{}{
 		"remaining_visits": newVisitCount,
@@ -225,21 +1123,44 @@ func (service *UrlService) RenewUrlVisits(urlToRenew *url.Url) error {
 	}
 
 	// //transaction--------------------------------------------------------------------------------------------------
-	var transactionType = "VISITSRENEWAL"
-	var note = fmt.Sprintf("%d visits renewed for %0.2f per visit price", urlToRenew.RemainingVisits, subscription.ExtraVisitPrice)
+	var transactionType = ledger.EntryTypeVisitsRenewal
+	var note = fmt.Sprintf("%d visits renewed for %s per visit price", urlToRenew.RemainingVisits, subscription.ExtraVisitPrice) + couponserv.Note(redemption)
 
-	if err := service.transactionservice.CreateTransaction(uow, urlOwner.ID, totalPriceToRenew, transactionType, note); err != nil {
+	if err := service.ledgerservice.PostWalletPurchase(uow, urlOwner.ID, breakdown.Taxable, breakdown.Tax(), transactionType, note); err != nil {
+		return err
+	}
+
+	purchase := transaction.NewPurchase(urlOwner.ID, transactionType, note, breakdown)
+	if err := service.transactionservice.RecordTransaction(uow, purchase); err != nil {
 		uow.RollBack()
 		return errors.NewDatabaseError("unable to create transaction")
 	}
 
+	if err := service.couponservice.Record(uow, redemption, &purchase.ID); err != nil {
+		return err
+	}
+
+	if err := service.referralservice.ConfirmReferral(uow, purchase); err != nil {
+		return err
+	}
+
+	// Nothing is invoiced when a coupon or a free plan leaves nothing to pay.
+	if purchase.Amount > 0 {
+		lines := []*invoice.InvoiceLine{
+			invoice.NewLine("Visits renewed for "+existingUrl.ShortUrl, urlToRenew.RemainingVisits, subscription.ExtraVisitPrice),
+		}
+		if _, err := service.invoiceservice.IssueInvoice(uow, purchase, append(lines, couponserv.InvoiceLines(redemption)...)); err != nil {
+			return err
+		}
+	}
+
 	uow.Commit()
 	return nil
 }
 
 func (service *UrlService) GetAllUrls(allUrl *[]url.UrlDTO, totalCount *int, parser *web.Parser, userIdFromUrl, userIdFromToken uuid.UUID) error {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return err
 	}
 
@@ -312,8 +1233,11 @@ func (service *UrlService) addSearchQueries(requestForm urlNet.Values) repositor
 		return nil
 	}
 
+	// The long url of a secret link carries a credential or invite, so searches never match secret links.
+	// They are still listed to their owner when no search is given.
 	queryProcessors = append(queryProcessors,
 		repository.Filter("(long_url LIKE ? OR short_url LIKE ?)", "%"+searchTerm+"%", "%"+searchTerm+"%"),
+		repository.Filter("link_type <> ?", url.LinkTypeSecret),
 	)
 
 	return repository.CombineQueries(queryProcessors)
@@ -321,7 +1245,7 @@ func (service *UrlService) addSearchQueries(requestForm urlNet.Values) repositor
 
 func (service *UrlService) GetUrlByID(targetURL *url.UrlDTO) error {
 
-	if err := service.doesUserExist(targetURL.UserID); err != nil {
+	if err := user.Exists(service.db, targetURL.UserID); err != nil {
 		return err
 	}
 
@@ -341,14 +1265,14 @@ func (service *UrlService) GetUrlByID(targetURL *url.UrlDTO) error {
 
 func (service *UrlService) GetUrlByShortUrl(originalUrl *url.UrlDTO) error {
 
-	if err := service.doesUserExist(originalUrl.UserID); err != nil {
+	if err := user.Exists(service.db, originalUrl.UserID); err != nil {
 		return err
 	}
 
 	uow := repository.NewUnitOfWork(service.db, false)
 	defer uow.RollBack()
 
-	if err := service.repository.GetRecord(uow, &originalUrl, repository.Filter("short_url = ? AND user_id = ?", originalUrl.ShortUrl, originalUrl.UserID)); err != nil {
+	if err := service.repository.GetRecord(uow, &originalUrl, repository.Filter("short_url = ? AND user_id = ? AND link_type <> ?", originalUrl.ShortUrl, originalUrl.UserID, url.LinkTypeSecret)); err != nil {
 		return errors.NewDatabaseError("no url found for this user with given short url")
 	}
 
@@ -358,7 +1282,7 @@ func (service *UrlService) GetUrlByShortUrl(originalUrl *url.UrlDTO) error {
 
 func (service *UrlService) UpdateUrl(targetUrl *url.Url) error {
 
-	if err := service.doesUserExist(targetUrl.UserID); err != nil {
+	if err := user.Exists(service.db, targetUrl.UserID); err != nil {
 		return err
 	}
 
@@ -366,7 +1290,36 @@ func (service *UrlService) UpdateUrl(targetUrl *url.Url) error {
 		return err
 	}
 
-	if err := service.doesLongUrlExistsForCurrentUser(targetUrl.LongUrl, targetUrl.UserID); err != nil {
+	existingUrl := &url.Url{}
+	if err := service.db.First(existingUrl, "id = ? AND user_id = ?", targetUrl.ID, targetUrl.UserID).Error; err != nil {
+		return errors.NewValidationError("no url found for this user with given url id")
+	}
+	if targetUrl.UnwrapRedirects == nil {
+		targetUrl.UnwrapRedirects = existingUrl.UnwrapRedirects
+	}
+
+	// Whether a link is secret and how often it opens is fixed when it is created, a used up link stays used up.
+	if existingUrl.ConsumedAt != nil {
+		return errors.NewValidationError("this link has already been used and can no longer be edited")
+	}
+	targetUrl.LinkType, targetUrl.MaxOpens, targetUrl.ConsumedAt = existingUrl.LinkType, existingUrl.MaxOpens, nil
+
+	// The short url stays in the namespace of its domain, the domain itself cannot be changed here.
+	if targetUrl.ShortUrl != existingUrl.ShortUrl {
+		if err := service.doesShortUrlExists(targetUrl.ShortUrl, existingUrl.DomainID); err != nil {
+			return err
+		}
+	}
+
+	if err := targetUrl.SetCanonicalUrl(); err != nil {
+		return err
+	}
+
+	if err := service.resolveRedirectChain(targetUrl); err != nil {
+		return err
+	}
+
+	if err := service.doesLongUrlExistsForCurrentUser(targetUrl.CanonicalUrl, targetUrl.UserID, targetUrl.ID); err != nil {
 		return err
 	}
 
@@ -379,7 +1332,22 @@ func (service *UrlService) UpdateUrl(targetUrl *url.Url) error {
 
 	targetUrl.UpdatedAt = time.Now()
 
-	if err := service.repository.Update(uow, targetUrl, repository.Filter("id = ? AND user_id = ?", targetUrl.ID, targetUrl.UserID)); err != nil {
+	// Only the destination and short url are edited here. Visits, billing and the other settings have
+	// their own endpoints, final_url is written even when blank so an old destination is cleared.
+	updates := map[string]interface{}{
+		"long_url":      targetUrl.LongUrl,
+		"canonical_url": targetUrl.CanonicalUrl,
+		"final_url":     targetUrl.FinalUrl,
+		"short_url":     targetUrl.ShortUrl,
+		"updated_by":    targetUrl.UpdatedBy,
+		"updated_at":    targetUrl.UpdatedAt,
+	}
+	if targetUrl.UnwrapRedirects != nil {
+		updates["unwrap_redirects"] = targetUrl.UnwrapRedirects
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &url.Url{}, updates,
+		repository.Filter("id = ? AND user_id = ?", targetUrl.ID, targetUrl.UserID)); err != nil {
 		return errors.NewDatabaseError("unable to update url")
 	}
 
@@ -389,7 +1357,7 @@ func (service *UrlService) UpdateUrl(targetUrl *url.Url) error {
 
 func (service *UrlService) Delete(urlID uuid.UUID, deletedBy uuid.UUID) error {
 
-	if err := service.doesUserExist(deletedBy); err != nil {
+	if err := user.Exists(service.db, deletedBy); err != nil {
 		return err
 	}
 
@@ -400,6 +1368,11 @@ func (service *UrlService) Delete(urlID uuid.UUID, deletedBy uuid.UUID) error {
 	uow := repository.NewUnitOfWork(service.db, false)
 	defer uow.RollBack()
 
+	existingUrl := &url.Url{}
+	if err := service.repository.GetRecordByID(uow, urlID, existingUrl); err != nil {
+		return errors.NewValidationError("URL ID is invalid")
+	}
+
 	now := time.Now()
 
 	if err := service.repository.UpdateWithMap(uow, &url.Url{}, map[string]interface{}{
@@ -410,6 +1383,12 @@ func (service *UrlService) Delete(urlID uuid.UUID, deletedBy uuid.UUID) error {
 	}
 
 	uow.Commit()
+
+	if existingUrl.IsFile() {
+		if err := service.storage.Delete(existingUrl.FileKey); err != nil {
+			log.GetLogger().Error("unable to delete stored file: ", err.Error())
+		}
+	}
 	return nil
 }
 
@@ -423,30 +1402,104 @@ func (service *UrlService) doesUrlExist(urlID uuid.UUID) error {
 	return nil
 }
 
-func (service *UrlService) doesLongUrlExistsForCurrentUser(longUrl string, userId uuid.UUID) error {
-	exists, _ := repository.DoesLongUrlExist(service.db, longUrl, userId, url.Url{},
-		repository.Filter("long_url = ?", longUrl))
-	if exists {
+// doesLongUrlExistsForCurrentUser compares canonical urls, so that differently written urls of the same
+// resource are detected as duplicates. The url with excludeID is not compared against itself.
+func (service *UrlService) doesLongUrlExistsForCurrentUser(canonicalUrl string, userId, excludeID uuid.UUID) error {
+	var count int
+	if err := service.db.Model(&url.Url{}).Where("canonical_url = ? AND user_id = ? AND id <> ?", canonicalUrl, userId, excludeID).
+		Count(&count).Error; err != nil {
+		return errors.NewDatabaseError("unable to check long url")
+	}
+	if count > 0 {
 		return errors.NewValidationError("Requested URL is already registered")
 	}
 	return nil
 }
 
-func (service *UrlService) doesShortUrlExists(shortUrl string) error {
+// doesShortUrlExists checks the short url within its domain, the same code can exist once per custom domain
+// and once on the shared host.
+func (service *UrlService) doesShortUrlExists(shortUrl string, domainID *uuid.UUID) error {
+	domainScope := repository.Filter("domain_id IS NULL")
+	if domainID != nil {
+		domainScope = repository.Filter("domain_id = ?", *domainID)
+	}
+
 	exists, _ := repository.DoesShortUrlExist(service.db, shortUrl, url.Url{},
-		repository.Filter("short_url = ?", shortUrl))
+		repository.Filter("short_url = ?", shortUrl), domainScope)
 	if exists {
 		return errors.NewValidationError("This Short URL is already registered, try another pattern")
 	}
 	return nil
 }
 
-// ---------------- Helpers ----------------
+// resolveRedirectChain rejects long urls that are not found, loop, redirect too often or lead back to one of our hosts,
+// and keeps the final destination when the owner asked to skip the intermediate hops.
+func (service *UrlService) resolveRedirectChain(targetUrl *url.Url) error {
+	targetUrl.FinalUrl = ""
 
-func (service *UrlService) doesUserExist(ID uuid.UUID) error {
-	var u user.User
-	if err := service.db.First(&u, "id = ?", ID).Error; err != nil {
-		return errors.NewValidationError("user Doesn't exists")
+	if targetUrl.IsSecret() {
+		// Following the chain could use up a one-time invite, so only the host is checked.
+		if err := service.chainResolver.CheckHost(targetUrl.LongUrl); err != nil {
+			return errors.NewValidationError(err.Error())
+		}
+		return nil
+	}
+
+	if err := targetUrl.CheckDestination(); err != nil {
+		return err
+	}
+
+	chain, err := service.chainResolver.Resolve(targetUrl.LongUrl)
+	if err != nil {
+		return errors.NewValidationError("unable to resolve long url: " + err.Error())
+	}
+
+	if targetUrl.UnwrapRedirects != nil && *targetUrl.UnwrapRedirects && len(chain.Hops) > 1 {
+		targetUrl.FinalUrl = chain.FinalUrl()
 	}
 	return nil
 }
+
+// isOwnHost reports whether the host serves our short links, either as a configured host or a verified custom domain.
+func (service *UrlService) isOwnHost(host string) bool {
+	for _, ownHost := range strings.Split(config.ShortLinkHosts.GetStringValue(), ",") {
+		if strings.EqualFold(strings.TrimSpace(ownHost), host) {
+			return true
+		}
+	}
+
+	var count int
+	service.db.Model(&domain.Domain{}).Where("host = ? AND is_verified = ?", host, true).Count(&count)
+	return count > 0
+}
+
+// findByShortUrl resolves a short code in the namespace of the request host. Hosts that are not
+// a verified custom domain share the default namespace.
+func (service *UrlService) findByShortUrl(uow *repository.UnitOfWork, target *url.Url, host string) error {
+	domainScope := repository.Filter("domain_id IS NULL")
+
+	if host != "" {
+		customDomain := domain.Domain{}
+		if err := service.repository.GetRecord(uow, &customDomain, repository.Filter("host = ? AND is_verified = ?", host, true)); err == nil {
+			domainScope = repository.Filter("domain_id = ?", customDomain.ID)
+		}
+	}
+
+	return service.repository.GetRecord(uow, target, repository.Filter("short_url = ?", target.ShortUrl), domainScope)
+}
+
+// ---------------- Helpers ----------------
+
+func isDestinationReachable(client *http.Client, longUrl string) bool {
+	resp, err := client.Head(longUrl)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = client.Get(longUrl)
+	}
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusGone && resp.StatusCode < http.StatusInternalServerError
+}
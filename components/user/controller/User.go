@@ -4,14 +4,19 @@ import (
 	"net/http"
 	"strconv"
 	"url-shortner-be/components/errors"
+	"url-shortner-be/components/idempotency"
 	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
+	"url-shortner-be/components/referral"
 	"url-shortner-be/components/security"
 	"url-shortner-be/components/web"
 	"url-shortner-be/model/credential"
+	"url-shortner-be/model/payment"
 	"url-shortner-be/model/stats"
 	"url-shortner-be/model/subscription"
 	"url-shortner-be/model/transaction"
 	"url-shortner-be/model/user"
+	"url-shortner-be/model/withdrawal"
 
 	userService "url-shortner-be/components/user/service"
 
@@ -22,12 +27,14 @@ import (
 type UserController struct {
 	log         log.Logger
 	UserService *userService.UserService
+	idempotency *idempotency.Guard
 }
 
-func NewUserController(userService *userService.UserService, log log.Logger) *UserController {
+func NewUserController(userService *userService.UserService, idempotencyGuard *idempotency.Guard, log log.Logger) *UserController {
 	return &UserController{
 		log:         log,
 		UserService: userService,
+		idempotency: idempotencyGuard,
 	}
 }
 
@@ -44,9 +51,9 @@ func (userController *UserController) RegisterRoutes(router *mux.Router) {
 	unguardedRouter.HandleFunc("/register-user", userController.registerUser).Methods(http.MethodPost)
 	adminguardedRouter.HandleFunc("/register-admin", userController.registerAdmin).Methods(http.MethodPost)
 
-	userguardedRouter.HandleFunc("/{userId}/wallet/add", userController.addAmountToWallet).Methods(http.MethodPost)
-	userguardedRouter.HandleFunc("/{userId}/wallet/withdraw", userController.withdrawAmountFromWallet).Methods(http.MethodPost)
-	userguardedRouter.HandleFunc("/{userId}/renew-urls", userController.renewUrlsByUserId).Methods(http.MethodPost)
+	userguardedRouter.Handle("/{userId}/wallet/add", userController.idempotency.Wrap(userController.addAmountToWallet)).Methods(http.MethodPost)
+	userguardedRouter.Handle("/{userId}/wallet/withdraw", userController.idempotency.Wrap(userController.withdrawAmountFromWallet)).Methods(http.MethodPost)
+	userguardedRouter.Handle("/{userId}/renew-urls", userController.idempotency.Wrap(userController.renewUrlsByUserId)).Methods(http.MethodPost)
 	userguardedRouter.HandleFunc("/{userId}/amount", userController.getwalletAmount).Methods(http.MethodGet)
 	userguardedRouter.HandleFunc("/{userId}/report", userController.getUserReportStats).Methods(http.MethodGet)
 
@@ -111,6 +118,7 @@ func (controller *UserController) registerUser(w http.ResponseWriter, r *http.Re
 		web.RespondError(w, err)
 		return
 	}
+	newUser.SignupIPHash, newUser.SignupDeviceHash = referral.SignupFingerprints(r)
 
 	if err = controller.UserService.CreateUser(&newUser); err != nil {
 		web.RespondError(w, err)
@@ -279,7 +287,7 @@ func (controller *UserController) addAmountToWallet(w http.ResponseWriter, r *ht
 		return
 	}
 
-	if userToAddMoney.Wallet > 1000000.00 {
+	if userToAddMoney.Wallet > money.FromRupees(1000000) {
 		web.RespondErrorMessage(w, http.StatusInternalServerError, "add amount must not be greater than 1000000")
 		return
 	}
@@ -292,15 +300,15 @@ func (controller *UserController) addAmountToWallet(w http.ResponseWriter, r *ht
 	}
 	userToAddMoney.ID = userIdFromToken
 
-	if err = controller.UserService.AddAmountToWalllet(userIdFromUrl, userToAddMoney); err != nil {
+	order := &payment.PaymentOrder{}
+	if err = controller.UserService.AddAmountToWalllet(userIdFromUrl, userToAddMoney, order); err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
 		return
 	}
 
-	web.RespondJSON(w, http.StatusOK, map[string]string{
-		"message": "Amount added successfully",
-	})
+	// The amount is added once the payment for this order is confirmed by the gateway.
+	web.RespondJSON(w, http.StatusCreated, order)
 }
 
 func (controller *UserController) withdrawAmountFromWallet(w http.ResponseWriter, r *http.Request) {
@@ -312,35 +320,28 @@ func (controller *UserController) withdrawAmountFromWallet(w http.ResponseWriter
 		return
 	}
 
-	userToWithdrawMoney := &user.User{}
-	err = web.UnmarshalJSON(r, &userToWithdrawMoney)
+	request := &withdrawal.WithdrawalRequest{}
+	err = web.UnmarshalJSON(r, request)
 	if err != nil {
 		web.RespondError(w, errors.NewHTTPError("Unable to parse request body", http.StatusBadRequest))
 		return
 	}
 
-	if userToWithdrawMoney.Wallet > 1000000.00 {
-		web.RespondErrorMessage(w, http.StatusInternalServerError, "withdrawal amount must not be greater than 1000000")
-		return
-	}
-
 	userIdFromToken, err := security.ExtractUserIDFromToken(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
 		return
 	}
-	userToWithdrawMoney.ID = userIdFromToken
 
-	if err = controller.UserService.WithdrawMoneyFromWallet(userIdFromUrl, userToWithdrawMoney); err != nil {
+	if err = controller.UserService.WithdrawMoneyFromWallet(userIdFromUrl, userIdFromToken, request); err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
 		return
 	}
 
-	web.RespondJSON(w, http.StatusOK, map[string]string{
-		"message": "Amount removed successfully",
-	})
+	// The amount is paid out once an admin approves the request.
+	web.RespondJSON(w, http.StatusCreated, request)
 }
 
 func (controller *UserController) getTransactionByUserId(w http.ResponseWriter, r *http.Request) {
@@ -497,6 +498,7 @@ func (controller *UserController) getMonthWiseRecords(w http.ResponseWriter, r *
 	}
 
 	// Call appropriate service
+	var revenue []stats.MonthlyAmount
 	var stats []stats.MonthlyStat
 	switch value {
 	case "new-users":
@@ -506,9 +508,9 @@ func (controller *UserController) getMonthWiseRecords(w http.ResponseWriter, r *
 	case "urls-generated":
 		stats, err = controller.UserService.GetMonthlyStats(userIdFromToken, "urls", "created_at", year, "")
 	case "urls-renewed":
-		stats, err = controller.UserService.GetMonthlyStats(userIdFromToken, "transactions", "created_at", year, "AND amount > 0")
+		stats, err = controller.UserService.GetMonthlyStats(userIdFromToken, "transactions", "created_at", year, "AND type IN ('URLRENEWAL','VISITSRENEWAL')")
 	case "total-revenue":
-		stats, err = controller.UserService.GetMonthlyRevenue(userIdFromToken, year)
+		revenue, err = controller.UserService.GetMonthlyRevenue(userIdFromToken, year)
 	case "paid-user":
 		stats, err = controller.UserService.GetMonthlyUniqueUserTransactions(userIdFromToken, year)
 	default:
@@ -528,7 +530,7 @@ func (controller *UserController) getMonthWiseRecords(w http.ResponseWriter, r *
 	}
 
 	monthName := months[monthInt-1]
-	var valueForMonth float64 = 0.0
+	var valueForMonth interface{} = 0.0
 
 	for _, stat := range stats {
 		if stat.Month == monthInt {
@@ -537,6 +539,17 @@ func (controller *UserController) getMonthWiseRecords(w http.ResponseWriter, r *
 		}
 	}
 
+	// Revenue is reported as an exact amount instead of a float
+	if value == "total-revenue" {
+		valueForMonth = money.Money(0)
+		for _, stat := range revenue {
+			if stat.Month == monthInt {
+				valueForMonth = stat.Value
+				break
+			}
+		}
+	}
+
 	// Final response
 	response := map[string]interface{}{
 		"month": monthName,
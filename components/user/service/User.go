@@ -5,15 +5,29 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+	couponserv "url-shortner-be/components/coupon/service"
 	"url-shortner-be/components/errors"
+	invoiceserv "url-shortner-be/components/invoice/service"
+	ledgerserv "url-shortner-be/components/ledger/service"
+	"url-shortner-be/components/money"
+	paymentserv "url-shortner-be/components/payment/service"
+	referralserv "url-shortner-be/components/referral/service"
 	"url-shortner-be/components/security"
+	subscriptionserv "url-shortner-be/components/subscription/service"
+	taxserv "url-shortner-be/components/tax/service"
 	transactionserv "url-shortner-be/components/transaction/service"
 	"url-shortner-be/components/web"
+	withdrawalserv "url-shortner-be/components/withdrawal/service"
+	"url-shortner-be/model/coupon"
 	"url-shortner-be/model/credential"
+	"url-shortner-be/model/invoice"
+	"url-shortner-be/model/ledger"
+	"url-shortner-be/model/payment"
 	"url-shortner-be/model/stats"
 	"url-shortner-be/model/subscription"
 	"url-shortner-be/model/transaction"
 	"url-shortner-be/model/user"
+	"url-shortner-be/model/withdrawal"
 	"url-shortner-be/module/repository"
 
 	uuid "github.com/satori/go.uuid"
@@ -27,22 +41,38 @@ const cost = 10
 
 type Values map[string][]string
 type UserService struct {
-	db                 *gorm.DB
-	repository         repository.Repository
-	transactionservice *transactionserv.TransactionService
+	db                  *gorm.DB
+	repository          repository.Repository
+	transactionservice  *transactionserv.TransactionService
+	ledgerservice       *ledgerserv.LedgerService
+	paymentservice      *paymentserv.PaymentService
+	withdrawalservice   *withdrawalserv.WithdrawalService
+	invoiceservice      *invoiceserv.InvoiceService
+	taxservice          *taxserv.TaxService
+	couponservice       *couponserv.CouponService
+	referralservice     *referralserv.ReferralService
+	subscriptionservice *subscriptionserv.SubscriptionService
 }
 
-func NewUserService(DB *gorm.DB, repo repository.Repository, txService *transactionserv.TransactionService) *UserService {
+func NewUserService(DB *gorm.DB, repo repository.Repository, txService *transactionserv.TransactionService, ledgerService *ledgerserv.LedgerService, paymentService *paymentserv.PaymentService, withdrawalService *withdrawalserv.WithdrawalService, invoiceService *invoiceserv.InvoiceService) *UserService {
 	return &UserService{
-		db:                 DB,
-		repository:         repo,
-		transactionservice: txService,
+		db:                  DB,
+		repository:          repo,
+		transactionservice:  txService,
+		ledgerservice:       ledgerService,
+		paymentservice:      paymentService,
+		withdrawalservice:   withdrawalService,
+		invoiceservice:      invoiceService,
+		taxservice:          taxserv.NewTaxService(DB, repo),
+		couponservice:       couponserv.NewCouponService(DB, repo),
+		referralservice:     referralserv.NewReferralService(DB, repo),
+		subscriptionservice: subscriptionserv.NewSubscriptionService(DB, repo),
 	}
 }
 
 func (service *UserService) CreateAdmin(newUser *user.User, userIdFromToken uuid.UUID) error {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return err
 	}
 
@@ -77,6 +107,10 @@ func (service *UserService) CreateAdmin(newUser *user.User, userIdFromToken uuid
 	}
 	newUser.Credentials.Password = string(hashedPassword)
 
+	if newUser.ReferralCode, err = service.referralservice.NewCode(uow); err != nil {
+		return err
+	}
+
 	if err := uow.DB.Create(newUser).Error; err != nil {
 		return errors.NewDatabaseError("Failed to create user")
 	}
@@ -109,17 +143,37 @@ func (service *UserService) CreateUser(newUser *user.User) error {
 	newUser.Credentials.Password = string(hashedPassword)
 
 	subscription := &subscription.Subscription{}
-	err = service.repository.GetRecord(uow, &subscription)
-	if err != nil {
-		uow.RollBack()
-		return errors.NewDatabaseError("Admin has not set subscription price yet, please contact admin")
+	if err := service.subscriptionservice.DefaultPlan(uow, subscription); err != nil {
+		return err
 	}
+	newUser.SubscriptionID = &subscription.ID
 	newUser.UrlCount = subscription.FreeShortUrls
 
+	newUser.ID = uuid.NewV4()
+	redemption, err := service.couponservice.Redeem(uow, newUser.CouponCode, newUser.ID, coupon.TypeSignup, 0)
+	if err != nil {
+		return err
+	}
+	if redemption != nil {
+		newUser.UrlCount += redemption.BonusQuantity
+	}
+
+	if newUser.ReferralCode, err = service.referralservice.NewCode(uow); err != nil {
+		return err
+	}
+
 	if err := uow.DB.Create(newUser).Error; err != nil {
 		return errors.NewDatabaseError("Failed to create user")
 	}
 
+	if err := service.couponservice.Record(uow, redemption, nil); err != nil {
+		return err
+	}
+
+	if err := service.referralservice.Refer(uow, newUser); err != nil {
+		return err
+	}
+
 	//transaction--------------------------------------------------------------------------------------------------
 	// var transactionType = "ACCOUNTCREATION"
 	// var note = fmt.Sprintf("First %d Free Url's limit is added to account, with %d Free visits per URL", subscription.FreeShortUrls, subscription.FreeVisits)
@@ -179,7 +233,7 @@ func (service *UserService) Login(userCredential *credential.Credential, claim *
 
 func (service *UserService) GetUserByID(targetUser *user.UserDTO, tokenUserId uuid.UUID) error {
 
-	if err := service.doesUserExist(tokenUserId); err != nil {
+	if err := user.Exists(service.db, tokenUserId); err != nil {
 		return err
 	}
 
@@ -217,7 +271,7 @@ func (service *UserService) GetUserByID(targetUser *user.UserDTO, tokenUserId uu
 
 func (service *UserService) GetAllUsers(allUsers *[]user.UserDTO, parser *web.Parser, totalCount *int, userIdFromToken uuid.UUID) error {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return err
 	}
 
@@ -288,7 +342,7 @@ func (service *UserService) addSearchQueries(requestForm url.Values) repository.
 
 func (service *UserService) UpdateUser(targetUser *user.User, userIdFromToken uuid.UUID) error {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return err
 	}
 
@@ -304,7 +358,56 @@ func (service *UserService) UpdateUser(targetUser *user.User, userIdFromToken uu
 		return errors.NewValidationError("Inactive user cannot perform update operation")
 	}
 
-	if err := service.repository.Update(uow, targetUser, repository.Filter("id = ?", targetUser.ID)); err != nil {
+	if targetUser.ID != tempUser.ID && (tempUser.IsAdmin == nil || !*tempUser.IsAdmin) {
+		return errors.NewUnauthorizedError("you are not authorized to update this user")
+	}
+
+	existingUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, targetUser.ID, &existingUser); err != nil {
+		return errors.NewNotFoundError("User not found")
+	}
+
+	if err := readOnlyUserFieldsChanged(targetUser, &existingUser); err != nil {
+		return err
+	}
+
+	// Only the profile is updated here. The wallet, url count, referral code and plan are changed by
+	// the ledger, purchases and the referral and plan endpoints, blank fields are left as they are.
+	updates := map[string]interface{}{
+		"updated_by": targetUser.UpdatedBy,
+		"updated_at": time.Now(),
+	}
+	// Admins activate and deactivate users, nobody can change their own status.
+	if targetUser.IsActive != nil && *targetUser.IsActive != *existingUser.IsActive {
+		if tempUser.IsAdmin == nil || !*tempUser.IsAdmin || targetUser.ID == tempUser.ID {
+			return errors.NewUnauthorizedError("only an admin can activate or deactivate another user")
+		}
+		updates["is_active"] = *targetUser.IsActive
+	}
+	profile := map[string]string{
+		"first_name": targetUser.FirstName,
+		"last_name":  targetUser.LastName,
+		"phone_no":   targetUser.PhoneNo,
+		"state":      targetUser.State,
+		"gstin":      targetUser.GSTIN,
+	}
+	for column, value := range profile {
+		if value != "" {
+			updates[column] = value
+		}
+	}
+
+	if targetUser.State == "" {
+		targetUser.State = existingUser.State
+	}
+	if targetUser.GSTIN == "" {
+		targetUser.GSTIN = existingUser.GSTIN
+	}
+	if err := targetUser.ValidateTaxProfile(); err != nil {
+		return err
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &user.User{}, updates, repository.Filter("id = ?", targetUser.ID)); err != nil {
 		return errors.NewDatabaseError("unable to update user")
 	}
 
@@ -312,8 +415,39 @@ func (service *UserService) UpdateUser(targetUser *user.User, userIdFromToken uu
 	return nil
 }
 
+// readOnlyUserFieldsChanged rejects an update that tries to change a field UpdateUser does not write, so that
+// the change is not dropped silently. Fields sent back unchanged or left blank are fine.
+func readOnlyUserFieldsChanged(targetUser, existingUser *user.User) error {
+	if targetUser.Email != "" && targetUser.Email != existingUser.Email ||
+		targetUser.Credentials != nil && targetUser.Credentials.Email != "" && targetUser.Credentials.Email != existingUser.Email {
+		return errors.NewValidationError("email cannot be changed")
+	}
+	if targetUser.IsAdmin != nil && *targetUser.IsAdmin != (existingUser.IsAdmin != nil && *existingUser.IsAdmin) {
+		return errors.NewValidationError("admin rights cannot be changed")
+	}
+	if targetUser.Wallet != 0 && targetUser.Wallet != existingUser.Wallet {
+		return errors.NewValidationError("wallet is changed through top ups and withdrawals")
+	}
+	if targetUser.UrlCount != 0 && targetUser.UrlCount != existingUser.UrlCount {
+		return errors.NewValidationError("url count is changed through url renewals")
+	}
+	if targetUser.ReferralCode != "" && targetUser.ReferralCode != existingUser.ReferralCode {
+		return errors.NewValidationError("referral code cannot be changed")
+	}
+	if !sameUUID(targetUser.SubscriptionID, existingUser.SubscriptionID) ||
+		!sameUUID(targetUser.PendingSubscriptionID, existingUser.PendingSubscriptionID) {
+		return errors.NewValidationError("plans are changed through the upgrade and downgrade endpoints")
+	}
+	return nil
+}
+
+// sameUUID reports whether an optional id sent in a request is blank or equal to the stored one.
+func sameUUID(sent, stored *uuid.UUID) bool {
+	return sent == nil || (stored != nil && uuid.Equal(*sent, *stored))
+}
+
 func (service *UserService) Delete(userID uuid.UUID, deletedBy uuid.UUID) error {
-	if err := service.doesUserExist(userID); err != nil {
+	if err := user.Exists(service.db, userID); err != nil {
 		return err
 	}
 
@@ -349,13 +483,14 @@ func (service *UserService) Delete(userID uuid.UUID, deletedBy uuid.UUID) error
 	return nil
 }
 
-func (service *UserService) AddAmountToWalllet(userID uuid.UUID, userToAddMoney *user.User) error {
+// AddAmountToWalllet opens a payment order for the amount, the wallet is credited by the payment webhook once the user has paid.
+func (service *UserService) AddAmountToWalllet(userID uuid.UUID, userToAddMoney *user.User, order *payment.PaymentOrder) error {
 
-	if err := service.doesUserExist(userToAddMoney.ID); err != nil {
+	if err := user.Exists(service.db, userToAddMoney.ID); err != nil {
 		return err
 	}
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWork(service.db, true)
 	defer uow.RollBack()
 
 	var dbUser user.User
@@ -377,101 +512,33 @@ func (service *UserService) AddAmountToWalllet(userID uuid.UUID, userToAddMoney
 
 	var amount = userToAddMoney.Wallet
 
-	dbUser.Wallet += amount
-
-	if dbUser.Wallet > 1000000000.00 {
+	if dbUser.Wallet+amount > money.FromRupees(1000000000) {
 		return errors.NewHTTPError("wallet balance must not exceed 1000000000.00", http.StatusInternalServerError)
 	}
 
-	if err := service.repository.UpdateWithMap(uow, &dbUser,
-		map[string]interface{}{
-			"wallet":     dbUser.Wallet,
-			"updated_at": time.Now(),
-		},
-		repository.Filter("id = ?", userID),
-	); err != nil {
-		return errors.NewDatabaseError("Failed to update wallet")
-	}
-
-	// transaction--------------------------------------------------------------------------------------------------
-	// var transactionType = "CREDIT"
-	// var note = fmt.Sprintf("%0.2f added in the wallet", amount)
-
-	// if err := service.transactionservice.CreateTransaction(uow, userToAddMoney.ID, amount, transactionType, note); err != nil {
-	// 	uow.RollBack()
-	// 	return errors.NewDatabaseError("unable to create transaction")
-	// }
-
-	uow.Commit()
-	return nil
+	order.Amount = amount
+	return service.paymentservice.CreateTopUpOrder(userID, order)
 }
 
-func (service *UserService) WithdrawMoneyFromWallet(userID uuid.UUID, userToWthdrawMoney *user.User) error {
+// WithdrawMoneyFromWallet raises a withdrawal request, the amount is held from the wallet until an admin approves or rejects it.
+func (service *UserService) WithdrawMoneyFromWallet(userID, userIdFromToken uuid.UUID, request *withdrawal.WithdrawalRequest) error {
 
-	if err := service.doesUserExist(userToWthdrawMoney.ID); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return err
 	}
 
-	if userToWthdrawMoney.Wallet <= 0 {
-		return errors.NewValidationError("Wihdraw amount must be greater than zero")
-	}
-
-	if err := service.doesUserExist(userID); err != nil {
-		return err
-	}
-
-	uow := repository.NewUnitOfWork(service.db, false)
-	defer uow.RollBack()
-
-	var dbUser user.User
-	if err := service.repository.GetRecord(uow, &dbUser, repository.Filter("id = ?", userID)); err != nil {
-		return errors.NewNotFoundError("User not found")
-	}
-
-	if !*dbUser.IsActive {
-		return errors.NewValidationError("Inactive users cannot withdraw money")
-	}
-
-	if dbUser.ID != userToWthdrawMoney.ID {
+	if userID != userIdFromToken {
 		return errors.NewUnauthorizedError("you are not authorized to withdraw amount from wallet")
 	}
 
-	if userToWthdrawMoney.Wallet >= dbUser.Wallet {
-		return errors.NewValidationError("insufficient balance to withdraw")
-	}
-
-	var amount = userToWthdrawMoney.Wallet
-
-	dbUser.Wallet -= amount
-
-	if err := service.repository.UpdateWithMap(uow, &dbUser,
-		map[string]interface{}{
-			"wallet":     dbUser.Wallet,
-			"updated_at": time.Now(),
-		},
-		repository.Filter("id = ?", userID),
-	); err != nil {
-		return errors.NewDatabaseError("Failed to update wallet")
-	}
-
-	//transaction--------------------------------------------------------------------------------------------------
-	// var transactionType = "DEBIT"
-	// var note = fmt.Sprintf("%0.2f removed from the wallet", amount)
-
-	// if err := service.transactionservice.CreateTransaction(uow, userToWthdrawMoney.ID, amount, transactionType, note); err != nil {
-	// 	uow.RollBack()
-	// 	return errors.NewDatabaseError("unable to create transaction")
-	// }
-
-	uow.Commit()
-	return nil
+	return service.withdrawalservice.RequestWithdrawal(userID, request)
 }
 
-func (service *UserService) WithdrawAmountFromWallet(userID uuid.UUID, amount float32) error {
+func (service *UserService) WithdrawAmountFromWallet(userID uuid.UUID, amount money.Money) error {
 	uow := repository.NewUnitOfWork(service.db, false)
 	defer uow.RollBack()
 
-	if err := service.doesUserExist(userID); err != nil {
+	if err := user.Exists(service.db, userID); err != nil {
 		return err
 	}
 
@@ -480,6 +547,7 @@ func (service *UserService) WithdrawAmountFromWallet(userID uuid.UUID, amount fl
 		uow,
 		&dbUser,
 		repository.Filter("id = ?", userID),
+		repository.ForUpdate(),
 	); err != nil {
 		return errors.NewNotFoundError("User not found")
 	}
@@ -488,18 +556,14 @@ func (service *UserService) WithdrawAmountFromWallet(userID uuid.UUID, amount fl
 		return errors.NewValidationError("Insufficient balance")
 	}
 
-	dbUser.Wallet -= amount
+	var note = fmt.Sprintf("%s removed from the wallet", amount)
 
-	if err := service.repository.UpdateWithMap(
-		uow,
-		&dbUser,
-		map[string]interface{}{
-			"wallet":     dbUser.Wallet,
-			"updated_at": time.Now(),
-		},
-		repository.Filter("id = ?", userID),
-	); err != nil {
-		return errors.NewDatabaseError("Failed to update wallet")
+	if err := service.ledgerservice.PostWalletEntry(uow, userID, -amount, ledger.AccountFunding, ledger.EntryTypeDebit, note); err != nil {
+		return err
+	}
+
+	if err := service.transactionservice.CreateTransaction(uow, userID, amount, ledger.EntryTypeDebit, note); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
 	}
 
 	uow.Commit()
@@ -508,7 +572,7 @@ func (service *UserService) WithdrawAmountFromWallet(userID uuid.UUID, amount fl
 
 func (service *UserService) GetAllTransactions(transactions *[]transaction.Transaction, totalCount *int, parser *web.Parser, userIdFromUrl, userIdFromToken uuid.UUID) error {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return errors.NewDatabaseError("user not found with given id")
 	}
 
@@ -553,9 +617,9 @@ func (service *UserService) GetAllTransactions(transactions *[]transaction.Trans
 	return nil
 }
 
-func (service *UserService) GetWalletAmount(user *user.UserDTO, userIdFromToken uuid.UUID) error {
+func (service *UserService) GetWalletAmount(walletUser *user.UserDTO, userIdFromToken uuid.UUID) error {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return err
 	}
 
@@ -566,7 +630,7 @@ func (service *UserService) GetWalletAmount(user *user.UserDTO, userIdFromToken
 
 	// }
 
-	if err := service.repository.GetRecordByID(uow, user.ID, user); err != nil {
+	if err := service.repository.GetRecordByID(uow, walletUser.ID, walletUser); err != nil {
 		return errors.NewDatabaseError("Unable to fetch wallet amount for this user")
 	}
 
@@ -582,7 +646,7 @@ func (service *UserService) GetSubscription(subscriptions *[]subscription.Subscr
 	uow := repository.NewUnitOfWork(service.db, true)
 	defer uow.RollBack()
 
-	if err := service.repository.GetAll(uow, subscriptions, repository.Filter("user_id = ?", userID), repository.Paginate(limit, offset, totalCount), repository.Order("created_at desc")); err != nil {
+	if err := service.repository.GetAll(uow, subscriptions, repository.Filter("id IN (SELECT subscription_id FROM users WHERE id = ?)", userID), repository.Paginate(limit, offset, totalCount), repository.Order("created_at desc")); err != nil {
 		return errors.NewDatabaseError("unable to fetch subscriptions for this user")
 	}
 
@@ -592,7 +656,7 @@ func (service *UserService) GetSubscription(subscriptions *[]subscription.Subscr
 
 func (service *UserService) RenewUrls(userToUpdate *user.User) error {
 
-	if err := service.doesUserExist(userToUpdate.UpdatedBy); err != nil {
+	if err := user.Exists(service.db, userToUpdate.UpdatedBy); err != nil {
 		return err
 	}
 
@@ -617,27 +681,39 @@ func (service *UserService) RenewUrls(userToUpdate *user.User) error {
 	}
 
 	existingUser := &user.User{}
-	if err := service.repository.GetRecordByID(uow, userToUpdate.ID, &existingUser); err != nil {
+	if err := service.repository.GetRecordByID(uow, userToUpdate.ID, &existingUser, repository.ForUpdate()); err != nil {
 		return errors.NewDatabaseError("unable to find user")
 	}
 
 	subscription := &subscription.Subscription{}
-	if err := service.repository.GetRecord(uow, &subscription, repository.Order("created_at desc")); err != nil {
-		return errors.NewDatabaseError("unable to fetch subscription details")
+	if err := service.subscriptionservice.PlanFor(uow, existingUser, subscription); err != nil {
+		return err
 	}
 
-	totalPriceToRenew := float32(userToUpdate.UrlCount) * subscription.NewUrlPrice
+	price := subscription.NewUrlPrice.Mul(userToUpdate.UrlCount)
+	redemption, err := service.couponservice.Redeem(uow, userToUpdate.CouponCode, existingUser.ID, coupon.TypeUrlRenewal, price)
+	if err != nil {
+		return err
+	}
+	bonusUrls := 0
+	if redemption != nil {
+		price -= redemption.Discount
+		bonusUrls = redemption.BonusQuantity
+	}
+
+	breakdown, err := service.taxservice.Quote(uow, existingUser, price)
+	if err != nil {
+		return err
+	}
+	totalPriceToRenew := breakdown.Total
 
 	if existingUser.Wallet < totalPriceToRenew {
 		return errors.NewValidationError("insufficient balance in wallet, please add money to wallet")
 	}
 
-	existingUser.Wallet -= totalPriceToRenew
-
-	newUrlCount := userToUpdate.UrlCount + existingUser.UrlCount
+	newUrlCount := userToUpdate.UrlCount + bonusUrls + existingUser.UrlCount
 
 	if err := service.repository.UpdateWithMap(uow, existingUser, map[string]interface{}{
-		"wallet":     existingUser.Wallet,
 		"url_count":  newUrlCount,
 		"updated_by": userToUpdate.UpdatedBy,
 	}); err != nil {
@@ -646,21 +722,44 @@ func (service *UserService) RenewUrls(userToUpdate *user.User) error {
 	}
 
 	//transaction--------------------------------------------------------------------------------------------------
-	var transactionType = "URLRENEWAL"
-	var note = fmt.Sprintf("%d url renewed for %0.2f per url renewal price", userToUpdate.UrlCount, subscription.NewUrlPrice)
+	var transactionType = ledger.EntryTypeUrlRenewal
+	var note = fmt.Sprintf("%d url renewed for %s per url renewal price", userToUpdate.UrlCount, subscription.NewUrlPrice) + couponserv.Note(redemption)
 
-	if err := service.transactionservice.CreateTransaction(uow, existingUser.ID, totalPriceToRenew, transactionType, note); err != nil {
+	if err := service.ledgerservice.PostWalletPurchase(uow, existingUser.ID, breakdown.Taxable, breakdown.Tax(), transactionType, note); err != nil {
+		return err
+	}
+
+	purchase := transaction.NewPurchase(existingUser.ID, transactionType, note, breakdown)
+	if err := service.transactionservice.RecordTransaction(uow, purchase); err != nil {
 		uow.RollBack()
 		return errors.NewDatabaseError("unable to create transaction")
 	}
 
+	if err := service.couponservice.Record(uow, redemption, &purchase.ID); err != nil {
+		return err
+	}
+
+	if err := service.referralservice.ConfirmReferral(uow, purchase); err != nil {
+		return err
+	}
+
+	// Nothing is invoiced when a coupon or a free plan leaves nothing to pay.
+	if purchase.Amount > 0 {
+		lines := []*invoice.InvoiceLine{
+			invoice.NewLine("Short url renewal", userToUpdate.UrlCount, subscription.NewUrlPrice),
+		}
+		if _, err := service.invoiceservice.IssueInvoice(uow, purchase, append(lines, couponserv.InvoiceLines(redemption)...)); err != nil {
+			return err
+		}
+	}
+
 	uow.Commit()
 	return nil
 }
 
 func (service *UserService) GetMonthlyStats(userIdFromToken uuid.UUID, table string, column string, year int, extraFilter string) ([]stats.MonthlyStat, error) {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return nil, err
 	}
 
@@ -691,7 +790,7 @@ func (service *UserService) GetMonthlyStats(userIdFromToken uuid.UUID, table str
 }
 
 func (s *UserService) GetMonthlyRenewalCounts(userIdFromToken uuid.UUID, year int) ([]stats.MonthlyStat, error) {
-	if err := s.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(s.db, userIdFromToken); err != nil {
 		return nil, err
 	}
 
@@ -725,9 +824,9 @@ func (s *UserService) GetMonthlyRenewalCounts(userIdFromToken uuid.UUID, year in
 	return stats, err
 }
 
-func (service *UserService) GetMonthlyRevenue(userIdFromToken uuid.UUID, year int) ([]stats.MonthlyStat, error) {
+func (service *UserService) GetMonthlyRevenue(userIdFromToken uuid.UUID, year int) ([]stats.MonthlyAmount, error) {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return nil, err
 	}
 
@@ -743,13 +842,13 @@ func (service *UserService) GetMonthlyRevenue(userIdFromToken uuid.UUID, year in
 		return nil, errors.NewUnauthorizedError("Inactive users cannot see monthly revenue")
 	}
 
-	var stats []stats.MonthlyStat
+	var stats []stats.MonthlyAmount
 
 	query := `
-		SELECT MONTH(created_at) as month, SUM(amount) as value
+		SELECT MONTH(created_at) as month, SUM(taxable_amount_paise) as value
 		FROM transactions
 		WHERE YEAR(created_at) = ?
-		 AND type In('URLRENEWAL','VISITSRENEWAL')
+		 AND type In('URLRENEWAL','VISITSRENEWAL','PLANPURCHASE')
 		GROUP BY MONTH(created_at)
 		ORDER BY MONTH(created_at)
 	`
@@ -759,7 +858,7 @@ func (service *UserService) GetMonthlyRevenue(userIdFromToken uuid.UUID, year in
 
 func (service *UserService) GetMonthlyUniqueUserTransactions(userIdFromToken uuid.UUID, year int) ([]stats.MonthlyStat, error) {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return nil, err
 	}
 
@@ -780,6 +879,7 @@ func (service *UserService) GetMonthlyUniqueUserTransactions(userIdFromToken uui
 		SELECT MONTH(created_at) as month, COUNT(DISTINCT user_id) as value
 		FROM transactions
 		WHERE YEAR(created_at) = ? AND deleted_at IS NULL
+		 AND type IN ('URLRENEWAL','VISITSRENEWAL','PLANPURCHASE')
 		GROUP BY MONTH(created_at)
 		ORDER BY MONTH(created_at)
 	`
@@ -949,7 +1049,7 @@ func (s *UserService) GetReportStats(userIdFromToken uuid.UUID, year int) ([]sta
 
 func (service *UserService) GetUserReportStats(userId, userIdFromToken uuid.UUID, year int) ([]stats.UserReportStats, error) {
 
-	if err := service.doesUserExist(userIdFromToken); err != nil {
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
 		return nil, err
 	}
 
@@ -970,15 +1070,15 @@ func (service *UserService) GetUserReportStats(userId, userIdFromToken uuid.UUID
 	}
 
 	// --- 1️⃣ Monthly Total Spending for renewals ---
-	var MonthlySpendingStats []stats.MonthlyStat
+	var MonthlySpendingStats []stats.MonthlyAmount
 	MonthlySpendingQuery := `
 SELECT 
     MONTH(created_at) AS month, 
-    SUM(amount) AS value
+    SUM(amount_paise) AS value
 FROM transactions 
 WHERE user_id = ?
   AND YEAR(created_at) = ?
-  AND type IN ('URLRENEWAL', 'VISITSRENEWAL')
+  AND type IN ('URLRENEWAL', 'VISITSRENEWAL', 'PLANPURCHASE')
   AND deleted_at IS NULL
 GROUP BY MONTH(created_at)
 ORDER BY MONTH(created_at)
@@ -1035,7 +1135,7 @@ ORDER BY MONTH(created_at)
 	// Add renewals
 	for _, r := range MonthlySpendingStats {
 		if stat, exists := statsMap[r.Month]; exists {
-			stat.MonthlySpending = int(r.Value)
+			stat.MonthlySpending = r.Value
 			statsMap[r.Month] = stat
 		}
 	}
@@ -1074,14 +1174,6 @@ func (service *UserService) doesEmailExists(email string) error {
 	return nil
 }
 
-func (service *UserService) doesUserExist(ID uuid.UUID) error {
-	var u user.User
-	if err := service.db.First(&u, "id = ?", ID).Error; err != nil {
-		return errors.NewValidationError("user Doesn't exists")
-	}
-	return nil
-}
-
 func hashPassword(password string) ([]byte, error) {
 	return bcrypt.GenerateFromPassword([]byte(password), cost)
 }
@@ -0,0 +1,43 @@
+package visitor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"time"
+	"url-shortner-be/components/config"
+)
+
+// Fingerprints returns the visitor hash for the current salt period and the one before it, so a
+// repeat visit just after the salt rotates is still recognised. Raw IPs never leave this function.
+//
+// The hash of a visitor changes every period on purpose, so nothing can link their visits over a longer
+// time. Unique visitor counts are therefore unique per salt period: a visitor who comes back in three
+// periods is counted three times when the sketches of those periods are merged.
+func Fingerprints(ip, userAgent string, now time.Time) (current, previous string) {
+	rotation := time.Duration(config.VisitorSaltRotationHours.GetInt64Value()) * time.Hour
+	if rotation <= 0 {
+		rotation = 24 * time.Hour
+	}
+
+	period := now.Unix() / int64(rotation.Seconds())
+	return fingerprint(ip, userAgent, period), fingerprint(ip, userAgent, period-1)
+}
+
+// Hash64 turns a fingerprint into the 64 bit value fed to the unique visitor sketches.
+func Hash64(fingerprint string) uint64 {
+	raw, err := hex.DecodeString(fingerprint)
+	if err != nil || len(raw) < 8 {
+		sum := sha256.Sum256([]byte(fingerprint))
+		raw = sum[:]
+	}
+	return binary.BigEndian.Uint64(raw[:8])
+}
+
+func fingerprint(ip, userAgent string, period int64) string {
+	mac := hmac.New(sha256.New, []byte(config.VisitorSalt.GetStringValue()+":"+strconv.FormatInt(period, 10)))
+	mac.Write([]byte(ip + "|" + userAgent))
+	return hex.EncodeToString(mac.Sum(nil))
+}
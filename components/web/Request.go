@@ -3,7 +3,11 @@ package web
 import (
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"url-shortner-be/components/config"
 	"url-shortner-be/components/errors"
 )
 
@@ -28,3 +32,66 @@ func UnmarshalJSON(request *http.Request, out interface{}) error {
 
 	return nil
 }
+
+// ClientIP returns the address of the client, X-Forwarded-For is only honoured when the
+// request came through one of the TRUSTED_PROXIES.
+func ClientIP(request *http.Request) string {
+	clientIP, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		clientIP = request.RemoteAddr
+	}
+
+	if !isTrustedProxy(clientIP) {
+		return clientIP
+	}
+
+	forwarded := strings.Split(request.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		candidate := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(candidate) == nil {
+			break
+		}
+		clientIP = candidate
+		if !isTrustedProxy(candidate) {
+			break
+		}
+	}
+	return clientIP
+}
+
+var (
+	trustedProxies     []*net.IPNet
+	trustedProxiesOnce sync.Once
+)
+
+func isTrustedProxy(address string) bool {
+	trustedProxiesOnce.Do(func() {
+		for _, entry := range strings.Split(config.TrustedProxies.GetStringValue(), ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			if !strings.Contains(entry, "/") {
+				if strings.Contains(entry, ":") {
+					entry += "/128"
+				} else {
+					entry += "/32"
+				}
+			}
+			if _, network, err := net.ParseCIDR(entry); err == nil {
+				trustedProxies = append(trustedProxies, network)
+			}
+		}
+	})
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"net/http/httptest"
+	"testing"
+	"url-shortner-be/components/config"
+)
+
+func TestClientIP(t *testing.T) {
+	// Trusted proxies are read once, so every case shares this configuration.
+	t.Setenv(string(config.TrustedProxies), "10.0.0.0/8, 192.168.1.1, ::1")
+	config.InitializeGlobalConfig(config.Environment("test"))
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor string
+		wantClientIP string
+	}{
+		{"direct client", "203.0.113.7:51000", "", "203.0.113.7"},
+		{"spoofed header from an untrusted client", "203.0.113.7:51000", "1.2.3.4", "203.0.113.7"},
+		{"client behind a trusted proxy", "10.0.0.5:443", "203.0.113.7", "203.0.113.7"},
+		{"spoofed entry before the one the proxy added", "10.0.0.5:443", "1.2.3.4, 203.0.113.7", "203.0.113.7"},
+		{"chain of trusted proxies", "10.0.0.5:443", "203.0.113.7, 192.168.1.1", "203.0.113.7"},
+		{"every hop trusted", "10.0.0.5:443", "10.1.1.1", "10.1.1.1"},
+		{"invalid forwarded address", "10.0.0.5:443", "not-an-ip", "10.0.0.5"},
+		{"trusted proxy without header", "10.0.0.5:443", "", "10.0.0.5"},
+		{"ipv6 proxy", "[::1]:443", "2001:db8::7", "2001:db8::7"},
+		{"ipv6 client", "[2001:db8::1]:443", "1.2.3.4", "2001:db8::1"},
+		{"remote address without port", "203.0.113.7", "1.2.3.4", "203.0.113.7"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/abcde", nil)
+			request.RemoteAddr = test.remoteAddr
+			if test.forwardedFor != "" {
+				request.Header.Set("X-Forwarded-For", test.forwardedFor)
+			}
+
+			if clientIP := ClientIP(request); clientIP != test.wantClientIP {
+				t.Errorf("expected client ip %s, got %s", test.wantClientIP, clientIP)
+			}
+		})
+	}
+}
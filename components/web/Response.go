@@ -57,3 +57,9 @@ func SetNewHeader(w http.ResponseWriter, headerName, value string) {
 	w.Header().Add("Access-Control-Expose-Headers", headerName)
 	w.Header().Set(headerName, value)
 }
+
+func RespondHTML(w http.ResponseWriter, code int, html string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write([]byte(html))
+}
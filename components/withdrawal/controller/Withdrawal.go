@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"net/http"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/security"
+	"url-shortner-be/components/web"
+	withdrawalService "url-shortner-be/components/withdrawal/service"
+	"url-shortner-be/model/withdrawal"
+
+	"github.com/gorilla/mux"
+)
+
+type WithdrawalController struct {
+	log               log.Logger
+	WithdrawalService *withdrawalService.WithdrawalService
+}
+
+func NewWithdrawalController(withdrawalService *withdrawalService.WithdrawalService, log log.Logger) *WithdrawalController {
+	return &WithdrawalController{
+		log:               log,
+		WithdrawalService: withdrawalService,
+	}
+}
+
+func (withdrawalController *WithdrawalController) RegisterRoutes(router *mux.Router) {
+
+	withdrawalRouter := router.PathPrefix("/withdrawals").Subrouter()
+	userguardedRouter := withdrawalRouter.PathPrefix("/").Subrouter()
+	adminguardedRouter := withdrawalRouter.PathPrefix("/").Subrouter()
+
+	userguardedRouter.HandleFunc("/mine", withdrawalController.getUserWithdrawals).Methods(http.MethodGet)
+
+	adminguardedRouter.HandleFunc("/", withdrawalController.getAllWithdrawals).Methods(http.MethodGet)
+	adminguardedRouter.HandleFunc("/{withdrawalId}/approve", withdrawalController.approveWithdrawal).Methods(http.MethodPost)
+	adminguardedRouter.HandleFunc("/{withdrawalId}/reject", withdrawalController.rejectWithdrawal).Methods(http.MethodPost)
+	adminguardedRouter.HandleFunc("/{withdrawalId}/payout", withdrawalController.retryPayout).Methods(http.MethodPost)
+
+	userguardedRouter.Use(security.MiddlewareUser)
+	adminguardedRouter.Use(security.MiddlewareAdmin)
+}
+
+func (controller *WithdrawalController) getUserWithdrawals(w http.ResponseWriter, r *http.Request) {
+	requests := []withdrawal.WithdrawalRequest{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err = controller.WithdrawalService.GetUserWithdrawals(&requests, &totalCount, parser, userIdFromToken); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, requests)
+}
+
+func (controller *WithdrawalController) getAllWithdrawals(w http.ResponseWriter, r *http.Request) {
+	requests := []withdrawal.WithdrawalRequest{}
+	var totalCount int
+	parser := web.NewParser(r)
+
+	if err := controller.WithdrawalService.GetAllWithdrawals(&requests, &totalCount, parser); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, requests)
+}
+
+func (controller *WithdrawalController) approveWithdrawal(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	withdrawalID, err := parser.GetUUID("withdrawalId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid withdrawal ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	request := &withdrawal.WithdrawalRequest{}
+	if err = controller.WithdrawalService.ApproveWithdrawal(withdrawalID, userIdFromToken, request); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	// The request stays APPROVED with a failureReason when the payout did not go through.
+	web.RespondJSON(w, http.StatusOK, request)
+}
+
+func (controller *WithdrawalController) rejectWithdrawal(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	withdrawalID, err := parser.GetUUID("withdrawalId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid withdrawal ID format"))
+		return
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err = web.UnmarshalJSON(r, &body); err != nil {
+		web.RespondError(w, errors.NewHTTPError("Unable to parse request body", http.StatusBadRequest))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	request := &withdrawal.WithdrawalRequest{}
+	if err = controller.WithdrawalService.RejectWithdrawal(withdrawalID, userIdFromToken, body.Reason, request); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, request)
+}
+
+func (controller *WithdrawalController) retryPayout(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	withdrawalID, err := parser.GetUUID("withdrawalId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid withdrawal ID format"))
+		return
+	}
+
+	userIdFromToken, err := security.ExtractUserIDFromToken(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	request := &withdrawal.WithdrawalRequest{}
+	if err = controller.WithdrawalService.RetryPayout(withdrawalID, userIdFromToken, request); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, request)
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"url-shortner-be/components/errors"
+	ledgerserv "url-shortner-be/components/ledger/service"
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
+	"url-shortner-be/components/payout"
+	transactionserv "url-shortner-be/components/transaction/service"
+	"url-shortner-be/components/web"
+	"url-shortner-be/model/ledger"
+	"url-shortner-be/model/user"
+	"url-shortner-be/model/withdrawal"
+	"url-shortner-be/module/repository"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+const payoutTimeout = 30 * time.Second
+
+type WithdrawalService struct {
+	db                 *gorm.DB
+	repository         repository.Repository
+	provider           payout.Provider
+	ledgerservice      *ledgerserv.LedgerService
+	transactionservice *transactionserv.TransactionService
+}
+
+func NewWithdrawalService(DB *gorm.DB, repo repository.Repository, provider payout.Provider) *WithdrawalService {
+	return &WithdrawalService{
+		db:                 DB,
+		repository:         repo,
+		provider:           provider,
+		ledgerservice:      ledgerserv.NewLedgerService(DB, repo),
+		transactionservice: transactionserv.NewTransactionService(DB, repo),
+	}
+}
+
+// RequestWithdrawal holds the amount from the user's wallet until an admin approves or rejects the request.
+func (service *WithdrawalService) RequestWithdrawal(userID uuid.UUID, request *withdrawal.WithdrawalRequest) error {
+
+	if err := request.Validate(); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	var dbUser user.User
+	if err := service.repository.GetRecord(uow, &dbUser, repository.Filter("id = ?", userID), repository.ForUpdate()); err != nil {
+		return errors.NewNotFoundError("User not found")
+	}
+
+	if !*dbUser.IsActive {
+		return errors.NewValidationError("Inactive users cannot withdraw money")
+	}
+
+	if request.Amount > dbUser.Wallet {
+		return errors.NewValidationError("insufficient balance to withdraw")
+	}
+
+	request.ID = uuid.NewV4()
+	request.UserID = userID
+	request.Currency = money.CurrencyINR
+	request.Status = withdrawal.StatusRequested
+	request.PayoutProvider = ""
+	request.PayoutReference = ""
+	request.RejectionReason = ""
+	request.FailureReason = ""
+	request.ReviewedBy = nil
+	request.ReviewedAt = nil
+	request.PaidAt = nil
+	request.CreatedBy = userID
+
+	if err := service.repository.Add(uow, request); err != nil {
+		return errors.NewDatabaseError("unable to save withdrawal request")
+	}
+
+	var note = fmt.Sprintf("%s held for withdrawal", request.Amount)
+
+	if err := service.ledgerservice.PostWalletEntry(uow, userID, -request.Amount, ledger.AccountWithdrawalsHeld, ledger.EntryTypeWithdrawalHold, note); err != nil {
+		return err
+	}
+
+	if err := service.transactionservice.CreateTransaction(uow, userID, request.Amount, ledger.EntryTypeWithdrawalHold, note); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *WithdrawalService) GetUserWithdrawals(requests *[]withdrawal.WithdrawalRequest, totalCount *int, parser *web.Parser, userIdFromToken uuid.UUID) error {
+
+	if err := user.Exists(service.db, userIdFromToken); err != nil {
+		return err
+	}
+
+	limit, offset := parser.ParseLimitAndOffset()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetAll(uow, requests,
+		repository.Filter("user_id = ?", userIdFromToken),
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("created_at desc"),
+	); err != nil {
+		return errors.NewDatabaseError("unable to fetch withdrawal requests")
+	}
+
+	return nil
+}
+
+// GetAllWithdrawals lists the withdrawal requests of every user, optionally filtered by status and userId.
+func (service *WithdrawalService) GetAllWithdrawals(requests *[]withdrawal.WithdrawalRequest, totalCount *int, parser *web.Parser) error {
+
+	var queryProcessors []repository.QueryProcessor
+
+	if status := parser.Form.Get("status"); status != "" {
+		queryProcessors = append(queryProcessors, repository.Filter("status = ?", status))
+	}
+
+	if userID := parser.Form.Get("userId"); userID != "" {
+		id, err := web.ParseUUID(userID)
+		if err != nil {
+			return errors.NewValidationError("Invalid user ID format")
+		}
+		queryProcessors = append(queryProcessors, repository.Filter("user_id = ?", id))
+	}
+
+	limit, offset := parser.ParseLimitAndOffset()
+	queryProcessors = append(queryProcessors,
+		repository.Paginate(limit, offset, totalCount),
+		repository.Order("created_at desc"),
+	)
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetAll(uow, requests, queryProcessors...); err != nil {
+		return errors.NewDatabaseError("unable to fetch withdrawal requests")
+	}
+
+	return nil
+}
+
+// ApproveWithdrawal moves the held amount to pending payouts and sends it through the payout provider.
+// A failed payout leaves the request approved with the failure recorded so that it can be retried.
+func (service *WithdrawalService) ApproveWithdrawal(withdrawalID, adminID uuid.UUID, request *withdrawal.WithdrawalRequest) error {
+
+	if err := user.Exists(service.db, adminID); err != nil {
+		return err
+	}
+
+	if err := service.checkProvider(); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	if err := service.lockWithdrawal(uow, withdrawalID, request); err != nil {
+		return err
+	}
+
+	if request.Status != withdrawal.StatusRequested {
+		return errors.NewValidationError("only requested withdrawals can be approved")
+	}
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, request, map[string]interface{}{
+		"status":      withdrawal.StatusApproved,
+		"reviewed_by": adminID,
+		"reviewed_at": now,
+		"updated_by":  adminID,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update withdrawal request")
+	}
+	request.Status = withdrawal.StatusApproved
+	request.ReviewedBy = &adminID
+	request.ReviewedAt = &now
+
+	var note = fmt.Sprintf("%s withdrawal approved", request.Amount)
+
+	if err := service.ledgerservice.PostSystemEntry(uow, ledger.AccountWithdrawalsHeld, ledger.AccountPayoutsPending,
+		request.Amount, ledger.EntryTypeWithdrawalApproval, note, adminID); err != nil {
+		return err
+	}
+
+	if err := service.transactionservice.CreateTransaction(uow, request.UserID, request.Amount, ledger.EntryTypeWithdrawalApproval, note); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
+	}
+
+	if err := service.payout(uow, request, adminID); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// RetryPayout sends an approved withdrawal whose earlier payout failed through the payout provider again.
+func (service *WithdrawalService) RetryPayout(withdrawalID, adminID uuid.UUID, request *withdrawal.WithdrawalRequest) error {
+
+	if err := user.Exists(service.db, adminID); err != nil {
+		return err
+	}
+
+	if err := service.checkProvider(); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	if err := service.lockWithdrawal(uow, withdrawalID, request); err != nil {
+		return err
+	}
+
+	if request.Status != withdrawal.StatusApproved {
+		return errors.NewValidationError("only approved withdrawals can be paid out")
+	}
+
+	if err := service.payout(uow, request, adminID); err != nil {
+		return err
+	}
+
+	uow.Commit()
+
+	if request.Status != withdrawal.StatusPaid {
+		return errors.NewHTTPError("payout failed: "+request.FailureReason, http.StatusBadGateway)
+	}
+	return nil
+}
+
+// RejectWithdrawal releases the amount of a requested or approved but unpaid withdrawal back to the wallet.
+func (service *WithdrawalService) RejectWithdrawal(withdrawalID, adminID uuid.UUID, reason string, request *withdrawal.WithdrawalRequest) error {
+
+	if reason == "" {
+		return errors.NewValidationError("rejection reason is required")
+	}
+
+	if len(reason) > 255 {
+		return errors.NewValidationError("rejection reason must not be longer than 255 characters")
+	}
+
+	if err := user.Exists(service.db, adminID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	if err := service.lockWithdrawal(uow, withdrawalID, request); err != nil {
+		return err
+	}
+
+	var heldIn string
+	switch request.Status {
+	case withdrawal.StatusRequested:
+		heldIn = ledger.AccountWithdrawalsHeld
+	case withdrawal.StatusApproved:
+		heldIn = ledger.AccountPayoutsPending
+	default:
+		return errors.NewValidationError("only requested or approved withdrawals can be rejected")
+	}
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, request, map[string]interface{}{
+		"status":           withdrawal.StatusRejected,
+		"rejection_reason": reason,
+		"reviewed_by":      adminID,
+		"reviewed_at":      now,
+		"updated_by":       adminID,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update withdrawal request")
+	}
+	request.Status = withdrawal.StatusRejected
+	request.RejectionReason = reason
+	request.ReviewedBy = &adminID
+	request.ReviewedAt = &now
+
+	var note = fmt.Sprintf("%s released back to the wallet", request.Amount)
+
+	if err := service.ledgerservice.PostWalletEntry(uow, request.UserID, request.Amount, heldIn, ledger.EntryTypeWithdrawalRelease, note); err != nil {
+		return err
+	}
+
+	if err := service.transactionservice.CreateTransaction(uow, request.UserID, request.Amount, ledger.EntryTypeWithdrawalRelease, note); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// ---------------- Helpers ----------------
+
+// checkProvider refuses to approve or pay out withdrawals when no payout provider is configured.
+func (service *WithdrawalService) checkProvider() error {
+	if service.provider == nil {
+		return errors.NewHTTPError("no payout provider is configured, withdrawals cannot be paid out", http.StatusServiceUnavailable)
+	}
+	return nil
+}
+
+// payout sends an approved withdrawal through the provider. The withdrawal id is the payout reference,
+// so a provider that already paid it out on an earlier attempt does not pay it twice.
+func (service *WithdrawalService) payout(uow *repository.UnitOfWork, request *withdrawal.WithdrawalRequest, adminID uuid.UUID) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), payoutTimeout)
+	defer cancel()
+
+	reference, err := service.provider.Send(ctx, &payout.Payout{
+		ReferenceID:       request.ID.String(),
+		Amount:            request.Amount,
+		Currency:          request.Currency,
+		AccountHolderName: request.AccountHolderName,
+		AccountNumber:     request.AccountNumber,
+		IFSC:              request.IFSC,
+		UpiID:             request.UpiID,
+	})
+	if err != nil {
+		log.GetLogger().Error("payout of withdrawal ", request.ID.String(), " failed: ", err.Error())
+		request.PayoutProvider = service.provider.Name()
+		request.FailureReason = err.Error()
+		if len(request.FailureReason) > 255 {
+			request.FailureReason = request.FailureReason[:255]
+		}
+		if err := service.repository.UpdateWithMap(uow, request, map[string]interface{}{
+			"payout_provider": request.PayoutProvider,
+			"failure_reason":  request.FailureReason,
+			"updated_by":      adminID,
+		}); err != nil {
+			return errors.NewDatabaseError("unable to update withdrawal request")
+		}
+		return nil
+	}
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, request, map[string]interface{}{
+		"status":           withdrawal.StatusPaid,
+		"payout_provider":  service.provider.Name(),
+		"payout_reference": reference,
+		"failure_reason":   "",
+		"paid_at":          now,
+		"updated_by":       adminID,
+	}); err != nil {
+		return errors.NewDatabaseError("unable to update withdrawal request")
+	}
+	request.Status = withdrawal.StatusPaid
+	request.PayoutProvider = service.provider.Name()
+	request.PayoutReference = reference
+	request.FailureReason = ""
+	request.PaidAt = &now
+
+	var note = fmt.Sprintf("%s paid out for withdrawal", request.Amount)
+
+	if err := service.ledgerservice.PostSystemEntry(uow, ledger.AccountPayoutsPending, ledger.AccountFunding,
+		request.Amount, ledger.EntryTypeWithdrawalPayout, note, adminID); err != nil {
+		return err
+	}
+
+	if err := service.transactionservice.CreateTransaction(uow, request.UserID, request.Amount, ledger.EntryTypeWithdrawalPayout, note); err != nil {
+		return errors.NewDatabaseError("unable to create transaction")
+	}
+	return nil
+}
+
+func (service *WithdrawalService) lockWithdrawal(uow *repository.UnitOfWork, withdrawalID uuid.UUID, request *withdrawal.WithdrawalRequest) error {
+	if err := service.repository.GetRecordByID(uow, withdrawalID, request, repository.ForUpdate()); err != nil {
+		return errors.NewNotFoundError("withdrawal request not found")
+	}
+	return nil
+}
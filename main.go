@@ -50,6 +50,7 @@ func main() {
 	app.Log.Print("Server Started")
 
 	module.Configure(app)
+	module.StartJobs(app, repository)
 
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
@@ -0,0 +1,28 @@
+package click
+
+import (
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type Click struct {
+	model.Base
+	UrlID     uuid.UUID `json:"urlId" gorm:"not null;type:varchar(36);index"`
+	IsBot     *bool     `json:"isBot" gorm:"type:tinyint(1);default:false"`
+	UserAgent string    `json:"userAgent" gorm:"type:varchar(255)"`
+	Referer   string    `json:"referer" gorm:"type:varchar(255)"`
+	Host      string    `json:"host" gorm:"type:varchar(255)"`
+
+	// VisitorHash is a salted, rotating hash of the client IP and user agent, raw IPs are never stored.
+	VisitorHash string `json:"-" gorm:"type:varchar(64);index"`
+	IsRepeat    *bool  `json:"isRepeat" gorm:"type:tinyint(1);default:false"`
+
+	CountryCode string `json:"countryCode" gorm:"type:varchar(2);index"`
+	Region      string `json:"region" gorm:"type:varchar(100)"`
+	City        string `json:"city" gorm:"type:varchar(100)"`
+
+	ClientIP string `json:"-" gorm:"-"`
+	// IsRange is set for requests with a Range header, which may be continuing a download already counted.
+	IsRange bool `json:"-" gorm:"-"`
+}
@@ -0,0 +1,45 @@
+package click
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type ClickModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewClickModuleConfig(db *gorm.DB) *ClickModuleConfig {
+	return &ClickModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *ClickModuleConfig) MigrateTables() {
+
+	model := &Click{}
+	sketchModel := &VisitorSketch{}
+
+	err := c.DB.AutoMigrate(model, sketchModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Click ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddForeignKey("url_id", "urls(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Click ==> %s", err)
+	}
+
+	err = c.DB.Model(sketchModel).AddForeignKey("url_id", "urls(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Visitor Sketch ==> %s", err)
+	}
+
+	err = c.DB.Model(sketchModel).AddUniqueIndex("idx_visitor_sketch_url_day", "url_id", "day").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Visitor Sketch ==> %s", err)
+	}
+
+	log.GetLogger().Print("Click Module Configured.")
+}
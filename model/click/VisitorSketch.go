@@ -0,0 +1,15 @@
+package click
+
+import (
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// VisitorSketch holds the HyperLogLog registers of one url's visitors for one day.
+type VisitorSketch struct {
+	model.Base
+	UrlID     uuid.UUID `json:"urlId" gorm:"not null;type:varchar(36)"`
+	Day       string    `json:"day" gorm:"not null;type:varchar(10)"`
+	Registers []byte    `json:"-" gorm:"type:blob"`
+}
@@ -0,0 +1,156 @@
+package coupon
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/money"
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	KindPercentage = "PERCENTAGE"
+	KindFixed      = "FIXED"
+	KindBonus      = "BONUS"
+)
+
+// Purchases a coupon can be applied to, the renewals use the same names as their transactions.
+const (
+	TypeUrlRenewal    = "URLRENEWAL"
+	TypeVisitsRenewal = "VISITSRENEWAL"
+	TypeSignup        = "SIGNUP"
+)
+
+var codePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,20}$`)
+
+// Coupon is a code an admin hands out for a discount on a purchase or for bonus urls and visits on top of it.
+type Coupon struct {
+	model.Base
+	Code        string `json:"code" gorm:"not null;type:varchar(20)"`
+	Description string `json:"description" gorm:"type:varchar(255)"`
+	Kind        string `json:"kind" gorm:"not null;type:varchar(20)" example:"PERCENTAGE/FIXED/BONUS"`
+	// PercentOff is the discount of a PERCENTAGE coupon, AmountOff of a FIXED one and
+	// BonusQuantity the free urls or visits a BONUS coupon adds to the purchase.
+	PercentOff    int         `json:"percentOff" gorm:"type:int;not null;default:0"`
+	AmountOff     money.Money `json:"amountOff" gorm:"column:amount_off_paise;type:bigint;not null;default:0"`
+	BonusQuantity int         `json:"bonusQuantity" gorm:"type:int;not null;default:0"`
+	// AppliesTo is a comma separated list of the purchases the coupon can be used for.
+	AppliesTo  string     `json:"appliesTo" gorm:"not null;type:varchar(100)" example:"URLRENEWAL,VISITSRENEWAL,SIGNUP"`
+	ValidFrom  *time.Time `json:"validFrom"`
+	ValidUntil *time.Time `json:"validUntil"`
+	// Redemption limits, zero means unlimited.
+	MaxRedemptions        int   `json:"maxRedemptions" gorm:"type:int;not null;default:0"`
+	MaxRedemptionsPerUser int   `json:"maxRedemptionsPerUser" gorm:"type:int;not null;default:0"`
+	RedemptionCount       int   `json:"redemptionCount" gorm:"type:int;not null;default:0"`
+	IsActive              *bool `json:"isActive" gorm:"type:tinyint(1);default:true"`
+}
+
+// CouponRedemption records a coupon used by a user and what it gave them. TransactionID is the
+// purchase it was applied to, signups have none.
+type CouponRedemption struct {
+	model.Base
+	CouponID      uuid.UUID   `json:"couponId" gorm:"not null;type:varchar(36);index"`
+	Code          string      `json:"code" gorm:"not null;type:varchar(20)"`
+	UserID        uuid.UUID   `json:"userId" gorm:"not null;type:varchar(36);index"`
+	TransactionID *uuid.UUID  `json:"transactionId" gorm:"type:varchar(36)"`
+	Type          string      `json:"type" gorm:"not null;type:varchar(20)"`
+	Discount      money.Money `json:"discount" gorm:"column:discount_paise;type:bigint;not null;default:0"`
+	BonusQuantity int         `json:"bonusQuantity" gorm:"type:int;not null;default:0"`
+}
+
+// CouponReport sums what a coupon has given away.
+type CouponReport struct {
+	CouponID      uuid.UUID   `json:"couponId"`
+	Code          string      `json:"code"`
+	Kind          string      `json:"kind"`
+	Redemptions   int         `json:"redemptions"`
+	Discount      money.Money `json:"discount"`
+	BonusQuantity int         `json:"bonusQuantity"`
+}
+
+func (coupon *Coupon) Validate() error {
+	if !codePattern.MatchString(coupon.Code) {
+		return errors.NewValidationError("coupon code must be 3 to 20 letters, digits, '-' or '_'")
+	}
+
+	switch coupon.Kind {
+	case KindPercentage:
+		if coupon.PercentOff < 1 || coupon.PercentOff > 100 {
+			return errors.NewValidationError("percent off must be between 1 and 100")
+		}
+	case KindFixed:
+		if coupon.AmountOff <= 0 {
+			return errors.NewValidationError("amount off must be greater than zero")
+		}
+	case KindBonus:
+		if coupon.BonusQuantity <= 0 {
+			return errors.NewValidationError("bonus quantity must be greater than zero")
+		}
+	default:
+		return errors.NewValidationError("coupon kind must be PERCENTAGE, FIXED or BONUS")
+	}
+
+	types := coupon.Types()
+	if len(types) == 0 {
+		return errors.NewValidationError("coupon must apply to at least one of URLRENEWAL, VISITSRENEWAL or SIGNUP")
+	}
+	for _, purchaseType := range types {
+		switch purchaseType {
+		case TypeUrlRenewal, TypeVisitsRenewal:
+		case TypeSignup:
+			// Nothing is paid at signup, so there is nothing to discount.
+			if coupon.Kind != KindBonus {
+				return errors.NewValidationError("only BONUS coupons can apply to SIGNUP")
+			}
+		default:
+			return errors.NewValidationError("coupon must apply to at least one of URLRENEWAL, VISITSRENEWAL or SIGNUP")
+		}
+	}
+
+	if coupon.ValidFrom != nil && coupon.ValidUntil != nil && !coupon.ValidUntil.After(*coupon.ValidFrom) {
+		return errors.NewValidationError("coupon must be valid until after it is valid from")
+	}
+	if coupon.MaxRedemptions < 0 || coupon.MaxRedemptionsPerUser < 0 {
+		return errors.NewValidationError("redemption limits must not be negative")
+	}
+	return nil
+}
+
+// Types lists the purchases the coupon applies to.
+func (coupon *Coupon) Types() []string {
+	types := []string{}
+	for _, purchaseType := range strings.Split(coupon.AppliesTo, ",") {
+		if purchaseType = strings.TrimSpace(purchaseType); purchaseType != "" {
+			types = append(types, purchaseType)
+		}
+	}
+	return types
+}
+
+func (coupon *Coupon) AppliesToType(purchaseType string) bool {
+	for _, applicable := range coupon.Types() {
+		if applicable == purchaseType {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscountOn is the discount the coupon gives on price, never more than the price itself.
+// Percentages round half up to the paisa.
+func (coupon *Coupon) DiscountOn(price money.Money) money.Money {
+	var discount money.Money
+	switch coupon.Kind {
+	case KindPercentage:
+		discount = (price*money.Money(coupon.PercentOff) + 50) / 100
+	case KindFixed:
+		discount = coupon.AmountOff
+	}
+	if discount > price {
+		return price
+	}
+	return discount
+}
@@ -0,0 +1,50 @@
+package coupon
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type CouponModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewCouponModuleConfig(db *gorm.DB) *CouponModuleConfig {
+	return &CouponModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *CouponModuleConfig) MigrateTables() {
+
+	couponModel := &Coupon{}
+	redemptionModel := &CouponRedemption{}
+
+	err := c.DB.AutoMigrate(couponModel, redemptionModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Coupon ==> %s", err)
+	}
+
+	err = c.DB.Model(couponModel).AddUniqueIndex("idx_coupon_code", "code").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Coupon ==> %s", err)
+	}
+
+	err = c.DB.Model(redemptionModel).AddForeignKey("coupon_id", "coupons(id)", "RESTRICT", "RESTRICT").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Coupon Redemption ==> %s", err)
+	}
+
+	err = c.DB.Model(redemptionModel).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Coupon Redemption ==> %s", err)
+	}
+
+	err = c.DB.Model(redemptionModel).AddForeignKey("transaction_id", "transactions(id)", "RESTRICT", "RESTRICT").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Coupon Redemption ==> %s", err)
+	}
+
+	log.GetLogger().Print("Coupon Module Configured.")
+}
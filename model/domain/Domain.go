@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"url-shortner-be/components/errors"
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// VerificationPrefix is the label under which the owner publishes the TXT record.
+	VerificationPrefix = "_url-shortner-verify."
+	// VerificationValuePrefix precedes the token in the TXT record value.
+	VerificationValuePrefix = "url-shortner-verification="
+)
+
+var hostPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// Domain is a custom host owned by a user, short urls created on it are resolved only through that host.
+type Domain struct {
+	model.Base
+	UserID            uuid.UUID  `json:"userId" gorm:"not null;type:varchar(36)"`
+	Host              string     `json:"host" gorm:"not null;type:varchar(255)"`
+	VerificationToken string     `json:"verificationToken" gorm:"not null;type:varchar(64)"`
+	IsVerified        *bool      `json:"isVerified" gorm:"type:tinyint(1);default:false"`
+	VerifiedAt        *time.Time `json:"verifiedAt"`
+	// VerifiedHost is only set once the domain is verified, so unverified claims of a host never block its owner.
+	VerifiedHost *string `json:"-" gorm:"type:varchar(255)"`
+
+	VerificationRecord string `json:"verificationRecord" gorm:"-"`
+	VerificationValue  string `json:"verificationValue" gorm:"-"`
+}
+
+func (d *Domain) Validate() error {
+	d.Host = NormalizeHost(d.Host)
+	if len(d.Host) > 253 || !hostPattern.MatchString(d.Host) {
+		return errors.NewValidationError("Host must be a valid domain name like go.example.com")
+	}
+	return nil
+}
+
+// SetVerificationDetails fills in the TXT record the owner has to publish.
+func (d *Domain) SetVerificationDetails() {
+	d.VerificationRecord = VerificationPrefix + d.Host
+	d.VerificationValue = VerificationValuePrefix + d.VerificationToken
+}
+
+// NormalizeHost lowercases a host and strips the port and trailing dot.
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.LastIndex(host, ":"); i != -1 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
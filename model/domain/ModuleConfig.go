@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type DomainModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewDomainModuleConfig(db *gorm.DB) *DomainModuleConfig {
+	return &DomainModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *DomainModuleConfig) MigrateTables() {
+
+	model := &Domain{}
+
+	err := c.DB.AutoMigrate(model).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Domain ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Domain ==> %s", err)
+	}
+
+	// A host is only reserved by the user who verified it, anyone may claim it until then.
+	err = c.DB.Model(model).RemoveIndex("idx_domain_host").Error
+	if err != nil {
+		log.GetLogger().Print("Removing Unique Index Of Domain ==> %s", err)
+	}
+
+	err = c.DB.Exec("UPDATE domains SET verified_host = host WHERE is_verified = true AND deleted_at IS NULL AND verified_host IS NULL").Error
+	if err != nil {
+		log.GetLogger().Print("Backfilling Verified Host Of Domain ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddUniqueIndex("idx_domain_verified_host", "verified_host").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Domain ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddUniqueIndex("idx_domain_user_host", "user_id", "host").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Domain ==> %s", err)
+	}
+
+	log.GetLogger().Print("Domain Module Configured.")
+}
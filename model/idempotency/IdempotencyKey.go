@@ -0,0 +1,24 @@
+package idempotency
+
+import (
+	"time"
+	model "url-shortner-be/model/general"
+)
+
+// IdempotencyKey remembers the response of a money moving request so that a retry with the
+// same Idempotency-Key header gets the stored response instead of running the request again.
+type IdempotencyKey struct {
+	model.Base
+	Scope        string     `json:"scope" gorm:"not null;type:varchar(100)"`
+	Key          string     `json:"key" gorm:"column:idempotency_key;not null;type:varchar(255)"`
+	RequestHash  string     `json:"requestHash" gorm:"not null;type:char(64)"`
+	StatusCode   int        `json:"statusCode" gorm:"type:int;default:0"`
+	ContentType  string     `json:"contentType" gorm:"type:varchar(100)"`
+	ResponseBody string     `json:"responseBody" gorm:"type:mediumtext"`
+	CompletedAt  *time.Time `json:"completedAt"`
+	ExpiresAt    time.Time  `json:"expiresAt" gorm:"index"`
+}
+
+func (k *IdempotencyKey) IsCompleted() bool {
+	return k.CompletedAt != nil
+}
@@ -0,0 +1,34 @@
+package idempotency
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type IdempotencyModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewIdempotencyModuleConfig(db *gorm.DB) *IdempotencyModuleConfig {
+	return &IdempotencyModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *IdempotencyModuleConfig) MigrateTables() {
+
+	model := &IdempotencyKey{}
+
+	err := c.DB.AutoMigrate(model).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Idempotency Key ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddUniqueIndex("idx_idempotency_scope_key", "scope", "idempotency_key").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Idempotency Key ==> %s", err)
+	}
+
+	log.GetLogger().Print("Idempotency Module Configured.")
+}
@@ -0,0 +1,122 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"url-shortner-be/components/money"
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var ErrInvoiceImmutable = errors.New("issued invoices cannot be changed")
+
+// Invoice is the tax invoice of a purchase. Seller and buyer details are copied in when it is
+// issued, so that an invoice always renders the same however the user or seller details change later.
+type Invoice struct {
+	model.Base
+	Number        string    `json:"number" gorm:"not null;type:varchar(30)"`
+	UserID        uuid.UUID `json:"userId" gorm:"not null;type:varchar(36);index"`
+	TransactionID uuid.UUID `json:"transactionId" gorm:"not null;type:varchar(36)"`
+	Type          string    `json:"type" gorm:"not null;type:varchar(36)" example:"URLRENEWAL/VISITSRENEWAL"`
+	IssuedAt      time.Time `json:"issuedAt"`
+	SellerName    string    `json:"sellerName" gorm:"type:varchar(100)"`
+	SellerAddress string    `json:"sellerAddress" gorm:"type:varchar(255)"`
+	SellerGSTIN   string    `json:"sellerGstin" gorm:"column:seller_gstin;type:varchar(15)"`
+	SellerState   string    `json:"sellerState" gorm:"type:varchar(50)"`
+	BuyerName     string    `json:"buyerName" gorm:"type:varchar(100)"`
+	BuyerEmail    string    `json:"buyerEmail" gorm:"type:varchar(100)"`
+	BuyerPhone    string    `json:"buyerPhone" gorm:"type:varchar(15)"`
+	BuyerState    string    `json:"buyerState" gorm:"type:varchar(50)"`
+	BuyerGSTIN    string    `json:"buyerGstin" gorm:"column:buyer_gstin;type:varchar(15)"`
+	PlaceOfSupply string    `json:"placeOfSupply" gorm:"type:varchar(50)"`
+	Currency      string    `json:"currency" gorm:"type:varchar(3);default:'INR'"`
+	// Subtotal is the taxable value, with tax inclusive prices it is less than the sum of the lines.
+	Subtotal money.Money    `json:"subtotal" gorm:"column:subtotal_paise;type:bigint;not null;default:0"`
+	TaxTotal money.Money    `json:"taxTotal" gorm:"column:tax_total_paise;type:bigint;not null;default:0"`
+	Total    money.Money    `json:"total" gorm:"column:total_paise;type:bigint;not null;default:0"`
+	Lines    []*InvoiceLine `json:"lines" gorm:"foreignKey:InvoiceID"`
+	Taxes    []*InvoiceTax  `json:"taxes" gorm:"foreignKey:InvoiceID"`
+}
+
+// InvoiceLine is one item bought, its amount is before tax.
+type InvoiceLine struct {
+	model.Base
+	InvoiceID   uuid.UUID   `json:"invoiceId" gorm:"not null;type:varchar(36);index"`
+	Description string      `json:"description" gorm:"not null;type:varchar(255)"`
+	SAC         string      `json:"sac" gorm:"column:sac;type:varchar(10)"`
+	Quantity    int         `json:"quantity" gorm:"type:int"`
+	UnitPrice   money.Money `json:"unitPrice" gorm:"column:unit_price_paise;type:bigint;not null;default:0"`
+	Amount      money.Money `json:"amount" gorm:"column:amount_paise;type:bigint;not null;default:0"`
+}
+
+// InvoiceTax is one tax charged on the invoice, e.g. CGST or SGST.
+type InvoiceTax struct {
+	model.Base
+	InvoiceID uuid.UUID `json:"invoiceId" gorm:"not null;type:varchar(36);index"`
+	Name      string    `json:"name" gorm:"not null;type:varchar(20)"`
+	// RateBasisPoints is the rate in hundredths of a percent, 900 is 9%.
+	RateBasisPoints int         `json:"rateBasisPoints" gorm:"type:int"`
+	Amount          money.Money `json:"amount" gorm:"column:amount_paise;type:bigint;not null;default:0"`
+}
+
+// InvoiceSequence hands out invoice numbers of one series without gaps.
+type InvoiceSequence struct {
+	Series     string `gorm:"primary_key;type:varchar(20)"`
+	LastNumber int    `gorm:"type:int;not null;default:0"`
+}
+
+func (*Invoice) BeforeUpdate() error {
+	return ErrInvoiceImmutable
+}
+
+func (*Invoice) BeforeDelete() error {
+	return ErrInvoiceImmutable
+}
+
+func (*InvoiceLine) BeforeUpdate() error {
+	return ErrInvoiceImmutable
+}
+
+func (*InvoiceLine) BeforeDelete() error {
+	return ErrInvoiceImmutable
+}
+
+func (*InvoiceTax) BeforeUpdate() error {
+	return ErrInvoiceImmutable
+}
+
+func (*InvoiceTax) BeforeDelete() error {
+	return ErrInvoiceImmutable
+}
+
+func NewLine(description string, quantity int, unitPrice money.Money) *InvoiceLine {
+	return &InvoiceLine{
+		Description: description,
+		Quantity:    quantity,
+		UnitPrice:   unitPrice,
+		Amount:      unitPrice.Mul(quantity),
+	}
+}
+
+// Rate formats the tax rate as a percentage, e.g. "9%" or "2.5%".
+func (tax *InvoiceTax) Rate() string {
+	rate := fmt.Sprintf("%d.%02d", tax.RateBasisPoints/100, tax.RateBasisPoints%100)
+	return strings.TrimSuffix(strings.TrimRight(rate, "0"), ".") + "%"
+}
+
+// Series returns the numbering series of a date, invoices are numbered per Indian financial year (April to March).
+func Series(date time.Time) string {
+	year := date.Year()
+	if date.Month() < time.April {
+		year--
+	}
+	return fmt.Sprintf("INV/%d-%02d", year, (year+1)%100)
+}
+
+// FormatNumber returns the invoice number of the sequence number in the series, e.g. INV/2026-27/000042.
+func FormatNumber(series string, number int) string {
+	return fmt.Sprintf("%s/%06d", series, number)
+}
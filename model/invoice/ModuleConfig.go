@@ -0,0 +1,62 @@
+package invoice
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type InvoiceModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewInvoiceModuleConfig(db *gorm.DB) *InvoiceModuleConfig {
+	return &InvoiceModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *InvoiceModuleConfig) MigrateTables() {
+
+	invoiceModel := &Invoice{}
+	lineModel := &InvoiceLine{}
+	taxModel := &InvoiceTax{}
+	sequenceModel := &InvoiceSequence{}
+
+	err := c.DB.AutoMigrate(invoiceModel, lineModel, taxModel, sequenceModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Invoice ==> %s", err)
+	}
+
+	err = c.DB.Model(invoiceModel).AddForeignKey("user_id", "users(id)", "RESTRICT", "RESTRICT").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Invoice ==> %s", err)
+	}
+
+	err = c.DB.Model(invoiceModel).AddForeignKey("transaction_id", "transactions(id)", "RESTRICT", "RESTRICT").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Invoice ==> %s", err)
+	}
+
+	err = c.DB.Model(lineModel).AddForeignKey("invoice_id", "invoices(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Invoice Line ==> %s", err)
+	}
+
+	err = c.DB.Model(taxModel).AddForeignKey("invoice_id", "invoices(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Invoice Tax ==> %s", err)
+	}
+
+	err = c.DB.Model(invoiceModel).AddUniqueIndex("idx_invoice_number", "number").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Invoice ==> %s", err)
+	}
+
+	err = c.DB.Model(invoiceModel).AddUniqueIndex("idx_invoice_transaction", "transaction_id").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Invoice ==> %s", err)
+	}
+
+	log.GetLogger().Print("Invoice Module Configured.")
+}
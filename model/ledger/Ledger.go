@@ -0,0 +1,145 @@
+package ledger
+
+import (
+	"time"
+	"url-shortner-be/components/money"
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	AccountTypeWallet = "WALLET"
+	AccountTypeSystem = "SYSTEM"
+
+	// Money paid in from or out to the outside world, e.g. wallet top ups and withdrawals.
+	AccountFunding = "system:funding"
+	// Money earned by the platform from renewals.
+	AccountRevenue = "system:revenue"
+	// Counterpart of the balances that existed before the ledger was introduced.
+	AccountOpeningBalance = "system:opening-balance"
+	// Money taken out of wallets for withdrawals that have not been paid out or rejected yet.
+	AccountWithdrawalsHeld = "system:withdrawals-held"
+	// Money of approved withdrawals waiting for the payout provider.
+	AccountPayoutsPending = "system:payouts-pending"
+	// GST collected on purchases that is owed to the government.
+	AccountTaxPayable = "system:tax-payable"
+	// Wallet credit given away as referral rewards.
+	AccountReferralRewards = "system:referral-rewards"
+
+	EntryTypeOpeningBalance = "OPENING_BALANCE"
+	EntryTypeCredit         = "CREDIT"
+	EntryTypeDebit          = "DEBIT"
+	EntryTypeUrlRenewal     = "URLRENEWAL"
+	EntryTypeVisitsRenewal  = "VISITSRENEWAL"
+	EntryTypeRefund         = "REFUND"
+
+	EntryTypeWithdrawalHold     = "WITHDRAWAL_HOLD"
+	EntryTypeWithdrawalApproval = "WITHDRAWAL_APPROVAL"
+	EntryTypeWithdrawalRelease  = "WITHDRAWAL_RELEASE"
+	EntryTypeWithdrawalPayout   = "WITHDRAWAL_PAYOUT"
+
+	EntryTypeReferralReward = "REFERRAL_REWARD"
+	EntryTypePlanPurchase   = "PLANPURCHASE"
+)
+
+// Account holds money, its balance is the sum of its postings.
+type Account struct {
+	model.Base
+	Code     string     `json:"code" gorm:"not null;type:varchar(60)"`
+	Type     string     `json:"type" gorm:"not null;type:varchar(20)" example:"WALLET/SYSTEM"`
+	UserID   *uuid.UUID `json:"userId" gorm:"type:varchar(36);index"`
+	Currency string     `json:"currency" gorm:"type:varchar(3);default:'INR'"`
+}
+
+func (*Account) TableName() string {
+	return "ledger_accounts"
+}
+
+// JournalEntry groups the postings of one money movement, its postings always add up to zero.
+type JournalEntry struct {
+	model.Base
+	Type     string     `json:"type" gorm:"not null;type:varchar(36)"`
+	Note     string     `json:"note" gorm:"type:varchar(255)"`
+	Postings []*Posting `json:"postings" gorm:"foreignKey:JournalEntryID"`
+}
+
+func (*JournalEntry) TableName() string {
+	return "ledger_journal_entries"
+}
+
+// Posting moves a signed amount into (positive) or out of (negative) an account.
+type Posting struct {
+	model.Base
+	JournalEntryID uuid.UUID   `json:"journalEntryId" gorm:"not null;type:varchar(36);index"`
+	AccountID      uuid.UUID   `json:"accountId" gorm:"not null;type:varchar(36);index"`
+	Amount         money.Money `json:"amount" gorm:"column:amount_paise;type:bigint;not null;default:0"`
+}
+
+func (*Posting) TableName() string {
+	return "ledger_postings"
+}
+
+// BalanceMismatch is a user whose stored wallet balance is not what their ledger postings add up to.
+type BalanceMismatch struct {
+	UserID        uuid.UUID   `json:"userId"`
+	StoredBalance money.Money `json:"storedBalance"`
+	LedgerBalance money.Money `json:"ledgerBalance"`
+}
+
+type UnbalancedEntry struct {
+	JournalEntryID uuid.UUID   `json:"journalEntryId"`
+	Total          money.Money `json:"total"`
+}
+
+type ReconciliationReport struct {
+	CheckedAt         time.Time         `json:"checkedAt"`
+	Mismatches        []BalanceMismatch `json:"mismatches"`
+	UnbalancedEntries []UnbalancedEntry `json:"unbalancedEntries"`
+}
+
+func WalletAccountCode(userID uuid.UUID) string {
+	return "wallet:" + userID.String()
+}
+
+// NewWalletEntry builds an entry moving amount into the user's wallet from the counter account,
+// a negative amount moves it out of the wallet instead.
+func NewWalletEntry(entryType, note string, wallet, counter *Account, amount money.Money) *JournalEntry {
+	return &JournalEntry{
+		Type: entryType,
+		Note: note,
+		Postings: []*Posting{
+			{AccountID: wallet.ID, Amount: amount},
+			{AccountID: counter.ID, Amount: -amount},
+		},
+	}
+}
+
+// NewSystemEntry builds an entry moving amount from one system account to another.
+func NewSystemEntry(entryType, note string, from, to *Account, amount money.Money) *JournalEntry {
+	return &JournalEntry{
+		Type: entryType,
+		Note: note,
+		Postings: []*Posting{
+			{AccountID: from.ID, Amount: -amount},
+			{AccountID: to.ID, Amount: amount},
+		},
+	}
+}
+
+// NewPurchaseEntry builds an entry paying for a purchase from the user's wallet, the taxable value
+// goes to revenue and the tax to the tax payable account.
+func NewPurchaseEntry(entryType, note string, wallet, revenue, taxPayable *Account, taxable, tax money.Money) *JournalEntry {
+	entry := &JournalEntry{
+		Type: entryType,
+		Note: note,
+		Postings: []*Posting{
+			{AccountID: wallet.ID, Amount: -(taxable + tax)},
+			{AccountID: revenue.ID, Amount: taxable},
+		},
+	}
+	if tax != 0 {
+		entry.Postings = append(entry.Postings, &Posting{AccountID: taxPayable.ID, Amount: tax})
+	}
+	return entry
+}
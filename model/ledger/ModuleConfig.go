@@ -0,0 +1,104 @@
+package ledger
+
+import (
+	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type LedgerModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewLedgerModuleConfig(db *gorm.DB) *LedgerModuleConfig {
+	return &LedgerModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *LedgerModuleConfig) MigrateTables() {
+
+	accountModel := &Account{}
+	entryModel := &JournalEntry{}
+	postingModel := &Posting{}
+
+	err := c.DB.AutoMigrate(accountModel, entryModel, postingModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Ledger ==> %s", err)
+	}
+
+	err = c.DB.Model(accountModel).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Ledger Account ==> %s", err)
+	}
+
+	err = c.DB.Model(postingModel).AddForeignKey("journal_entry_id", "ledger_journal_entries(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Ledger Posting ==> %s", err)
+	}
+
+	err = c.DB.Model(postingModel).AddForeignKey("account_id", "ledger_accounts(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Ledger Posting ==> %s", err)
+	}
+
+	err = c.DB.Model(accountModel).AddUniqueIndex("idx_ledger_account_code", "code").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Ledger Account ==> %s", err)
+	}
+
+	c.openExistingWallets()
+
+	log.GetLogger().Print("Ledger Module Configured.")
+}
+
+// openExistingWallets gives every user without a wallet account one, carrying over the balance
+// they had before the ledger existed as an opening entry so that reconciliation starts clean.
+func (c *LedgerModuleConfig) openExistingWallets() {
+	type pendingWallet struct {
+		ID          uuid.UUID
+		WalletPaise int64
+	}
+
+	pendingWallets := []pendingWallet{}
+	if err := c.DB.Raw(`
+		SELECT u.id, u.wallet_paise
+		FROM users u
+		LEFT JOIN ledger_accounts a ON a.user_id = u.id AND a.type = ? AND a.deleted_at IS NULL
+		WHERE a.id IS NULL
+	`, AccountTypeWallet).Scan(&pendingWallets).Error; err != nil {
+		log.GetLogger().Print("Opening Ledger Wallets ==> %s", err)
+		return
+	}
+	if len(pendingWallets) == 0 {
+		return
+	}
+
+	opening := &Account{}
+	if err := c.DB.Where(Account{Code: AccountOpeningBalance}).
+		Attrs(Account{Type: AccountTypeSystem, Currency: money.CurrencyINR}).
+		FirstOrCreate(opening).Error; err != nil {
+		log.GetLogger().Print("Opening Ledger Wallets ==> %s", err)
+		return
+	}
+
+	for _, pendingWallet := range pendingWallets {
+		userID := pendingWallet.ID
+		tx := c.DB.Begin()
+
+		wallet := &Account{Code: WalletAccountCode(userID), Type: AccountTypeWallet, UserID: &userID, Currency: money.CurrencyINR}
+		err := tx.Create(wallet).Error
+		if err == nil && pendingWallet.WalletPaise != 0 {
+			err = tx.Create(NewWalletEntry(EntryTypeOpeningBalance, "balance before the ledger was introduced",
+				wallet, opening, money.FromPaise(pendingWallet.WalletPaise))).Error
+		}
+		if err != nil {
+			tx.Rollback()
+			log.GetLogger().Print("Opening Ledger Wallets ==> %s", err)
+			return
+		}
+		tx.Commit()
+	}
+}
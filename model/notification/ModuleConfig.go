@@ -0,0 +1,34 @@
+package notification
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type NotificationModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewNotificationModuleConfig(db *gorm.DB) *NotificationModuleConfig {
+	return &NotificationModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *NotificationModuleConfig) MigrateTables() {
+
+	model := &Notification{}
+
+	err := c.DB.AutoMigrate(model).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Notification ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Notification ==> %s", err)
+	}
+
+	log.GetLogger().Print("Notification Module Configured.")
+}
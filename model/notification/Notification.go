@@ -0,0 +1,24 @@
+package notification
+
+import (
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	TypeLowVisits         = "LOW_VISITS"
+	TypeVisitsExhausted   = "VISITS_EXHAUSTED"
+	TypeAutoRenewFailed   = "AUTO_RENEW_FAILED"
+	TypeDestinationBroken = "DESTINATION_BROKEN"
+)
+
+type Notification struct {
+	model.Base
+	UserID  uuid.UUID  `json:"userId" gorm:"not null;type:varchar(36)"`
+	UrlID   *uuid.UUID `json:"urlId" gorm:"type:varchar(36)"`
+	Type    string     `json:"type" gorm:"not null;type:varchar(36)" example:"LOW_VISITS/VISITS_EXHAUSTED/AUTO_RENEW_FAILED/DESTINATION_BROKEN"`
+	Title   string     `json:"title" gorm:"type:varchar(100)"`
+	Message string     `json:"message" gorm:"type:varchar(500)"`
+	IsRead  *bool      `json:"isRead" gorm:"type:tinyint(1);default:false"`
+}
@@ -0,0 +1,50 @@
+package page
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type PageModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewPageModuleConfig(db *gorm.DB) *PageModuleConfig {
+	return &PageModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *PageModuleConfig) MigrateTables() {
+
+	pageModel := &Page{}
+	linkModel := &PageLink{}
+
+	err := c.DB.AutoMigrate(pageModel, linkModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Page ==> %s", err)
+	}
+
+	err = c.DB.Model(pageModel).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Page ==> %s", err)
+	}
+
+	err = c.DB.Model(pageModel).AddUniqueIndex("idx_page_slug", "slug").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Page ==> %s", err)
+	}
+
+	err = c.DB.Model(linkModel).AddForeignKey("page_id", "pages(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Page Link ==> %s", err)
+	}
+
+	err = c.DB.Model(linkModel).AddForeignKey("url_id", "urls(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Page Link ==> %s", err)
+	}
+
+	log.GetLogger().Print("Page Module Configured.")
+}
@@ -0,0 +1,74 @@
+package page
+
+import (
+	"regexp"
+	"strings"
+	"url-shortner-be/components/errors"
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const maxPageLinks = 50
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{2,49}$`)
+
+// Page is a public link-in-bio profile listing some of a user's short urls.
+type Page struct {
+	model.Base
+	UserID      uuid.UUID   `json:"userId" gorm:"not null;type:varchar(36)"`
+	Slug        string      `json:"slug" gorm:"not null;type:varchar(50)"`
+	Title       string      `json:"title" gorm:"not null;type:varchar(100)"`
+	Bio         string      `json:"bio" gorm:"type:varchar(500)"`
+	AvatarUrl   string      `json:"avatarUrl" gorm:"type:text"`
+	IsPublished *bool       `json:"isPublished" gorm:"type:tinyint(1);default:true"`
+	Links       []*PageLink `json:"links" gorm:"foreignKey:PageID"`
+}
+
+type PageLink struct {
+	model.Base
+	PageID   uuid.UUID `json:"pageId" gorm:"not null;type:varchar(36)"`
+	UrlID    uuid.UUID `json:"urlId" gorm:"not null;type:varchar(36)"`
+	Title    string    `json:"title" gorm:"not null;type:varchar(100)"`
+	Position int       `json:"position" gorm:"type:int;default:0"`
+}
+
+// PublicLink is a page link as rendered on the public page.
+type PublicLink struct {
+	Title    string
+	ShortUrl string
+}
+
+func (p *Page) Validate() error {
+	p.Slug = strings.ToLower(strings.TrimSpace(p.Slug))
+	p.Title = strings.TrimSpace(p.Title)
+
+	if !slugPattern.MatchString(p.Slug) {
+		return errors.NewValidationError("Slug must be 3 to 50 lowercase letters, digits, '-' or '_'")
+	}
+	if p.Title == "" || len(p.Title) > 100 {
+		return errors.NewValidationError("Title must be specified and at most 100 characters long")
+	}
+	if len(p.Bio) > 500 {
+		return errors.NewValidationError("Bio can be at most 500 characters long")
+	}
+	if p.AvatarUrl != "" && !strings.HasPrefix(p.AvatarUrl, "https://") && !strings.HasPrefix(p.AvatarUrl, "http://") {
+		return errors.NewValidationError("Avatar url must be an http or https url")
+	}
+	if len(p.Links) > maxPageLinks {
+		return errors.NewValidationError("A page can have at most 50 links")
+	}
+
+	seen := make(map[uuid.UUID]bool, len(p.Links))
+	for _, link := range p.Links {
+		link.Title = strings.TrimSpace(link.Title)
+		if link.Title == "" || len(link.Title) > 100 {
+			return errors.NewValidationError("Every link must have a title of at most 100 characters")
+		}
+		if link.UrlID == uuid.Nil || seen[link.UrlID] {
+			return errors.NewValidationError("Every link must refer to a different url")
+		}
+		seen[link.UrlID] = true
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package payment
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type PaymentModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewPaymentModuleConfig(db *gorm.DB) *PaymentModuleConfig {
+	return &PaymentModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *PaymentModuleConfig) MigrateTables() {
+
+	orderModel := &PaymentOrder{}
+	deliveryModel := &WebhookDelivery{}
+
+	err := c.DB.AutoMigrate(orderModel, deliveryModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Payment ==> %s", err)
+	}
+
+	err = c.DB.Model(orderModel).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Payment Order ==> %s", err)
+	}
+
+	err = c.DB.Model(orderModel).AddUniqueIndex("idx_payment_order_gateway_order", "gateway", "gateway_order_id").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Payment Order ==> %s", err)
+	}
+
+	err = c.DB.Model(deliveryModel).AddUniqueIndex("idx_webhook_delivery_event", "gateway", "event_id").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Webhook Delivery ==> %s", err)
+	}
+
+	log.GetLogger().Print("Payment Module Configured.")
+}
@@ -0,0 +1,58 @@
+package payment
+
+import (
+	"time"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/money"
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	StatusCreated  = "CREATED"
+	StatusPaid     = "PAID"
+	StatusFailed   = "FAILED"
+	StatusRefunded = "REFUNDED"
+
+	PurposeWalletTopUp = "WALLET_TOPUP"
+)
+
+// PaymentOrder is a wallet top up waiting for, or settled by, the payment gateway.
+type PaymentOrder struct {
+	model.Base
+	UserID           uuid.UUID   `json:"userId" gorm:"not null;type:varchar(36);index"`
+	Purpose          string      `json:"purpose" gorm:"not null;type:varchar(30);default:'WALLET_TOPUP'"`
+	Gateway          string      `json:"gateway" gorm:"not null;type:varchar(30)"`
+	GatewayOrderID   string      `json:"gatewayOrderId" gorm:"not null;type:varchar(100)"`
+	GatewayPaymentID string      `json:"gatewayPaymentId" gorm:"type:varchar(100)"`
+	GatewayKeyID     string      `json:"gatewayKeyId" gorm:"-"`
+	Amount           money.Money `json:"amount" gorm:"column:amount_paise;type:bigint;not null;default:0"`
+	Currency         string      `json:"currency" gorm:"type:varchar(3);default:'INR'"`
+	Status           string      `json:"status" gorm:"not null;type:varchar(20)" example:"CREATED/PAID/FAILED/REFUNDED"`
+	FailureReason    string      `json:"failureReason" gorm:"type:varchar(255)"`
+	PaidAt           *time.Time  `json:"paidAt"`
+	RefundedAt       *time.Time  `json:"refundedAt"`
+
+	// RefundedAmount adds up the refunds of the order, it is only REFUNDED once they reach the amount.
+	RefundedAmount money.Money `json:"refundedAmount" gorm:"column:refunded_amount_paise;type:bigint;not null;default:0"`
+}
+
+// WebhookDelivery records every processed webhook event so that redeliveries are ignored.
+type WebhookDelivery struct {
+	model.Base
+	Gateway   string `json:"gateway" gorm:"not null;type:varchar(30)"`
+	EventID   string `json:"eventId" gorm:"not null;type:varchar(100)"`
+	EventType string `json:"eventType" gorm:"type:varchar(50)"`
+	OrderID   string `json:"orderId" gorm:"type:varchar(100)"`
+}
+
+func (order *PaymentOrder) Validate() error {
+	if order.Amount <= 0 {
+		return errors.NewValidationError("Top up amount must be greater than zero")
+	}
+	if order.Amount > money.FromRupees(1000000) {
+		return errors.NewValidationError("Top up amount must not be greater than 1000000")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package referral
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type ReferralModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewReferralModuleConfig(db *gorm.DB) *ReferralModuleConfig {
+	return &ReferralModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *ReferralModuleConfig) MigrateTables() {
+
+	referralModel := &Referral{}
+
+	err := c.DB.AutoMigrate(referralModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Referral ==> %s", err)
+	}
+
+	err = c.DB.Model(referralModel).AddForeignKey("referrer_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Referral ==> %s", err)
+	}
+
+	err = c.DB.Model(referralModel).AddForeignKey("referee_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Referral ==> %s", err)
+	}
+
+	err = c.DB.Model(referralModel).AddForeignKey("transaction_id", "transactions(id)", "RESTRICT", "RESTRICT").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Referral ==> %s", err)
+	}
+
+	// A user can only ever be referred once.
+	err = c.DB.Model(referralModel).AddUniqueIndex("idx_referral_referee", "referee_id").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Referral ==> %s", err)
+	}
+
+	log.GetLogger().Print("Referral Module Configured.")
+}
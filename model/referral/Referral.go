@@ -0,0 +1,81 @@
+package referral
+
+import (
+	"crypto/rand"
+	"time"
+	"url-shortner-be/components/money"
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	StatusPending   = "PENDING"
+	StatusConfirmed = "CONFIRMED"
+	StatusRejected  = "REJECTED"
+
+	CodeLength = 8
+)
+
+// Referral is a user who registered with another user's referral code. Both get the rewards
+// recorded on it once the referred user makes their first paid purchase, referrals that look
+// like fraud at registration are rejected and never rewarded.
+type Referral struct {
+	model.Base
+	ReferrerID      uuid.UUID `json:"referrerId" gorm:"not null;type:varchar(36);index"`
+	RefereeID       uuid.UUID `json:"refereeId" gorm:"not null;type:varchar(36)"`
+	Code            string    `json:"code" gorm:"not null;type:varchar(8)"`
+	Status          string    `json:"status" gorm:"not null;type:varchar(20);index" example:"PENDING/CONFIRMED/REJECTED"`
+	RejectionReason string    `json:"rejectionReason" gorm:"type:varchar(255)"`
+
+	// Hashes of where the referred user registered from, compared against the referrer and their other referrals.
+	SignupIPHash     string `json:"-" gorm:"type:varchar(64);index"`
+	SignupDeviceHash string `json:"-" gorm:"type:varchar(64);index"`
+
+	// Rewards promised when the referral was made, paid out on confirmation.
+	ReferrerCredit   money.Money `json:"referrerCredit" gorm:"column:referrer_credit_paise;type:bigint;not null;default:0"`
+	RefereeCredit    money.Money `json:"refereeCredit" gorm:"column:referee_credit_paise;type:bigint;not null;default:0"`
+	ReferrerFreeUrls int         `json:"referrerFreeUrls" gorm:"type:int;not null;default:0"`
+	RefereeFreeUrls  int         `json:"refereeFreeUrls" gorm:"type:int;not null;default:0"`
+
+	TransactionID *uuid.UUID `json:"transactionId" gorm:"type:varchar(36)"`
+	ReferredAt    time.Time  `json:"referredAt"`
+	ConfirmedAt   *time.Time `json:"confirmedAt"`
+}
+
+// ReferralDashboard is what a user sees of the referral programme: their code, the referrals made
+// with it and what they earned, and the referral they registered through if any.
+type ReferralDashboard struct {
+	ReferralCode   string      `json:"referralCode"`
+	Pending        int         `json:"pending"`
+	Confirmed      int         `json:"confirmed"`
+	Rejected       int         `json:"rejected"`
+	PendingCredit  money.Money `json:"pendingCredit"`
+	EarnedCredit   money.Money `json:"earnedCredit"`
+	EarnedFreeUrls int         `json:"earnedFreeUrls"`
+	Referrals      []Referral  `json:"referrals"`
+	ReferredBy     *Referral   `json:"referredBy"`
+}
+
+// ReferralSummary counts a referrer's referrals in one status.
+type ReferralSummary struct {
+	Status   string
+	Count    int
+	Credit   money.Money
+	FreeUrls int
+}
+
+// GenerateCode makes a referral code without the letters and digits that are easily confused.
+func GenerateCode() string {
+
+	const letterBytes = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	b := make([]byte, CodeLength)
+	rand.Read(b)
+
+	for i := 0; i < CodeLength; i++ {
+		b[i] = letterBytes[int(b[i])%len(letterBytes)]
+	}
+
+	return string(b)
+}
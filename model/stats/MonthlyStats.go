@@ -1,6 +1,14 @@
 package stats
 
+import "url-shortner-be/components/money"
+
 type MonthlyStat struct {
 	Month int     `json:"month"`
 	Value float64 `json:"value"`
 }
+
+// MonthlyAmount is a monthly sum of money, kept exact unlike the counts in MonthlyStat.
+type MonthlyAmount struct {
+	Month int         `json:"month"`
+	Value money.Money `json:"value"`
+}
@@ -0,0 +1,25 @@
+package stats
+
+type UrlAnalytics struct {
+	UrlID       string `json:"urlId"`
+	HumanClicks int    `json:"humanClicks"`
+	BotClicks   int    `json:"botClicks"`
+	// UniqueVisitors is a HyperLogLog estimate of visitors per salt period (VISITOR_SALT_ROTATION_HOURS),
+	// summed over the periods in range: a visitor returning after the salt rotates is counted again.
+	UniqueVisitors uint64       `json:"uniqueVisitors"`
+	Daily          []DailyClick `json:"daily"`
+	Countries      []GeoClick   `json:"countries"`
+	Cities         []GeoClick   `json:"cities"`
+}
+
+type GeoClick struct {
+	CountryCode string `json:"countryCode"`
+	City        string `json:"city,omitempty"`
+	Clicks      int    `json:"clicks"`
+}
+
+type DailyClick struct {
+	Day         string `json:"day"`
+	HumanClicks int    `json:"humanClicks"`
+	BotClicks   int    `json:"botClicks"`
+}
@@ -1,19 +1,21 @@
 package stats
 
+import "url-shortner-be/components/money"
+
 type ReportStats struct {
 	Month         int
 	NewUsers      int
 	ActiveUsers   int
 	UrlsGenerated int
 	UrlsRenewed   int
-	TotalRevenue  float64
+	TotalRevenue  money.Money
 	PaidUser      int
 }
 
 
 type UserReportStats struct {
 	Month         int
-	MonthlySpending int 
+	MonthlySpending money.Money
 	UrlsRenewed   int
 	VisitsRenewed   int
 }
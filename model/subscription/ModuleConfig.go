@@ -2,6 +2,7 @@ package subscription
 
 import (
 	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
 
 	"github.com/jinzhu/gorm"
 )
@@ -25,6 +26,42 @@ func (c *SubscriptionModuleConfig) MigrateTables() {
 		log.NewLog().Print("Auto Migrating Subscription ==> %s", err)
 	}
 
+	for decimalColumn, paiseColumn := range map[string]string{
+		"new_url_price":     "new_url_price_paise",
+		"extra_visit_price": "extra_visit_price_paise",
+	} {
+		if err := money.MigrateDecimalColumn(c.DB, "subscriptions", decimalColumn, paiseColumn); err != nil {
+			log.GetLogger().Print("Migrating Subscription Prices To Paise ==> %s", err)
+		}
+	}
+
+	// The single subscription from before plans becomes the default Free plan.
+	err = c.DB.Exec("UPDATE subscriptions SET name = 'Free', is_default = true WHERE name IS NULL OR name = ''").Error
+	if err != nil {
+		log.GetLogger().Print("Naming Default Plan ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddUniqueIndex("idx_subscription_name", "name").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of Subscription ==> %s", err)
+	}
+
+	// Users from before plans are put on the default plan.
+	err = c.DB.Exec("UPDATE users SET subscription_id = (SELECT id FROM subscriptions WHERE is_default = true AND deleted_at IS NULL LIMIT 1) WHERE subscription_id IS NULL").Error
+	if err != nil {
+		log.GetLogger().Print("Assigning Default Plan ==> %s", err)
+	}
+
+	err = c.DB.Table("users").AddForeignKey("subscription_id", "subscriptions(id)", "RESTRICT", "RESTRICT").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of User Plan ==> %s", err)
+	}
+
+	err = c.DB.Table("users").AddForeignKey("pending_subscription_id", "subscriptions(id)", "SET NULL", "RESTRICT").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of User Plan ==> %s", err)
+	}
+
 	// err = c.DB.Model(&Subscription{}).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
 	// if err != nil {
 	// 	log.GetLogger().Print("Foreign Key Constraints Of Subscription ==> %s", err)
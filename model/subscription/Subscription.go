@@ -1,21 +1,57 @@
 package subscription
 
 import (
+	"strings"
+	"time"
 	"url-shortner-be/components/errors"
+	"url-shortner-be/components/money"
 	model "url-shortner-be/model/general"
 )
 
+// PlanPeriod is how long a paid plan lasts before it is charged again.
+const PlanPeriod = 30 * 24 * time.Hour
+
+// Subscription is a plan users are assigned to, e.g. Free, Pro or Business, with its own limits and prices.
+// Users without a plan are on the default one.
 type Subscription struct {
 	model.Base
-	FreeShortUrls   int     `json:"freeShortUrls" gorm:"type:int"`
-	FreeVisits      int     `json:"freeVisits" gorm:"type:int"`
-	NewUrlPrice     float32 `json:"newUrlPrice" gorm:"type:decimal(4,2)"`
-	ExtraVisitPrice float32 `json:"extraVisitPrice" gorm:"type:decimal(4,2)"`
+	Name         string      `json:"name" example:"Pro" gorm:"not null;type:varchar(50)"`
+	Description  string      `json:"description" gorm:"type:varchar(255)"`
+	MonthlyPrice money.Money `json:"monthlyPrice" gorm:"column:monthly_price_paise;type:bigint;not null;default:0"`
+	IsDefault    *bool       `json:"isDefault" gorm:"type:tinyint(1);default:false"`
+	// Inactive plans can no longer be switched to, users already on them keep them.
+	IsActive *bool `json:"isActive" gorm:"type:tinyint(1);default:true"`
+
+	FreeShortUrls   int         `json:"freeShortUrls" gorm:"type:int"`
+	FreeVisits      int         `json:"freeVisits" gorm:"type:int"`
+	NewUrlPrice     money.Money `json:"newUrlPrice" gorm:"column:new_url_price_paise;type:bigint;not null;default:0"`
+	ExtraVisitPrice money.Money `json:"extraVisitPrice" gorm:"column:extra_visit_price_paise;type:bigint;not null;default:0"`
+	Currency        string      `json:"currency" gorm:"type:varchar(3);default:'INR'"`
+
+	// TransferConsumesUrlCount decides whether accepting a url transfer uses up the recipient's url count.
+	TransferConsumesUrlCount *bool `json:"transferConsumesUrlCount" gorm:"type:tinyint(1);default:false"`
 
-	ExtraVisitPriceNew float32 `json:"extraVisitPriceNew" gorm:"-"`
+	// File links are limited by size and by the sniffed content type, "image/*" allows every image type.
+	MaxFileSizeMB    int    `json:"maxFileSizeMB" gorm:"type:int;default:10"`
+	AllowedFileTypes string `json:"allowedFileTypes" gorm:"type:varchar(500);default:'application/pdf,image/*'"`
+
+	ExtraVisitPriceNew money.Money `json:"extraVisitPriceNew" gorm:"-"`
+}
+
+// UserPlan is the plan a user is on, the plan they move to when it renews and when that is.
+type UserPlan struct {
+	Plan        *Subscription `json:"plan"`
+	PendingPlan *Subscription `json:"pendingPlan"`
+	RenewsAt    *time.Time    `json:"renewsAt"`
 }
 
 func (s *Subscription) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.NewValidationError("Plan name must be specified")
+	}
+	if s.MonthlyPrice < 0 {
+		return errors.NewValidationError("Monthly price cannot be negative")
+	}
 	if s.FreeShortUrls < 0 {
 		return errors.NewValidationError("Free short URLs cannot be negative")
 	}
@@ -28,5 +64,37 @@ func (s *Subscription) Validate() error {
 	if s.ExtraVisitPrice < 0 {
 		return errors.NewValidationError("Extra visit price cannot be negative")
 	}
+	if s.MaxFileSizeMB < 0 {
+		return errors.NewValidationError("Max file size cannot be negative")
+	}
 	return nil
 }
+
+func (s *Subscription) IsFree() bool {
+	return s.MonthlyPrice == 0
+}
+
+// Prorate is the share of price left for the rest of a plan period that ends at renewsAt.
+func Prorate(price money.Money, renewsAt, now time.Time) money.Money {
+	remaining := renewsAt.Sub(now)
+	if remaining <= 0 {
+		return 0
+	}
+	if remaining >= PlanPeriod {
+		return price
+	}
+	// Rounded half up to the paisa, the period is whole seconds so this stays in int64.
+	period := int64(PlanPeriod / time.Second)
+	return money.Money((int64(price)*int64(remaining/time.Second) + period/2) / period)
+}
+
+// AllowsFileType reports whether files of the given content type can be uploaded under this subscription.
+func (s *Subscription) AllowsFileType(contentType string) bool {
+	for _, allowed := range strings.Split(s.AllowedFileTypes, ",") {
+		allowed = strings.TrimSpace(strings.ToLower(allowed))
+		if allowed == contentType || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+	return false
+}
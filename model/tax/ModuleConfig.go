@@ -0,0 +1,29 @@
+package tax
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type TaxModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewTaxModuleConfig(db *gorm.DB) *TaxModuleConfig {
+	return &TaxModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *TaxModuleConfig) MigrateTables() {
+
+	settingModel := &TaxSetting{}
+
+	err := c.DB.AutoMigrate(settingModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Tax ==> %s", err)
+	}
+
+	log.GetLogger().Print("Tax Module Configured.")
+}
@@ -0,0 +1,56 @@
+package tax
+
+// States are the Indian states and union territories by their GST state code.
+var States = map[string]string{
+	"01": "Jammu and Kashmir",
+	"02": "Himachal Pradesh",
+	"03": "Punjab",
+	"04": "Chandigarh",
+	"05": "Uttarakhand",
+	"06": "Haryana",
+	"07": "Delhi",
+	"08": "Rajasthan",
+	"09": "Uttar Pradesh",
+	"10": "Bihar",
+	"11": "Sikkim",
+	"12": "Arunachal Pradesh",
+	"13": "Nagaland",
+	"14": "Manipur",
+	"15": "Mizoram",
+	"16": "Tripura",
+	"17": "Meghalaya",
+	"18": "Assam",
+	"19": "West Bengal",
+	"20": "Jharkhand",
+	"21": "Odisha",
+	"22": "Chhattisgarh",
+	"23": "Madhya Pradesh",
+	"24": "Gujarat",
+	"26": "Dadra and Nagar Haveli and Daman and Diu",
+	"27": "Maharashtra",
+	"29": "Karnataka",
+	"30": "Goa",
+	"31": "Lakshadweep",
+	"32": "Kerala",
+	"33": "Tamil Nadu",
+	"34": "Puducherry",
+	"35": "Andaman and Nicobar Islands",
+	"36": "Telangana",
+	"37": "Andhra Pradesh",
+	"38": "Ladakh",
+	"97": "Other Territory",
+}
+
+func IsValidState(code string) bool {
+	_, ok := States[code]
+	return ok
+}
+
+// StateLabel formats a state code for invoices, e.g. "Karnataka (29)".
+func StateLabel(code string) string {
+	name, ok := States[code]
+	if !ok {
+		return code
+	}
+	return name + " (" + code + ")"
+}
@@ -0,0 +1,69 @@
+package tax
+
+import (
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/money"
+	model "url-shortner-be/model/general"
+)
+
+const (
+	NameCGST = "CGST"
+	NameSGST = "SGST"
+	NameIGST = "IGST"
+)
+
+// TaxSetting is the GST configuration purchases are charged with. Settings are never edited,
+// an admin adds a new one and the latest applies from then on, so past purchases keep what they were charged.
+type TaxSetting struct {
+	model.Base
+	// RateBasisPoints is the GST rate in hundredths of a percent, 1800 is 18%.
+	RateBasisPoints int `json:"rateBasisPoints" gorm:"type:int;not null;default:0"`
+	// Inclusive means the subscription prices already contain GST instead of having it added on top.
+	Inclusive *bool `json:"inclusive" gorm:"type:tinyint(1);default:false"`
+}
+
+// Breakdown is how a purchase price splits into taxable value and GST. Within the seller's state
+// GST is split equally into CGST and SGST, for buyers in other states it is charged as IGST.
+type Breakdown struct {
+	RateBasisPoints int         `json:"rateBasisPoints"`
+	Inclusive       bool        `json:"inclusive"`
+	PlaceOfSupply   string      `json:"placeOfSupply"`
+	Taxable         money.Money `json:"taxable"`
+	CGST            money.Money `json:"cgst"`
+	SGST            money.Money `json:"sgst"`
+	IGST            money.Money `json:"igst"`
+	Total           money.Money `json:"total"`
+}
+
+// MonthlyTaxReport sums the purchases of a month charged at one rate in one place of supply,
+// which is how they are reported when filing GST returns.
+type MonthlyTaxReport struct {
+	Month           int         `json:"month"`
+	PlaceOfSupply   string      `json:"placeOfSupply"`
+	RateBasisPoints int         `json:"rateBasisPoints"`
+	Purchases       int         `json:"purchases"`
+	Taxable         money.Money `json:"taxable"`
+	CGST            money.Money `json:"cgst"`
+	SGST            money.Money `json:"sgst"`
+	IGST            money.Money `json:"igst"`
+	Total           money.Money `json:"total"`
+}
+
+func (setting *TaxSetting) Validate() error {
+	if setting.RateBasisPoints < 0 || setting.RateBasisPoints > 10000 {
+		return errors.NewValidationError("tax rate must be between 0 and 10000 basis points")
+	}
+	// CGST and SGST are half the rate each, an odd rate would not split into whole basis points.
+	if setting.RateBasisPoints%2 != 0 {
+		return errors.NewValidationError("tax rate must be an even number of basis points")
+	}
+	return nil
+}
+
+func (setting *TaxSetting) IsInclusive() bool {
+	return setting.Inclusive != nil && *setting.Inclusive
+}
+
+func (breakdown *Breakdown) Tax() money.Money {
+	return breakdown.CGST + breakdown.SGST + breakdown.IGST
+}
@@ -2,6 +2,7 @@ package transaction
 
 import (
 	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
 
 	"github.com/jinzhu/gorm"
 )
@@ -25,6 +26,19 @@ func (c *TransactionModuleConfig) MigrateTables() {
 		log.NewLog().Print("Auto Migrating Trnasaction ==> %s", err)
 	}
 
+	if err := money.MigrateDecimalColumn(c.DB, "transactions", "amount", "amount_paise"); err != nil {
+		log.GetLogger().Print("Migrating Transaction Amount To Paise ==> %s", err)
+	}
+
+	// Purchases made before GST was charged were taxable at their full amount.
+	if err := c.DB.Exec(`
+		UPDATE transactions SET taxable_amount_paise = amount_paise
+		WHERE type IN ('URLRENEWAL', 'VISITSRENEWAL') AND taxable_amount_paise = 0
+		 AND cgst_paise = 0 AND sgst_paise = 0 AND igst_paise = 0
+	`).Error; err != nil {
+		log.GetLogger().Print("Migrating Transaction Taxable Amount ==> %s", err)
+	}
+
 	err = c.DB.Model(&Transaction{}).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
 	if err != nil {
 		log.GetLogger().Print("Foreign Key Constraints Of Transaction ==> %s", err)
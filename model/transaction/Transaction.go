@@ -1,15 +1,42 @@
 package transaction
 
 import (
+	"url-shortner-be/components/money"
 	model "url-shortner-be/model/general"
+	"url-shortner-be/model/tax"
 
 	uuid "github.com/satori/go.uuid"
 )
 
 type Transaction struct {
 	model.Base
-	Amount float32   `json:"amount" gorm:"type:decimal(10,2)"`
-	Type   string    `json:"type" gorm:"not null;type:varchar(36)" example:"CREDIT/DEBIT/URLRENEWAL/VISITSRENEWAL"`
-	Note   string    `json:"note" gorm:"type:varchar(100)"`
-	UserID uuid.UUID `json:"userId" gorm:"not null;type:varchar(36)"`
+	Amount   money.Money `json:"amount" gorm:"column:amount_paise;type:bigint;not null;default:0"`
+	Currency string      `json:"currency" gorm:"type:varchar(3);default:'INR'"`
+	Type     string      `json:"type" gorm:"not null;type:varchar(36)" example:"CREDIT/DEBIT/URLRENEWAL/VISITSRENEWAL"`
+	Note     string      `json:"note" gorm:"type:varchar(100)"`
+	UserID   uuid.UUID   `json:"userId" gorm:"not null;type:varchar(36)"`
+
+	// GST charged on purchases, Amount is what was paid including it.
+	TaxableAmount      money.Money `json:"taxableAmount" gorm:"column:taxable_amount_paise;type:bigint;not null;default:0"`
+	CGST               money.Money `json:"cgst" gorm:"column:cgst_paise;type:bigint;not null;default:0"`
+	SGST               money.Money `json:"sgst" gorm:"column:sgst_paise;type:bigint;not null;default:0"`
+	IGST               money.Money `json:"igst" gorm:"column:igst_paise;type:bigint;not null;default:0"`
+	TaxRateBasisPoints int         `json:"taxRateBasisPoints" gorm:"type:int;not null;default:0"`
+	PlaceOfSupply      string      `json:"placeOfSupply" gorm:"type:varchar(2)"`
+}
+
+// NewPurchase builds the transaction of a purchase charged with the given tax breakdown.
+func NewPurchase(userID uuid.UUID, transactionType, note string, breakdown tax.Breakdown) *Transaction {
+	return &Transaction{
+		Amount:             breakdown.Total,
+		Type:               transactionType,
+		Note:               note,
+		UserID:             userID,
+		TaxableAmount:      breakdown.Taxable,
+		CGST:               breakdown.CGST,
+		SGST:               breakdown.SGST,
+		IGST:               breakdown.IGST,
+		TaxRateBasisPoints: breakdown.RateBasisPoints,
+		PlaceOfSupply:      breakdown.PlaceOfSupply,
+	}
 }
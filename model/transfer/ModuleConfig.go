@@ -0,0 +1,56 @@
+package transfer
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type TransferModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewTransferModuleConfig(db *gorm.DB) *TransferModuleConfig {
+	return &TransferModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *TransferModuleConfig) MigrateTables() {
+
+	transferModel := &UrlTransfer{}
+	itemModel := &UrlTransferItem{}
+
+	err := c.DB.AutoMigrate(transferModel, itemModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Url Transfer ==> %s", err)
+	}
+
+	// AutoMigrate does not widen existing columns.
+	err = c.DB.Model(transferModel).ModifyColumn("to_email", "varchar(255) NOT NULL").Error
+	if err != nil {
+		log.GetLogger().Print("Widening To Email Of Url Transfer ==> %s", err)
+	}
+
+	err = c.DB.Model(transferModel).AddForeignKey("from_user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Url Transfer ==> %s", err)
+	}
+
+	err = c.DB.Model(transferModel).AddForeignKey("to_user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Url Transfer ==> %s", err)
+	}
+
+	err = c.DB.Model(itemModel).AddForeignKey("transfer_id", "url_transfers(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Url Transfer Item ==> %s", err)
+	}
+
+	err = c.DB.Model(itemModel).AddForeignKey("url_id", "urls(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Url Transfer Item ==> %s", err)
+	}
+
+	log.GetLogger().Print("Transfer Module Configured.")
+}
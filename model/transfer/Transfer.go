@@ -0,0 +1,44 @@
+package transfer
+
+import (
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/util"
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	StatusPending   = "PENDING"
+	StatusAccepted  = "ACCEPTED"
+	StatusRejected  = "REJECTED"
+	StatusCancelled = "CANCELLED"
+)
+
+type UrlTransfer struct {
+	model.Base
+	FromUserID uuid.UUID          `json:"fromUserId" gorm:"not null;type:varchar(36)"`
+	ToUserID   uuid.UUID          `json:"toUserId" gorm:"not null;type:varchar(36)"`
+	ToEmail    string             `json:"toEmail" gorm:"not null;type:varchar(255)"`
+	Status     string             `json:"status" gorm:"not null;type:varchar(20)" example:"PENDING/ACCEPTED/REJECTED/CANCELLED"`
+	Note       string             `json:"note" gorm:"type:varchar(100)"`
+	Items      []*UrlTransferItem `json:"items" gorm:"foreignKey:TransferID"`
+
+	UrlIDs []uuid.UUID `json:"urlIds" gorm:"-"`
+}
+
+type UrlTransferItem struct {
+	model.Base
+	TransferID uuid.UUID `json:"transferId" gorm:"not null;type:varchar(36)"`
+	UrlID      uuid.UUID `json:"urlId" gorm:"not null;type:varchar(36)"`
+}
+
+func (t *UrlTransfer) Validate() error {
+	if util.IsEmpty(t.ToEmail) || !util.ValidateEmail(t.ToEmail) {
+		return errors.NewValidationError("Recipient email must be specified and should be of the type abc@domain.com")
+	}
+	if len(t.UrlIDs) == 0 {
+		return errors.NewValidationError("At least one url must be selected for transfer")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package url
+
+import (
+	"fmt"
+	urlNet "net/url"
+	"strings"
+	"url-shortner-be/components/errors"
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const maxGeoRules = 50
+
+// GeoRule sends visitors from a country (or a region of it) to a different destination, or blocks them.
+// Visitors that match no rule are sent to the url's LongUrl.
+type GeoRule struct {
+	model.Base
+	UrlID          uuid.UUID `json:"urlId" gorm:"type:char(36);index"`
+	CountryCode    string    `json:"countryCode" gorm:"not null;type:varchar(2)" example:"IN"`
+	Region         string    `json:"region" gorm:"type:varchar(100)"`
+	DestinationUrl string    `json:"destinationUrl" gorm:"type:text"`
+	IsBlocked      *bool     `json:"isBlocked" gorm:"type:tinyint(1);default:false"`
+}
+
+func (rule *GeoRule) Validate() error {
+	rule.CountryCode = strings.ToUpper(strings.TrimSpace(rule.CountryCode))
+	rule.Region = strings.TrimSpace(rule.Region)
+
+	if len(rule.CountryCode) != 2 {
+		return errors.NewValidationError("country code must be a 2 letter ISO code")
+	}
+
+	if rule.IsBlocked != nil && *rule.IsBlocked {
+		rule.DestinationUrl = ""
+		return nil
+	}
+
+	destination, err := urlNet.ParseRequestURI(rule.DestinationUrl)
+	if err != nil || (destination.Scheme != "http" && destination.Scheme != "https") || destination.Host == "" {
+		return errors.NewValidationError(fmt.Sprintf("destination url for %s must be a valid http or https url", rule.CountryCode))
+	}
+	return nil
+}
+
+func ValidateGeoRules(rules []GeoRule) error {
+	if len(rules) > maxGeoRules {
+		return errors.NewValidationError(fmt.Sprintf("a url can have at most %d geo rules", maxGeoRules))
+	}
+
+	seen := make(map[string]bool, len(rules))
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return err
+		}
+		key := rules[i].CountryCode + "/" + strings.ToLower(rules[i].Region)
+		if seen[key] {
+			return errors.NewValidationError("duplicate geo rule for " + rules[i].CountryCode + " " + rules[i].Region)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// MatchGeoRule returns the rule for the visitor's region if one exists, else the rule for the whole country.
+func MatchGeoRule(rules []GeoRule, countryCode, region string) *GeoRule {
+	if countryCode == "" {
+		return nil
+	}
+
+	var countryRule *GeoRule
+	for i := range rules {
+		if !strings.EqualFold(rules[i].CountryCode, countryCode) {
+			continue
+		}
+		if rules[i].Region == "" {
+			countryRule = &rules[i]
+		} else if region != "" && strings.EqualFold(rules[i].Region, region) {
+			return &rules[i]
+		}
+	}
+	return countryRule
+}
@@ -0,0 +1,45 @@
+package url
+
+const (
+	ImportStatusImported = "IMPORTED"
+	ImportStatusRemapped = "REMAPPED"
+	ImportStatusSkipped  = "SKIPPED"
+	ImportStatusFailed   = "FAILED"
+)
+
+// ImportReport describes the outcome of importing links exported from another shortener.
+type ImportReport struct {
+	Total    int          `json:"total"`
+	Imported int          `json:"imported"`
+	Remapped int          `json:"remapped"`
+	Skipped  int          `json:"skipped"`
+	Failed   int          `json:"failed"`
+	Rows     []*ImportRow `json:"rows"`
+}
+
+// ImportRow is the outcome of a single link, Remapped rows kept their link but got a new short code.
+type ImportRow struct {
+	Row          int    `json:"row"`
+	LongUrl      string `json:"longUrl"`
+	OriginalCode string `json:"originalCode"`
+	ShortUrl     string `json:"shortUrl,omitempty"`
+	VisitCount   int    `json:"visitCount"`
+	Status       string `json:"status" example:"IMPORTED/REMAPPED/SKIPPED/FAILED"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+func (report *ImportReport) Add(row *ImportRow) {
+	report.Rows = append(report.Rows, row)
+	report.Total++
+
+	switch row.Status {
+	case ImportStatusImported:
+		report.Imported++
+	case ImportStatusRemapped:
+		report.Remapped++
+	case ImportStatusSkipped:
+		report.Skipped++
+	default:
+		report.Failed++
+	}
+}
@@ -1,7 +1,9 @@
 package url
 
 import (
+	"url-shortner-be/components/canonical"
 	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
 
 	"github.com/jinzhu/gorm"
 )
@@ -19,8 +21,9 @@ func NewUrlModuleConfig(db *gorm.DB) *UrlModuleConfig {
 func (c *UrlModuleConfig) MigrateTables() {
 
 	model := &Url{}
+	geoRuleModel := &GeoRule{}
 
-	err := c.DB.AutoMigrate(model).Error
+	err := c.DB.AutoMigrate(model, geoRuleModel).Error
 	if err != nil {
 		log.NewLog().Print("Auto Migrating Url ==> %s", err)
 	}
@@ -30,6 +33,53 @@ func (c *UrlModuleConfig) MigrateTables() {
 		log.GetLogger().Print("Foreign Key Constraints Of Url ==> %s", err)
 	}
 
+	err = c.DB.Model(geoRuleModel).AddForeignKey("url_id", "urls(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Geo Rule ==> %s", err)
+	}
+
+	for decimalColumn, paiseColumn := range map[string]string{
+		"auto_renew_max_monthly_spend": "auto_renew_max_monthly_spend_paise",
+		"auto_renew_spent":             "auto_renew_spent_paise",
+	} {
+		if err := money.MigrateDecimalColumn(c.DB, "urls", decimalColumn, paiseColumn); err != nil {
+			log.GetLogger().Print("Migrating Auto Renew Spend To Paise ==> %s", err)
+		}
+	}
+
+	c.backfillCanonicalUrls()
+
 	log.GetLogger().Print("Url Module Configured.")
 
 }
+
+// backfillCanonicalUrls stores the canonical form of the urls created before long urls were canonicalised.
+func (c *UrlModuleConfig) backfillCanonicalUrls() {
+	stripTracking := StripTrackingParams()
+
+	for {
+		pendingUrls := []Url{}
+		if err := c.DB.Select("id, long_url").
+			Where("(canonical_url IS NULL OR canonical_url = '') AND link_type <> ? AND long_url <> ''", LinkTypeFile).
+			Limit(500).Find(&pendingUrls).Error; err != nil {
+			log.GetLogger().Print("Backfilling Canonical Urls ==> %s", err)
+			return
+		}
+		if len(pendingUrls) == 0 {
+			return
+		}
+
+		for _, pendingUrl := range pendingUrls {
+			// Urls that cannot be parsed keep their long url so that they are not picked again.
+			canonicalUrl, err := canonical.Canonicalize(pendingUrl.LongUrl, stripTracking)
+			if err != nil {
+				canonicalUrl = pendingUrl.LongUrl
+			}
+
+			if err := c.DB.Model(&Url{}).Where("id = ?", pendingUrl.ID).UpdateColumn("canonical_url", canonicalUrl).Error; err != nil {
+				log.GetLogger().Print("Backfilling Canonical Urls ==> %s", err)
+				return
+			}
+		}
+	}
+}
@@ -3,29 +3,115 @@ package url
 import (
 	"crypto/rand"
 	"net/http"
+	"time"
+	"url-shortner-be/components/canonical"
+	"url-shortner-be/components/config"
 	"url-shortner-be/components/errors"
 	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
 	model "url-shortner-be/model/general"
 
 	uuid "github.com/satori/go.uuid"
 )
 
+const (
+	BotPolicyServe    = "SERVE"
+	BotPolicyMetadata = "METADATA"
+	BotPolicyBlock    = "BLOCK"
+
+	BillingModeAll    = "ALL"
+	BillingModeUnique = "UNIQUE"
+
+	LinkTypeStandard = "STANDARD"
+	LinkTypeSecret   = "SECRET"
+	LinkTypeFile     = "FILE"
+
+	maxSecretLinkOpens = 100
+)
+
 type Url struct {
 	model.Base
-	LongUrl         string    `json:"longUrl" gorm:"not null;type:text"`
-	ShortUrl        string    `json:"shortUrl" gorm:"not null;type:varchar(5)"`
-	RemainingVisits int       `json:"remainingVisits" gorm:"not null;type:int;default:0"`
-	VisitCount      int       `json:"visitCount" gorm:"not null;type:int;default:0"`
-	UserID          uuid.UUID `json:"userId" gorm:"type:char(36)"`
+	LongUrl         string     `json:"longUrl" gorm:"not null;type:text"`
+	CanonicalUrl    string     `json:"canonicalUrl" gorm:"type:text"`
+	FinalUrl        string     `json:"finalUrl" gorm:"type:text"`
+	UnwrapRedirects *bool      `json:"unwrapRedirects" gorm:"type:tinyint(1);default:false"`
+	ShortUrl        string     `json:"shortUrl" gorm:"not null;type:varchar(5)"`
+	RemainingVisits int        `json:"remainingVisits" gorm:"not null;type:int;default:0"`
+	VisitCount      int        `json:"visitCount" gorm:"not null;type:int;default:0"`
+	UserID          uuid.UUID  `json:"userId" gorm:"type:char(36)"`
+	DomainID        *uuid.UUID `json:"domainId" gorm:"type:varchar(36);index"`
+
+	AutoRenew                *bool       `json:"autoRenew" gorm:"type:tinyint(1);default:false"`
+	AutoRenewVisits          int         `json:"autoRenewVisits" gorm:"type:int;default:0"`
+	AutoRenewMaxMonthlySpend money.Money `json:"autoRenewMaxMonthlySpend" gorm:"column:auto_renew_max_monthly_spend_paise;type:bigint;not null;default:0"`
+	AutoRenewSpent           money.Money `json:"autoRenewSpent" gorm:"column:auto_renew_spent_paise;type:bigint;not null;default:0"`
+	AutoRenewPeriod          string      `json:"autoRenewPeriod" gorm:"type:varchar(7)"`
+
+	LowVisitThreshold int        `json:"lowVisitThreshold" gorm:"type:int;default:0"`
+	IsBroken          *bool      `json:"isBroken" gorm:"type:tinyint(1);default:false"`
+	LastCheckedAt     *time.Time `json:"lastCheckedAt"`
+
+	RateLimitPerMinute int `json:"rateLimitPerMinute" gorm:"type:int;default:0"`
+	RateLimitBurst     int `json:"rateLimitBurst" gorm:"type:int;default:0"`
+
+	BotPolicy     string `json:"botPolicy" gorm:"type:varchar(20);default:'SERVE'" example:"SERVE/METADATA/BLOCK"`
+	BotVisitCount int    `json:"botVisitCount" gorm:"not null;type:int;default:0"`
+
+	BillingMode       string `json:"billingMode" gorm:"type:varchar(20);default:'ALL'" example:"ALL/UNIQUE"`
+	UniqueWindowHours int    `json:"uniqueWindowHours" gorm:"type:int;default:24"`
+
+	LinkType   string     `json:"linkType" gorm:"type:varchar(20);default:'STANDARD'" example:"STANDARD/SECRET"`
+	MaxOpens   int        `json:"maxOpens" gorm:"type:int;default:0"`
+	ConsumedAt *time.Time `json:"consumedAt"`
+
+	FileKey     string `json:"-" gorm:"type:varchar(255)"`
+	FileName    string `json:"fileName" gorm:"type:varchar(255)"`
+	FileSize    int64  `json:"fileSize" gorm:"type:bigint;default:0"`
+	ContentType string `json:"contentType" gorm:"type:varchar(100)"`
+
+	// CouponCode is only read from visit renewal requests, it is never stored on the url.
+	CouponCode string `json:"couponCode,omitempty" gorm:"-"`
 }
 
 type UrlDTO struct {
 	model.Base
-	LongUrl         string    `json:"longUrl" gorm:"not null;type:text"`
-	ShortUrl        string    `json:"shortUrl" gorm:"not null;unique;type:varchar(5)"`
-	RemainingVisits int       `json:"remainingVisits" gorm:"not null;type:int;default:0"`
-	VisitCount      int       `json:"visitCount" gorm:"not null;type:int;default:0"`
-	UserID          uuid.UUID `json:"userId" gorm:"foreignkey:ID;type:char(36)"`
+	LongUrl         string     `json:"longUrl" gorm:"not null;type:text"`
+	CanonicalUrl    string     `json:"canonicalUrl" gorm:"type:text"`
+	FinalUrl        string     `json:"finalUrl" gorm:"type:text"`
+	UnwrapRedirects *bool      `json:"unwrapRedirects" gorm:"type:tinyint(1);default:false"`
+	ShortUrl        string     `json:"shortUrl" gorm:"not null;unique;type:varchar(5)"`
+	RemainingVisits int        `json:"remainingVisits" gorm:"not null;type:int;default:0"`
+	VisitCount      int        `json:"visitCount" gorm:"not null;type:int;default:0"`
+	UserID          uuid.UUID  `json:"userId" gorm:"foreignkey:ID;type:char(36)"`
+	DomainID        *uuid.UUID `json:"domainId" gorm:"type:varchar(36);index"`
+
+	AutoRenew                *bool       `json:"autoRenew" gorm:"type:tinyint(1);default:false"`
+	AutoRenewVisits          int         `json:"autoRenewVisits" gorm:"type:int;default:0"`
+	AutoRenewMaxMonthlySpend money.Money `json:"autoRenewMaxMonthlySpend" gorm:"column:auto_renew_max_monthly_spend_paise;type:bigint;not null;default:0"`
+	AutoRenewSpent           money.Money `json:"autoRenewSpent" gorm:"column:auto_renew_spent_paise;type:bigint;not null;default:0"`
+	AutoRenewPeriod          string      `json:"autoRenewPeriod" gorm:"type:varchar(7)"`
+
+	LowVisitThreshold int        `json:"lowVisitThreshold" gorm:"type:int;default:0"`
+	IsBroken          *bool      `json:"isBroken" gorm:"type:tinyint(1);default:false"`
+	LastCheckedAt     *time.Time `json:"lastCheckedAt"`
+
+	RateLimitPerMinute int `json:"rateLimitPerMinute" gorm:"type:int;default:0"`
+	RateLimitBurst     int `json:"rateLimitBurst" gorm:"type:int;default:0"`
+
+	BotPolicy     string `json:"botPolicy" gorm:"type:varchar(20);default:'SERVE'" example:"SERVE/METADATA/BLOCK"`
+	BotVisitCount int    `json:"botVisitCount" gorm:"not null;type:int;default:0"`
+
+	BillingMode       string `json:"billingMode" gorm:"type:varchar(20);default:'ALL'" example:"ALL/UNIQUE"`
+	UniqueWindowHours int    `json:"uniqueWindowHours" gorm:"type:int;default:24"`
+
+	LinkType   string     `json:"linkType" gorm:"type:varchar(20);default:'STANDARD'" example:"STANDARD/SECRET"`
+	MaxOpens   int        `json:"maxOpens" gorm:"type:int;default:0"`
+	ConsumedAt *time.Time `json:"consumedAt"`
+
+	FileKey     string `json:"-" gorm:"type:varchar(255)"`
+	FileName    string `json:"fileName" gorm:"type:varchar(255)"`
+	FileSize    int64  `json:"fileSize" gorm:"type:bigint;default:0"`
+	ContentType string `json:"contentType" gorm:"type:varchar(100)"`
 }
 
 // ALTER TABLE urls
@@ -38,24 +124,127 @@ func (*UrlDTO) TableName() string {
 	return "urls"
 }
 
-func (url *Url) Validate(inputUrl string, shortUrl string) error {
-	resp, err := http.Get(inputUrl)
+func (url *Url) Validate() error {
+	if len(url.ShortUrl) == 0 || len(url.ShortUrl) != 5 {
+		return errors.NewHTTPError("short url must have 5 characters", http.StatusBadRequest)
+	}
+
+	return nil
+}
+
+// CheckDestination requests the long url and rejects it when it is not found. It is never called
+// for secret links, the request could use up the one-time invite the link points to.
+func (url *Url) CheckDestination() error {
+	resp, err := http.Get(url.LongUrl)
 	if err != nil {
 		log.GetLogger().Print(err)
-		return err
+		return errors.NewValidationError("unable to reach long url: " + err.Error())
 	}
+	defer resp.Body.Close()
+
 	if resp.StatusCode == 404 {
 		return errors.NewValidationError("request url not found, please provide a valid Long URL")
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if len(shortUrl) == 0 || len(shortUrl) != 5 {
-		return errors.NewHTTPError("short url must have 5 characters", http.StatusBadRequest)
+func (url *Url) ValidateAutoRenew() error {
+	if url.AutoRenew == nil {
+		return errors.NewValidationError("auto renew must be specified")
+	}
+	if !*url.AutoRenew {
+		return nil
+	}
+	if url.AutoRenewVisits <= 0 {
+		return errors.NewValidationError("auto renew visits should be a positive integer")
+	}
+	if url.AutoRenewMaxMonthlySpend <= 0 {
+		return errors.NewValidationError("auto renew maximum monthly spend should be greater than zero")
+	}
+	return nil
+}
+
+func (url *Url) ValidateLowVisitThreshold() error {
+	if url.LowVisitThreshold < 0 {
+		return errors.NewValidationError("low visit threshold cannot be negative")
+	}
+	return nil
+}
+
+func (url *Url) ValidateRateLimit() error {
+	if url.RateLimitPerMinute < 0 || url.RateLimitBurst < 0 {
+		return errors.NewValidationError("rate limit and burst cannot be negative")
+	}
+	if url.RateLimitPerMinute > 0 && url.RateLimitBurst == 0 {
+		return errors.NewValidationError("rate limit burst must be specified along with rate limit")
+	}
+	return nil
+}
+
+func (url *Url) ValidateBotPolicy() error {
+	if url.BotPolicy != BotPolicyServe && url.BotPolicy != BotPolicyMetadata && url.BotPolicy != BotPolicyBlock {
+		return errors.NewValidationError("bot policy must be one of SERVE, METADATA or BLOCK")
+	}
+	return nil
+}
+
+func (url *Url) ValidateBillingMode() error {
+	if url.BillingMode != BillingModeAll && url.BillingMode != BillingModeUnique {
+		return errors.NewValidationError("billing mode must be either ALL or UNIQUE")
+	}
+	if url.BillingMode == BillingModeUnique && (url.UniqueWindowHours <= 0 || url.UniqueWindowHours > 24*30) {
+		return errors.NewValidationError("unique window hours should be between 1 and 720")
+	}
+	return nil
+}
+
+// SetCanonicalUrl stores the canonical form of LongUrl, duplicate checks compare the canonical form.
+func (url *Url) SetCanonicalUrl() error {
+	canonicalUrl, err := canonical.Canonicalize(url.LongUrl, StripTrackingParams())
+	if err != nil {
+		return errors.NewValidationError("long url must be a valid http or https url")
 	}
+	url.CanonicalUrl = canonicalUrl
+	return nil
+}
+
+// StripTrackingParams reports whether tracking parameters like utm_source and fbclid are left out of canonical urls.
+func StripTrackingParams() bool {
+	return config.StripTrackingParams.GetStringValue() == "true"
+}
 
+// ValidateLinkType defaults the link to STANDARD and a secret link to a single open.
+func (url *Url) ValidateLinkType() error {
+	if url.LinkType == "" {
+		url.LinkType = LinkTypeStandard
+	}
+	if url.LinkType == LinkTypeFile {
+		return errors.NewValidationError("file links are created by uploading a file")
+	}
+	if url.LinkType != LinkTypeStandard && url.LinkType != LinkTypeSecret {
+		return errors.NewValidationError("link type must be either STANDARD or SECRET")
+	}
+	if url.LinkType == LinkTypeStandard {
+		url.MaxOpens = 0
+		return nil
+	}
+	if url.MaxOpens == 0 {
+		url.MaxOpens = 1
+	}
+	if url.MaxOpens < 0 || url.MaxOpens > maxSecretLinkOpens {
+		return errors.NewValidationError("max opens of a secret link should be between 1 and 100")
+	}
 	return nil
 }
 
+func (url *Url) IsSecret() bool {
+	return url.LinkType == LinkTypeSecret
+}
+
+func (url *Url) IsFile() bool {
+	return url.LinkType == LinkTypeFile
+}
+
 func GenerateShortUrl() string {
 
 	const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
@@ -2,6 +2,7 @@ package user
 
 import (
 	"url-shortner-be/components/log"
+	"url-shortner-be/components/money"
 
 	"github.com/jinzhu/gorm"
 )
@@ -25,4 +26,19 @@ func (u *UserModuleConfig) MigrateTables() {
 		log.NewLog().Print("Auto Migrating User ==> %s", err)
 	}
 
+	if err := money.MigrateDecimalColumn(u.DB, "users", "wallet", "wallet_paise"); err != nil {
+		log.GetLogger().Print("Migrating Wallet To Paise ==> %s", err)
+	}
+
+	// Users from before referrals get a code so that the unique index can be added.
+	err = u.DB.Exec("UPDATE users SET referral_code = UPPER(SUBSTRING(REPLACE(UUID(), '-', ''), 1, 8)) WHERE referral_code IS NULL OR referral_code = ''").Error
+	if err != nil {
+		log.GetLogger().Print("Backfilling Referral Codes ==> %s", err)
+	}
+
+	err = u.DB.Model(model).AddUniqueIndex("idx_user_referral_code", "referral_code").Error
+	if err != nil {
+		log.GetLogger().Print("Unique Index Of User ==> %s", err)
+	}
+
 }
@@ -1,14 +1,23 @@
 package user
 
 import (
+	"regexp"
+	"time"
 	"url-shortner-be/components/errors"
+	"url-shortner-be/components/money"
 	"url-shortner-be/components/util"
 	"url-shortner-be/model/credential"
 	model "url-shortner-be/model/general"
+	"url-shortner-be/model/tax"
 	"url-shortner-be/model/transaction"
 	"url-shortner-be/model/url"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
 )
 
+var gstinPattern = regexp.MustCompile(`^[0-9]{2}[A-Z]{5}[0-9]{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`)
+
 type User struct {
 	model.Base
 	FirstName   string                 `json:"firstName" example:"Ravi" gorm:"type:varchar(50)"`
@@ -17,9 +26,28 @@ type User struct {
 	Email       string                 `json:"email" gorm:"not null;type:varchar(36)"`
 	IsAdmin     *bool                  `json:"isAdmin" gorm:"type:tinyint(1);default:false"`
 	IsActive    *bool                  `json:"isActive" gorm:"type:tinyint(1);default:true"`
-	Wallet      float32                `json:"wallet" gorm:"type:decimal(10,2)"`
+	Wallet      money.Money            `json:"wallet" gorm:"column:wallet_paise;type:bigint;not null;default:0"`
+	Currency    string                 `json:"currency" gorm:"type:varchar(3);default:'INR'"`
 	UrlCount    int                    `json:"urlCount" gorm:"type:int"`
+	State       string                 `json:"state" example:"29" gorm:"type:varchar(2)"`
+	GSTIN       string                 `json:"gstin" gorm:"column:gstin;type:varchar(15)"`
 	Credentials *credential.Credential `json:"credential"`
+
+	ReferralCode string `json:"referralCode" gorm:"type:varchar(8)"`
+
+	// The user's plan, a downgrade waits in PendingSubscriptionID until the paid period ends at PlanRenewsAt.
+	SubscriptionID        *uuid.UUID `json:"subscriptionId" gorm:"type:varchar(36);index"`
+	PendingSubscriptionID *uuid.UUID `json:"pendingSubscriptionId" gorm:"type:varchar(36)"`
+	PlanRenewsAt          *time.Time `json:"planRenewsAt"`
+
+	// Hashes of the network and device the user registered from, used to spot fake referrals.
+	SignupIPHash     string `json:"-" gorm:"type:varchar(64)"`
+	SignupDeviceHash string `json:"-" gorm:"type:varchar(64)"`
+
+	// CouponCode is only read from registration and url renewal requests and ReferredBy only from
+	// registration, neither is stored on the user.
+	CouponCode string `json:"couponCode,omitempty" gorm:"-"`
+	ReferredBy string `json:"referredBy,omitempty" gorm:"-"`
 }
 
 type UserDTO struct {
@@ -30,11 +58,19 @@ type UserDTO struct {
 	Email        string                     `json:"email" gorm:"not null;type:varchar(36)"`
 	IsAdmin      *bool                      `json:"isAdmin" gorm:"type:tinyint(1);default:false"`
 	IsActive     *bool                      `json:"isActive" gorm:"type:tinyint(1);default:true"`
-	Wallet       float32                    `json:"wallet" gorm:"type:decimal(10,2)"`
+	Wallet       money.Money                `json:"wallet" gorm:"column:wallet_paise;type:bigint;not null;default:0"`
+	Currency     string                     `json:"currency" gorm:"type:varchar(3);default:'INR'"`
 	UrlCount     int                        `json:"urlCount" gorm:"type:int"`
+	State        string                     `json:"state" example:"29" gorm:"type:varchar(2)"`
+	GSTIN        string                     `json:"gstin" gorm:"column:gstin;type:varchar(15)"`
+	ReferralCode string                     `json:"referralCode" gorm:"type:varchar(8)"`
 	Credentials  *credential.CredentialDTO  `json:"credential" gorm:"foreignKey:UserId;"`
 	Url          []*url.UrlDTO              `json:"url" gorm:"foreignKey:userId"`
 	Transactions []*transaction.Transaction `json:"transactions" gorm:"foreignKey:userId"`
+
+	SubscriptionID        *uuid.UUID `json:"subscriptionId" gorm:"type:varchar(36);index"`
+	PendingSubscriptionID *uuid.UUID `json:"pendingSubscriptionId" gorm:"type:varchar(36)"`
+	PlanRenewsAt          *time.Time `json:"planRenewsAt"`
 }
 
 func (*UserDTO) TableName() string {
@@ -52,5 +88,31 @@ func (user *User) Validate() error {
 	if util.IsEmpty(user.PhoneNo) || !util.ValidateContact(user.PhoneNo) {
 		return errors.NewValidationError("User Contact must be specified and have 10 digits")
 	}
+	return user.ValidateTaxProfile()
+}
+
+// ValidateTaxProfile checks the state and GSTIN used to tax the user's purchases.
+func (user *User) ValidateTaxProfile() error {
+	if user.State != "" && !tax.IsValidState(user.State) {
+		return errors.NewValidationError("User State must be a GST state code")
+	}
+	if user.GSTIN != "" {
+		if !gstinPattern.MatchString(user.GSTIN) {
+			return errors.NewValidationError("User GSTIN is not valid")
+		}
+		if user.State != "" && user.GSTIN[:2] != user.State {
+			return errors.NewValidationError("User GSTIN does not belong to the user's state")
+		}
+	}
+	return nil
+}
+
+// Exists is the check every service runs on the user a request is made for or by,
+// a valid token can outlive its user.
+func Exists(db *gorm.DB, ID uuid.UUID) error {
+	var count int
+	if err := db.Model(&User{}).Where("id = ?", ID).Count(&count).Error; err != nil || count == 0 {
+		return errors.NewValidationError("user does not exist")
+	}
 	return nil
 }
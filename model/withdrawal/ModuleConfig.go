@@ -0,0 +1,34 @@
+package withdrawal
+
+import (
+	"url-shortner-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type WithdrawalModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewWithdrawalModuleConfig(db *gorm.DB) *WithdrawalModuleConfig {
+	return &WithdrawalModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *WithdrawalModuleConfig) MigrateTables() {
+
+	withdrawalModel := &WithdrawalRequest{}
+
+	err := c.DB.AutoMigrate(withdrawalModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Withdrawal ==> %s", err)
+	}
+
+	err = c.DB.Model(withdrawalModel).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.GetLogger().Print("Foreign Key Constraints Of Withdrawal Request ==> %s", err)
+	}
+
+	log.GetLogger().Print("Withdrawal Module Configured.")
+}
@@ -0,0 +1,60 @@
+package withdrawal
+
+import (
+	"regexp"
+	"time"
+	"url-shortner-be/components/errors"
+	"url-shortner-be/components/money"
+	model "url-shortner-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	StatusRequested = "REQUESTED"
+	StatusApproved  = "APPROVED"
+	StatusPaid      = "PAID"
+	StatusRejected  = "REJECTED"
+)
+
+var ifscPattern = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+
+// WithdrawalRequest is money a user asked to take out of their wallet. The amount is held from the
+// wallet when it is requested and either paid out once an admin approves it or released back on rejection.
+type WithdrawalRequest struct {
+	model.Base
+	UserID            uuid.UUID   `json:"userId" gorm:"not null;type:varchar(36);index"`
+	Amount            money.Money `json:"amount" gorm:"column:amount_paise;type:bigint;not null;default:0"`
+	Currency          string      `json:"currency" gorm:"type:varchar(3);default:'INR'"`
+	Status            string      `json:"status" gorm:"not null;type:varchar(20);index" example:"REQUESTED/APPROVED/PAID/REJECTED"`
+	AccountHolderName string      `json:"accountHolderName" gorm:"type:varchar(100)"`
+	AccountNumber     string      `json:"accountNumber" gorm:"type:varchar(30)"`
+	IFSC              string      `json:"ifsc" gorm:"column:ifsc;type:varchar(11)"`
+	UpiID             string      `json:"upiId" gorm:"type:varchar(100)"`
+	PayoutProvider    string      `json:"payoutProvider" gorm:"type:varchar(30)"`
+	PayoutReference   string      `json:"payoutReference" gorm:"type:varchar(100)"`
+	RejectionReason   string      `json:"rejectionReason" gorm:"type:varchar(255)"`
+	FailureReason     string      `json:"failureReason" gorm:"type:varchar(255)"`
+	ReviewedBy        *uuid.UUID  `json:"reviewedBy" gorm:"type:varchar(36)"`
+	ReviewedAt        *time.Time  `json:"reviewedAt"`
+	PaidAt            *time.Time  `json:"paidAt"`
+}
+
+func (request *WithdrawalRequest) Validate() error {
+	if request.Amount <= 0 {
+		return errors.NewValidationError("Withdrawal amount must be greater than zero")
+	}
+	if request.Amount > money.FromRupees(1000000) {
+		return errors.NewValidationError("withdrawal amount must not be greater than 1000000")
+	}
+	if request.UpiID != "" {
+		return nil
+	}
+	if request.AccountHolderName == "" || request.AccountNumber == "" || request.IFSC == "" {
+		return errors.NewValidationError("either a UPI id or the account holder name, account number and IFSC are required")
+	}
+	if !ifscPattern.MatchString(request.IFSC) {
+		return errors.NewValidationError("invalid IFSC")
+	}
+	return nil
+}
@@ -2,11 +2,24 @@ package module
 
 import (
 	"url-shortner-be/app"
+	"url-shortner-be/model/click"
+	"url-shortner-be/model/coupon"
 	"url-shortner-be/model/credential"
+	"url-shortner-be/model/domain"
+	"url-shortner-be/model/idempotency"
+	"url-shortner-be/model/invoice"
+	"url-shortner-be/model/ledger"
+	"url-shortner-be/model/notification"
+	"url-shortner-be/model/page"
+	"url-shortner-be/model/payment"
+	"url-shortner-be/model/referral"
 	"url-shortner-be/model/subscription"
+	"url-shortner-be/model/tax"
 	"url-shortner-be/model/transaction"
+	"url-shortner-be/model/transfer"
 	"url-shortner-be/model/url"
 	"url-shortner-be/model/user"
+	"url-shortner-be/model/withdrawal"
 )
 
 func Configure(appObj *app.App) {
@@ -17,6 +30,19 @@ func Configure(appObj *app.App) {
 	urlModule := url.NewUrlModuleConfig(appObj.DB)
 	subscriptionModule := subscription.NewSubscriptionModuleConfig(appObj.DB)
 	transactionModule := transaction.NewTransactionModuleConfig(appObj.DB)
+	transferModule := transfer.NewTransferModuleConfig(appObj.DB)
+	notificationModule := notification.NewNotificationModuleConfig(appObj.DB)
+	clickModule := click.NewClickModuleConfig(appObj.DB)
+	pageModule := page.NewPageModuleConfig(appObj.DB)
+	domainModule := domain.NewDomainModuleConfig(appObj.DB)
+	ledgerModule := ledger.NewLedgerModuleConfig(appObj.DB)
+	idempotencyModule := idempotency.NewIdempotencyModuleConfig(appObj.DB)
+	paymentModule := payment.NewPaymentModuleConfig(appObj.DB)
+	withdrawalModule := withdrawal.NewWithdrawalModuleConfig(appObj.DB)
+	invoiceModule := invoice.NewInvoiceModuleConfig(appObj.DB)
+	taxModule := tax.NewTaxModuleConfig(appObj.DB)
+	couponModule := coupon.NewCouponModuleConfig(appObj.DB)
+	referralModule := referral.NewReferralModuleConfig(appObj.DB)
 
-	appObj.MigrateModuleTables([]app.ModuleConfig{userModule, credentialModule, urlModule, subscriptionModule, transactionModule})
+	appObj.MigrateModuleTables([]app.ModuleConfig{userModule, credentialModule, urlModule, subscriptionModule, transactionModule, transferModule, notificationModule, clickModule, pageModule, domainModule, ledgerModule, idempotencyModule, paymentModule, withdrawalModule, invoiceModule, taxModule, couponModule, referralModule})
 }
@@ -0,0 +1,20 @@
+package module
+
+import (
+	"url-shortner-be/app"
+	"url-shortner-be/components/coupon/controller"
+	couponService "url-shortner-be/components/coupon/service"
+	"url-shortner-be/module/repository"
+)
+
+func registerCouponRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	couponService := couponService.NewCouponService(appObj.DB, repository)
+
+	couponController := controller.NewCouponController(couponService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		couponController,
+	})
+}
@@ -0,0 +1,21 @@
+package module
+
+import (
+	"url-shortner-be/app"
+	"url-shortner-be/components/dns"
+	"url-shortner-be/components/domain/controller"
+	domainService "url-shortner-be/components/domain/service"
+	"url-shortner-be/module/repository"
+)
+
+func registerDomainRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	domainService := domainService.NewDomainService(appObj.DB, repository, dns.NewResolver())
+
+	domainController := controller.NewDomainController(domainService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		domainController,
+	})
+}
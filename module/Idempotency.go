@@ -0,0 +1,14 @@
+package module
+
+import (
+	"time"
+	"url-shortner-be/app"
+	"url-shortner-be/components/config"
+	"url-shortner-be/components/idempotency"
+)
+
+// newIdempotencyGuard shares one database backed key store between the controllers with money moving routes.
+func newIdempotencyGuard(appObj *app.App) *idempotency.Guard {
+	ttl := time.Duration(config.IdempotencyKeyTTLHours.GetInt64Value()) * time.Hour
+	return idempotency.NewGuard(idempotency.NewGormStore(appObj.DB), ttl)
+}
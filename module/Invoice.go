@@ -0,0 +1,20 @@
+package module
+
+import (
+	"url-shortner-be/app"
+	"url-shortner-be/components/invoice/controller"
+	invoiceService "url-shortner-be/components/invoice/service"
+	"url-shortner-be/module/repository"
+)
+
+func registerInvoiceRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	invoiceService := invoiceService.NewInvoiceService(appObj.DB, repository)
+
+	invoiceController := controller.NewInvoiceController(invoiceService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		invoiceController,
+	})
+}
@@ -0,0 +1,44 @@
+package module
+
+import (
+	"time"
+	"url-shortner-be/app"
+	"url-shortner-be/components/config"
+	ledgerService "url-shortner-be/components/ledger/service"
+	subscriptionService "url-shortner-be/components/subscription/service"
+	urlService "url-shortner-be/components/url/service"
+	"url-shortner-be/module/repository"
+)
+
+// StartJobs launches the periodic background jobs, a job with a non positive interval stays disabled.
+func StartJobs(appObj *app.App, repository repository.Repository) {
+	appObj.Log.Print("============Starting-Background-Jobs==============")
+
+	urlService := urlService.NewUrlService(appObj.DB, repository)
+
+	ledgerService := ledgerService.NewLedgerService(appObj.DB, repository)
+
+	subscriptionService := subscriptionService.NewSubscriptionService(appObj.DB, repository)
+
+	runEvery(appObj, "link health check", config.LinkHealthCheckMinutes.GetInt64Value(), urlService.CheckDestinations)
+	runEvery(appObj, "ledger reconciliation", config.LedgerReconciliationMinutes.GetInt64Value(), ledgerService.ReconcileBalances)
+	runEvery(appObj, "idempotency key purge", config.IdempotencyKeyPurgeMinutes.GetInt64Value(), newIdempotencyGuard(appObj).PurgeExpired)
+	runEvery(appObj, "plan renewal", config.PlanRenewalMinutes.GetInt64Value(), subscriptionService.RenewPlans)
+}
+
+func runEvery(appObj *app.App, name string, minutes int64, job func()) {
+	if minutes <= 0 {
+		appObj.Log.Print("Background job disabled: ", name)
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(minutes) * time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			appObj.Log.Print("Running background job: ", name)
+			job()
+		}
+	}()
+}
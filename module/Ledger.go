@@ -0,0 +1,20 @@
+package module
+
+import (
+	"url-shortner-be/app"
+	"url-shortner-be/components/ledger/controller"
+	ledgerService "url-shortner-be/components/ledger/service"
+	"url-shortner-be/module/repository"
+)
+
+func registerLedgerRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	ledgerService := ledgerService.NewLedgerService(appObj.DB, repository)
+
+	ledgerController := controller.NewLedgerController(ledgerService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		ledgerController,
+	})
+}
@@ -0,0 +1,21 @@
+package module
+
+import (
+	"url-shortner-be/app"
+	"url-shortner-be/components/mail"
+	"url-shortner-be/components/notification/controller"
+	notificationService "url-shortner-be/components/notification/service"
+	"url-shortner-be/module/repository"
+)
+
+func registerNotificationRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	notificationService := notificationService.NewNotificationService(appObj.DB, repository, mail.NewSender())
+
+	notificationController := controller.NewNotificationController(notificationService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		notificationController,
+	})
+}
@@ -0,0 +1,30 @@
+package module
+
+import (
+	"url-shortner-be/app"
+	"url-shortner-be/components/payment"
+	"url-shortner-be/components/payment/controller"
+	paymentService "url-shortner-be/components/payment/service"
+	"url-shortner-be/module/repository"
+)
+
+func registerPaymentRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	paymentService := paymentService.NewPaymentService(appObj.DB, repository, newPaymentGateway(appObj))
+
+	paymentController := controller.NewPaymentController(paymentService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		paymentController,
+	})
+}
+
+// newPaymentGateway stops the app when no usable payment gateway is configured.
+func newPaymentGateway(appObj *app.App) payment.Gateway {
+	gateway, err := payment.NewGateway()
+	if err != nil {
+		appObj.Log.Fatalf("Payment gateway: %s", err)
+	}
+	return gateway
+}
@@ -0,0 +1,20 @@
+package module
+
+import (
+	"url-shortner-be/app"
+	"url-shortner-be/components/referral/controller"
+	referralService "url-shortner-be/components/referral/service"
+	"url-shortner-be/module/repository"
+)
+
+func registerReferralRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	referralService := referralService.NewReferralService(appObj.DB, repository)
+
+	referralController := controller.NewReferralController(referralService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		referralController,
+	})
+}
@@ -9,10 +9,20 @@ func RegisterModuleRoutes(app *app.App, repository repository.Repository) {
 	log := app.Log
 	log.Print("============Registering-Module-Routes==============")
 
-	app.WG.Add(5)
+	app.WG.Add(15)
 	registerUserRoutes(app, repository)
 	registerUrlRoutes(app, repository)
 	registerSubscriptionRoutes(app, repository)
 	registerTransactionRoutes(app, repository)
+	registerTransferRoutes(app, repository)
+	registerNotificationRoutes(app, repository)
+	registerDomainRoutes(app, repository)
+	registerLedgerRoutes(app, repository)
+	registerPaymentRoutes(app, repository)
+	registerWithdrawalRoutes(app, repository)
+	registerInvoiceRoutes(app, repository)
+	registerTaxRoutes(app, repository)
+	registerCouponRoutes(app, repository)
+	registerReferralRoutes(app, repository)
 	app.WG.Done()
 }
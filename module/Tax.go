@@ -0,0 +1,20 @@
+package module
+
+import (
+	"url-shortner-be/app"
+	"url-shortner-be/components/tax/controller"
+	taxService "url-shortner-be/components/tax/service"
+	"url-shortner-be/module/repository"
+)
+
+func registerTaxRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	taxService := taxService.NewTaxService(appObj.DB, repository)
+
+	taxController := controller.NewTaxController(taxService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		taxController,
+	})
+}
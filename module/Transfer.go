@@ -0,0 +1,21 @@
+package module
+
+import (
+	"url-shortner-be/app"
+	transactionService "url-shortner-be/components/transaction/service"
+	"url-shortner-be/components/transfer/controller"
+	transferService "url-shortner-be/components/transfer/service"
+	"url-shortner-be/module/repository"
+)
+
+func registerTransferRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	transferService := transferService.NewTransferService(appObj.DB, repository, transactionService.NewTransactionService(appObj.DB, repository))
+
+	transferController := controller.NewTransferController(transferService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		transferController,
+	})
+}
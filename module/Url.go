@@ -12,7 +12,7 @@ func registerUrlRoutes(appObj *app.App, repository repository.Repository) {
 	defer appObj.WG.Done()
 	urlService := urlService.NewUrlService(appObj.DB, repository)
 
-	urlController := controller.NewUrlController(urlService, appObj.Log)
+	urlController := controller.NewUrlController(urlService, newIdempotencyGuard(appObj), appObj.Log)
 
 	appObj.RegisterControllerRoutes([]app.Controller{
 		urlController,
@@ -2,18 +2,26 @@ package module
 
 import (
 	"url-shortner-be/app"
+	invoiceService "url-shortner-be/components/invoice/service"
+	ledgerService "url-shortner-be/components/ledger/service"
+	paymentService "url-shortner-be/components/payment/service"
 	transactionService "url-shortner-be/components/transaction/service"
 	"url-shortner-be/components/user/controller"
 	userService "url-shortner-be/components/user/service"
+	withdrawalService "url-shortner-be/components/withdrawal/service"
 	"url-shortner-be/module/repository"
 )
 
 func registerUserRoutes(appObj *app.App, repository repository.Repository) {
 
 	defer appObj.WG.Done()
-	userService := userService.NewUserService(appObj.DB, repository, transactionService.NewTransactionService(appObj.DB, repository))
+	userService := userService.NewUserService(appObj.DB, repository,
+		transactionService.NewTransactionService(appObj.DB, repository), ledgerService.NewLedgerService(appObj.DB, repository),
+		paymentService.NewPaymentService(appObj.DB, repository, newPaymentGateway(appObj)),
+		withdrawalService.NewWithdrawalService(appObj.DB, repository, newPayoutProvider(appObj)),
+		invoiceService.NewInvoiceService(appObj.DB, repository))
 
-	userController := controller.NewUserController(userService, appObj.Log)
+	userController := controller.NewUserController(userService, newIdempotencyGuard(appObj), appObj.Log)
 
 	appObj.RegisterControllerRoutes([]app.Controller{
 		userController,
@@ -0,0 +1,36 @@
+package module
+
+import (
+	"errors"
+	"url-shortner-be/app"
+	"url-shortner-be/components/payout"
+	"url-shortner-be/components/withdrawal/controller"
+	withdrawalService "url-shortner-be/components/withdrawal/service"
+	"url-shortner-be/module/repository"
+)
+
+func registerWithdrawalRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	withdrawalService := withdrawalService.NewWithdrawalService(appObj.DB, repository, newPayoutProvider(appObj))
+
+	withdrawalController := controller.NewWithdrawalController(withdrawalService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		withdrawalController,
+	})
+}
+
+// newPayoutProvider returns nil when no payout provider is configured, withdrawals can then still be requested
+// and rejected but not approved. An unknown provider stops the app.
+func newPayoutProvider(appObj *app.App) payout.Provider {
+	provider, err := payout.NewProvider()
+	if errors.Is(err, payout.ErrNoProvider) {
+		appObj.Log.Print("Payout provider: ", err, ", withdrawals cannot be approved")
+		return nil
+	}
+	if err != nil {
+		appObj.Log.Fatalf("Payout provider: %s", err)
+	}
+	return provider
+}
@@ -174,6 +174,14 @@ func Order(column string) QueryProcessor {
 	}
 }
 
+// ForUpdate locks the selected rows until the unit of work is committed or rolled back.
+func ForUpdate() QueryProcessor {
+	return func(db *gorm.DB, out interface{}) (*gorm.DB, error) {
+		db = db.Set("gorm:query_option", "FOR UPDATE")
+		return db, nil
+	}
+}
+
 // CombineQueries will process slice of queryprocessors and return single queryprocessor.
 func CombineQueries(queryProcessors []QueryProcessor) QueryProcessor {
 	return func(db *gorm.DB, out interface{}) (*gorm.DB, error) {